@@ -0,0 +1,151 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package autodetect inspects a project directory for language version files
+// and manifests, and proposes the devbox packages and scripts that match them.
+package autodetect
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Package is a devbox package that a project appears to need.
+type Package struct {
+	// Name is the nixpkgs name of the package, such as "nodejs".
+	Name string `json:"name"`
+
+	// Version is the version to pin, such as "20" or "3.12". It's "latest"
+	// if the project doesn't ask for a specific version.
+	Version string `json:"version"`
+
+	// Source is the file that the package was detected from.
+	Source string `json:"source"`
+}
+
+// String returns the package in name@version format.
+func (p Package) String() string {
+	return p.Name + "@" + p.Version
+}
+
+// Script is a devbox script that runs a common task for a project.
+type Script struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	Source  string `json:"source"`
+}
+
+// Proposal is the set of packages and scripts that autodetect suggests for a
+// project.
+type Proposal struct {
+	Packages []Package `json:"packages"`
+	Scripts  []Script  `json:"scripts"`
+}
+
+// Empty returns true if nothing was detected.
+func (p *Proposal) Empty() bool {
+	return len(p.Packages) == 0 && len(p.Scripts) == 0
+}
+
+func (p *Proposal) addPackage(pkg Package) {
+	for _, existing := range p.Packages {
+		if existing.Name == pkg.Name {
+			return
+		}
+	}
+	if pkg.Version == "" {
+		pkg.Version = "latest"
+	}
+	p.Packages = append(p.Packages, pkg)
+}
+
+func (p *Proposal) addScript(script Script) {
+	for _, existing := range p.Scripts {
+		if existing.Name == script.Name {
+			return
+		}
+	}
+	p.Scripts = append(p.Scripts, script)
+}
+
+// detector looks for a kind of project in dir and adds what it finds to the
+// proposal. Detectors should ignore files that don't exist.
+type detector func(dir string, p *Proposal) error
+
+// detectors run in priority order. When two files ask for different versions
+// of the same package, the first one wins, so files that pin an exact version
+// come before the looser constraints in manifests like the engines in
+// package.json. A tool's own version file, such as .nvmrc, comes before
+// .tool-versions.
+var detectors = []detector{
+	detectNodeVersionFile,
+	detectPythonVersionFile,
+	detectRubyVersionFile,
+	detectRustToolchain,
+	detectToolVersions,
+	detectGo,
+	detectPackageJSON,
+	detectPyproject,
+	detectGemfile,
+	detectCargo,
+}
+
+// Detect inspects the files in dir and returns the packages and scripts it
+// proposes for the project.
+func Detect(dir string) (*Proposal, error) {
+	p := &Proposal{}
+	for _, detect := range detectors {
+		if err := detect(dir, p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// readFile reads a file in dir. It returns nil and no error if the file
+// doesn't exist.
+func readFile(dir, name string) ([]byte, error) {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return b, err
+}
+
+func fileExists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+// firstLine returns the first non-empty, non-comment line of a version file.
+func firstLine(b []byte) string {
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
+
+var versionRegexp = regexp.MustCompile(`\d+(\.\d+)*`)
+
+// versionPrefix returns the first n components of the first version number
+// in s. For example, versionPrefix(">=3.10.2, <4", 2) returns "3.10". It
+// returns an empty string if s has no version number in it.
+//
+// Devbox resolves a partial version to the latest matching version, so
+// pinning fewer components lets projects pick up patch releases that are
+// available in nixpkgs.
+func versionPrefix(s string, n int) string {
+	version := versionRegexp.FindString(s)
+	if version == "" {
+		return ""
+	}
+	parts := strings.Split(version, ".")
+	return strings.Join(parts[:min(n, len(parts))], ".")
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package autodetect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		wantPackages []string
+		wantScripts  []string
+	}{
+		{
+			name:         "Empty",
+			files:        map[string]string{"README.md": "hello"},
+			wantPackages: []string{},
+			wantScripts:  []string{},
+		},
+		{
+			name: "GoToolchain",
+			files: map[string]string{
+				"go.mod": "module example.com/x\n\ngo 1.21\n\ntoolchain go1.22.3\n",
+			},
+			wantPackages: []string{"go@1.22"},
+			wantScripts:  []string{"build", "test"},
+		},
+		{
+			name: "NodeVersionFileWins",
+			files: map[string]string{
+				".nvmrc":       "v20.11.0\n",
+				"package.json": `{"engines": {"node": "^18.2"}, "packageManager": "yarn@4.1.0", "scripts": {"test": "jest"}}`,
+			},
+			wantPackages: []string{"nodejs@20", "yarn-berry@latest"},
+			wantScripts:  []string{"install", "test"},
+		},
+		{
+			name: "NodeLTS",
+			files: map[string]string{
+				".nvmrc": "lts/iron\n",
+			},
+			wantPackages: []string{"nodejs@latest"},
+			wantScripts:  []string{},
+		},
+		{
+			name: "Poetry",
+			files: map[string]string{
+				"pyproject.toml": "[tool.poetry]\nname = \"x\"\n\n[tool.poetry.dependencies]\npython = \"^3.10\"\n\n[tool.pytest.ini_options]\n",
+			},
+			wantPackages: []string{"python@3.10", "poetry@latest"},
+			wantScripts:  []string{"install", "test"},
+		},
+		{
+			name: "PythonVersionFile",
+			files: map[string]string{
+				".python-version": "3.12.1\n",
+				"pyproject.toml":  "[project]\nrequires-python = \">=3.9\"\n",
+			},
+			wantPackages: []string{"python@3.12"},
+			wantScripts:  []string{"install"},
+		},
+		{
+			name: "Ruby",
+			files: map[string]string{
+				".ruby-version": "ruby-3.3.0\n",
+				"Gemfile":       "source 'https://rubygems.org'\nruby '~> 3.1'\n",
+				"Rakefile":      "",
+			},
+			wantPackages: []string{"ruby@3.3"},
+			wantScripts:  []string{"install", "test"},
+		},
+		{
+			name: "Rust",
+			files: map[string]string{
+				"rust-toolchain.toml": "[toolchain]\nchannel = \"1.75.0\"\n",
+				"Cargo.toml":          "[package]\nname = \"x\"\n",
+			},
+			wantPackages: []string{"rustc@1.75", "cargo@1.75"},
+			wantScripts:  []string{"build", "test"},
+		},
+		{
+			name: "RustStableChannel",
+			files: map[string]string{
+				"rust-toolchain": "stable\n",
+			},
+			wantPackages: []string{"rustc@latest", "cargo@latest"},
+			wantScripts:  []string{},
+		},
+		{
+			name: "ToolVersions",
+			files: map[string]string{
+				".python-version": "3.11\n",
+				".tool-versions":  "# comment\npython 3.12.0\ngolang 1.22.1 1.21.0\nterraform system\nunknown 1.0\n",
+			},
+			wantPackages: []string{"python@3.11", "go@1.22"},
+			wantScripts:  []string{},
+		},
+		{
+			name: "ToolVersionsBeatManifests",
+			files: map[string]string{
+				".tool-versions": "nodejs 20.11.0\nruby 3.3.0\n",
+				"package.json":   `{"engines": {"node": ">=16"}}`,
+				"Gemfile":        "source 'https://rubygems.org'\nruby '~> 3.1'\n",
+			},
+			wantPackages: []string{"nodejs@20", "ruby@3.3"},
+			wantScripts:  []string{"install"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := Detect(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotPackages := []string{}
+			for _, pkg := range got.Packages {
+				gotPackages = append(gotPackages, pkg.String())
+			}
+			if diff := cmp.Diff(tt.wantPackages, gotPackages); diff != "" {
+				t.Errorf("wrong packages (-want +got):\n%s", diff)
+			}
+			gotScripts := []string{}
+			for _, script := range got.Scripts {
+				gotScripts = append(gotScripts, script.Name)
+			}
+			if diff := cmp.Diff(tt.wantScripts, gotScripts); diff != "" {
+				t.Errorf("wrong scripts (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseToolVersionsError(t *testing.T) {
+	if _, err := ParseToolVersions([]byte("nodejs 20.1.0\npython\n")); err == nil {
+		t.Error("got nil error for a tool without a version")
+	}
+}
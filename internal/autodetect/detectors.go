@@ -0,0 +1,278 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package autodetect
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+func detectGo(dir string, p *Proposal) error {
+	const name = "go.mod"
+	b, err := readFile(dir, name)
+	if err != nil || b == nil {
+		return err
+	}
+	mod, err := modfile.Parse(name, b, nil)
+	if err != nil {
+		return errors.Wrapf(err, "parse %s", name)
+	}
+
+	// The toolchain directive is the version that the project is built
+	// with, whereas the go directive is only the minimum version.
+	version := ""
+	if mod.Toolchain != nil {
+		version = versionPrefix(mod.Toolchain.Name, 2)
+	}
+	if version == "" && mod.Go != nil {
+		version = versionPrefix(mod.Go.Version, 2)
+	}
+	p.addPackage(Package{Name: "go", Version: version, Source: name})
+	p.addScript(Script{Name: "build", Command: "go build ./...", Source: name})
+	p.addScript(Script{Name: "test", Command: "go test ./...", Source: name})
+	return nil
+}
+
+// nodeVersion converts a Node.js version or version range to a nodejs
+// package version. nixpkgs has one nodejs package per major version.
+func nodeVersion(v string) string {
+	if strings.HasPrefix(v, "lts/") || v == "node" || v == "stable" {
+		return "latest"
+	}
+	return versionPrefix(v, 1)
+}
+
+func detectNodeVersionFile(dir string, p *Proposal) error {
+	for _, name := range []string{".nvmrc", ".node-version"} {
+		b, err := readFile(dir, name)
+		if err != nil {
+			return err
+		}
+		if b != nil {
+			p.addPackage(Package{Name: "nodejs", Version: nodeVersion(firstLine(b)), Source: name})
+			return nil
+		}
+	}
+	return nil
+}
+
+type packageJSON struct {
+	Engines struct {
+		Node string `json:"node"`
+	} `json:"engines"`
+	PackageManager string            `json:"packageManager"`
+	Scripts        map[string]string `json:"scripts"`
+}
+
+func detectPackageJSON(dir string, p *Proposal) error {
+	const name = "package.json"
+	b, err := readFile(dir, name)
+	if err != nil || b == nil {
+		return err
+	}
+	pkgJSON := packageJSON{}
+	if err := json.Unmarshal(b, &pkgJSON); err != nil {
+		return errors.Wrapf(err, "parse %s", name)
+	}
+	p.addPackage(Package{Name: "nodejs", Version: nodeVersion(pkgJSON.Engines.Node), Source: name})
+
+	// The packageManager field is "name@version", as used by corepack.
+	manager, managerVersion, _ := strings.Cut(pkgJSON.PackageManager, "@")
+	switch manager {
+	case "pnpm":
+		p.addPackage(Package{Name: "pnpm", Version: versionPrefix(managerVersion, 1), Source: name})
+	case "yarn":
+		// nixpkgs packages Yarn 2 and later as yarn-berry.
+		if major := versionPrefix(managerVersion, 1); major == "" || major == "1" {
+			p.addPackage(Package{Name: "yarn", Source: name})
+		} else {
+			p.addPackage(Package{Name: "yarn-berry", Source: name})
+		}
+	default:
+		manager = "npm"
+	}
+
+	p.addScript(Script{Name: "install", Command: manager + " install", Source: name})
+	for _, script := range []string{"build", "dev", "start", "test", "lint"} {
+		if _, ok := pkgJSON.Scripts[script]; ok {
+			p.addScript(Script{Name: script, Command: manager + " run " + script, Source: name})
+		}
+	}
+	return nil
+}
+
+func detectPythonVersionFile(dir string, p *Proposal) error {
+	const name = ".python-version"
+	b, err := readFile(dir, name)
+	if err != nil || b == nil {
+		return err
+	}
+	p.addPackage(Package{Name: "python", Version: versionPrefix(firstLine(b), 2), Source: name})
+	return nil
+}
+
+type pyproject struct {
+	Project struct {
+		RequiresPython string `toml:"requires-python"`
+	} `toml:"project"`
+	Tool struct {
+		Poetry *struct {
+			Dependencies map[string]any `toml:"dependencies"`
+		} `toml:"poetry"`
+		UV     map[string]any `toml:"uv"`
+		Pytest map[string]any `toml:"pytest"`
+	} `toml:"tool"`
+}
+
+func detectPyproject(dir string, p *Proposal) error {
+	const name = "pyproject.toml"
+	b, err := readFile(dir, name)
+	if err != nil || b == nil {
+		return err
+	}
+	project := pyproject{}
+	if err := toml.Unmarshal(b, &project); err != nil {
+		return errors.Wrapf(err, "parse %s", name)
+	}
+
+	// requires-python is a minimum version, which is a better guess than
+	// whatever the latest python happens to be.
+	version := versionPrefix(project.Project.RequiresPython, 2)
+	runner := ""
+	switch {
+	case project.Tool.Poetry != nil:
+		if constraint, ok := project.Tool.Poetry.Dependencies["python"].(string); ok && version == "" {
+			version = versionPrefix(constraint, 2)
+		}
+		p.addPackage(Package{Name: "python", Version: version, Source: name})
+		p.addPackage(Package{Name: "poetry", Source: name})
+		p.addScript(Script{Name: "install", Command: "poetry install", Source: name})
+		runner = "poetry run "
+	case project.Tool.UV != nil:
+		p.addPackage(Package{Name: "python", Version: version, Source: name})
+		p.addPackage(Package{Name: "uv", Source: name})
+		p.addScript(Script{Name: "install", Command: "uv sync", Source: name})
+		runner = "uv run "
+	default:
+		p.addPackage(Package{Name: "python", Version: version, Source: name})
+		p.addScript(Script{Name: "install", Command: "pip install -e .", Source: name})
+	}
+	if project.Tool.Pytest != nil {
+		p.addScript(Script{Name: "test", Command: runner + "pytest", Source: name})
+	}
+	return nil
+}
+
+func detectRubyVersionFile(dir string, p *Proposal) error {
+	const name = ".ruby-version"
+	b, err := readFile(dir, name)
+	if err != nil || b == nil {
+		return err
+	}
+	version := strings.TrimPrefix(firstLine(b), "ruby-")
+	p.addPackage(Package{Name: "ruby", Version: versionPrefix(version, 2), Source: name})
+	return nil
+}
+
+// gemfileRuby matches the ruby directive in a Gemfile, such as
+// ruby "3.2.2" or ruby '~> 3.1'.
+var gemfileRuby = regexp.MustCompile(`(?m)^\s*ruby\s+["']([^"']+)["']`)
+
+func detectGemfile(dir string, p *Proposal) error {
+	const name = "Gemfile"
+	b, err := readFile(dir, name)
+	if err != nil || b == nil {
+		return err
+	}
+	version := ""
+	if m := gemfileRuby.FindSubmatch(b); m != nil {
+		version = versionPrefix(string(m[1]), 2)
+	}
+	p.addPackage(Package{Name: "ruby", Version: version, Source: name})
+	p.addScript(Script{Name: "install", Command: "bundle install", Source: name})
+	if fileExists(dir, "Rakefile") {
+		p.addScript(Script{Name: "test", Command: "bundle exec rake test", Source: name})
+	}
+	return nil
+}
+
+type rustToolchain struct {
+	Toolchain struct {
+		Channel string `toml:"channel"`
+	} `toml:"toolchain"`
+}
+
+func detectRustToolchain(dir string, p *Proposal) error {
+	for _, name := range []string{"rust-toolchain.toml", "rust-toolchain"} {
+		b, err := readFile(dir, name)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			continue
+		}
+
+		// The legacy rust-toolchain file is either TOML or just the
+		// channel name.
+		channel := firstLine(b)
+		if name == "rust-toolchain.toml" || strings.HasPrefix(channel, "[") {
+			toolchain := rustToolchain{}
+			if err := toml.Unmarshal(b, &toolchain); err != nil {
+				return errors.Wrapf(err, "parse %s", name)
+			}
+			channel = toolchain.Toolchain.Channel
+		}
+		addRust(p, channel, name)
+		return nil
+	}
+	return nil
+}
+
+// addRust adds the rustc and cargo packages for a rustup channel. Only
+// numbered channels, such as "1.75.0", pin a version. Channels like "stable"
+// or "nightly-2024-01-01" use the latest version in nixpkgs.
+func addRust(p *Proposal, channel, source string) {
+	version := ""
+	if versionRegexp.FindString(channel) == channel {
+		version = versionPrefix(channel, 2)
+	}
+	p.addPackage(Package{Name: "rustc", Version: version, Source: source})
+	p.addPackage(Package{Name: "cargo", Version: version, Source: source})
+}
+
+func detectCargo(dir string, p *Proposal) error {
+	const name = "Cargo.toml"
+	if !fileExists(dir, name) {
+		return nil
+	}
+	addRust(p, "", name)
+	p.addScript(Script{Name: "build", Command: "cargo build", Source: name})
+	p.addScript(Script{Name: "test", Command: "cargo test", Source: name})
+	return nil
+}
+
+func detectToolVersions(dir string, p *Proposal) error {
+	const name = ".tool-versions"
+	b, err := readFile(dir, name)
+	if err != nil || b == nil {
+		return err
+	}
+	tools, err := ParseToolVersions(b)
+	if err != nil {
+		return errors.Wrapf(err, "parse %s", name)
+	}
+	mapping := DefaultToolMapping()
+	for _, tool := range tools {
+		for _, pkg := range mapping.Packages(tool) {
+			pkg.Source = name
+			p.addPackage(pkg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package autodetect
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ToolVersion is a line in an asdf or mise .tool-versions file.
+type ToolVersion struct {
+	// Tool is the name of the asdf plugin, such as "nodejs".
+	Tool string
+
+	// Versions are the versions to use in order of preference. asdf falls
+	// back to the next version if the first one isn't installed.
+	Versions []string
+}
+
+// ParseToolVersions parses the contents of a .tool-versions file.
+func ParseToolVersions(b []byte) ([]ToolVersion, error) {
+	tools := []ToolVersion{}
+	for i, line := range strings.Split(string(b), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, errors.Errorf("line %d: missing a version for %s", i+1, fields[0])
+		}
+		tools = append(tools, ToolVersion{Tool: fields[0], Versions: fields[1:]})
+	}
+	return tools, nil
+}
+
+// FormatToolVersions formats tools as the contents of a .tool-versions file.
+func FormatToolVersions(tools []ToolVersion) []byte {
+	sb := strings.Builder{}
+	for _, tool := range tools {
+		fmt.Fprintf(&sb, "%s %s\n", tool.Tool, strings.Join(tool.Versions, " "))
+	}
+	return []byte(sb.String())
+}
+
+// ToolPackages are the nixpkgs packages that provide an asdf tool.
+type ToolPackages struct {
+	// Packages are the nixpkgs package names. The first package is the
+	// one that's exported back to .tool-versions.
+	Packages []string `json:"packages"`
+
+	// Precision is the number of version components to keep when a
+	// tool's exact version isn't available in nixpkgs. For example, a
+	// precision of 1 turns nodejs 20.11.0 into nodejs@20.
+	Precision int `json:"precision,omitempty"`
+
+	// Aliases are other names for the tool, such as the names that mise
+	// uses.
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// ToolMapping maps asdf tool names to nixpkgs packages.
+type ToolMapping map[string]ToolPackages
+
+//go:embed tool_versions.json
+var defaultToolMappingJSON []byte
+
+// DefaultToolMapping returns the mapping that ships with devbox.
+func DefaultToolMapping() ToolMapping {
+	mapping := ToolMapping{}
+	if err := json.Unmarshal(defaultToolMappingJSON, &mapping); err != nil {
+		panic("invalid tool_versions.json: " + err.Error())
+	}
+	return mapping
+}
+
+// LoadToolMapping returns the default mapping with the entries in a JSON
+// file added to it. Entries in the file replace the default entries for the
+// same tool. An empty path returns the default mapping.
+func LoadToolMapping(path string) (ToolMapping, error) {
+	mapping := DefaultToolMapping()
+	if path == "" {
+		return mapping, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	overrides := ToolMapping{}
+	if err := json.Unmarshal(b, &overrides); err != nil {
+		return nil, errors.Wrapf(err, "parse tool mapping %s", path)
+	}
+	for tool, pkgs := range overrides {
+		if len(pkgs.Packages) == 0 {
+			return nil, errors.Errorf("tool mapping %s: %s has no packages", path, tool)
+		}
+		mapping[tool] = pkgs
+	}
+	return mapping, nil
+}
+
+// Lookup returns the packages for a tool name or alias.
+func (m ToolMapping) Lookup(tool string) (ToolPackages, bool) {
+	if pkgs, ok := m[tool]; ok {
+		return pkgs, true
+	}
+	for _, pkgs := range m {
+		if slices.Contains(pkgs.Aliases, tool) {
+			return pkgs, true
+		}
+	}
+	return ToolPackages{}, false
+}
+
+// ToolFor returns the tool whose first package is pkgName. Aliases aren't
+// returned so that exported files use asdf's plugin names.
+func (m ToolMapping) ToolFor(pkgName string) (string, bool) {
+	tools := make([]string, 0, len(m))
+	for tool := range m {
+		tools = append(tools, tool)
+	}
+	// Sort for a deterministic result if an override maps two tools to
+	// the same package.
+	slices.Sort(tools)
+	for _, tool := range tools {
+		if pkgs := m[tool]; pkgs.Packages[0] == pkgName {
+			return tool, true
+		}
+	}
+	return "", false
+}
+
+// Packages returns the devbox packages for a tool with the tool's preferred
+// version truncated to the mapping's precision. It returns nil if the tool
+// isn't in the mapping or if its version isn't a version number, such as
+// "system" or "ref:main".
+func (m ToolMapping) Packages(t ToolVersion) []Package {
+	pkgs, ok := m.Lookup(t.Tool)
+	if !ok || len(t.Versions) == 0 {
+		return nil
+	}
+	candidates := pkgs.VersionCandidates(t.Versions[0])
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	version := candidates[len(candidates)-1]
+	result := make([]Package, 0, len(pkgs.Packages))
+	for _, name := range pkgs.Packages {
+		result = append(result, Package{Name: name, Version: version})
+	}
+	return result
+}
+
+// VersionCandidates returns the package versions to try for a tool version,
+// from most to least specific: the full version number and then the version
+// truncated to the mapping's precision. It returns nil if the version isn't a
+// version number.
+func (p ToolPackages) VersionCandidates(version string) []string {
+	if version == "latest" {
+		return []string{"latest"}
+	}
+	// asdf versions like "ref:main" and "path:/opt/go" aren't releases.
+	if strings.Contains(version, ":") {
+		return nil
+	}
+	// Some plugins prefix the version with a distribution, such as the
+	// java plugin's "temurin-17.0.9+9".
+	full := versionPrefix(version, len(version))
+	if full == "" {
+		return nil
+	}
+	truncated := versionPrefix(full, max(p.Precision, 1))
+	if truncated == full {
+		return []string{full}
+	}
+	return []string{full, truncated}
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package autodetect
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFormatToolVersions(t *testing.T) {
+	in := "nodejs 20.11.0   # comment\n\ngolang 1.22.3 1.21.0\n"
+	tools, err := ParseToolVersions([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(FormatToolVersions(tools))
+	want := "nodejs 20.11.0\ngolang 1.22.3 1.21.0\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestVersionCandidates(t *testing.T) {
+	pkgs := ToolPackages{Packages: []string{"python"}, Precision: 2}
+	tests := map[string][]string{
+		"3.12.1":          {"3.12.1", "3.12"},
+		"3.12":            {"3.12"},
+		"latest":          {"latest"},
+		"temurin-17.0.9":  {"17.0.9", "17.0"},
+		"system":          nil,
+		"ref:main":        nil,
+		"path:/opt/py3.9": nil,
+	}
+	for version, want := range tests {
+		if got := pkgs.VersionCandidates(version); !slices.Equal(got, want) {
+			t.Errorf("VersionCandidates(%q) = %q, want %q", version, got, want)
+		}
+	}
+}
+
+func TestToolMappingLookup(t *testing.T) {
+	mapping := DefaultToolMapping()
+	for _, tool := range []string{"nodejs", "node"} {
+		pkgs, ok := mapping.Lookup(tool)
+		if !ok || pkgs.Packages[0] != "nodejs" {
+			t.Errorf("Lookup(%q) = %v, %v, want nodejs", tool, pkgs, ok)
+		}
+	}
+	if tool, ok := mapping.ToolFor("go"); !ok || tool != "golang" {
+		t.Errorf("ToolFor(go) = %q, %v, want golang", tool, ok)
+	}
+	if tool, ok := mapping.ToolFor("cargo"); ok {
+		t.Errorf("ToolFor(cargo) = %q, want no tool", tool)
+	}
+}
+
+func TestLoadToolMapping(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.json")
+	overrides := `{
+		"nodejs": {"packages": ["nodejs-slim"], "precision": 1},
+		"just": {"packages": ["just"]}
+	}`
+	if err := os.WriteFile(path, []byte(overrides), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := LoadToolMapping(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mapping["nodejs"].Packages; !slices.Equal(got, []string{"nodejs-slim"}) {
+		t.Errorf("got nodejs packages %q, want the override", got)
+	}
+	if _, ok := mapping.Lookup("just"); !ok {
+		t.Error("got no packages for a tool added by the mapping file")
+	}
+	if _, ok := mapping.Lookup("golang"); !ok {
+		t.Error("got no packages for a default tool")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"just": {}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadToolMapping(path); err == nil {
+		t.Error("got nil error for a tool without packages")
+	}
+}
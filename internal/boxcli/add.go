@@ -25,6 +25,8 @@ type addCmdFlags struct {
 	excludePlatforms []string
 	patchGlibc       bool
 	outputs          []string
+	groups           []string
+	progress         progressFlags
 }
 
 func addCmd() *cobra.Command {
@@ -71,11 +73,18 @@ func addCmd() *cobra.Command {
 	command.Flags().StringSliceVarP(
 		&flags.outputs, "outputs", "o", []string{},
 		"specify the outputs to select for the nix package")
+	command.Flags().StringSliceVarP(
+		&flags.groups, "group", "g", []string{},
+		"add packages to a package group instead of the default group")
+	flags.progress.register(command)
 
 	return command
 }
 
 func addCmdFunc(cmd *cobra.Command, args []string, flags addCmdFlags) error {
+	if err := flags.progress.init(cmd); err != nil {
+		return err
+	}
 	box, err := devbox.Open(&devopt.Opts{
 		Dir:         flags.config.path,
 		Environment: flags.config.environment,
@@ -92,5 +101,6 @@ func addCmdFunc(cmd *cobra.Command, args []string, flags addCmdFlags) error {
 		ExcludePlatforms: flags.excludePlatforms,
 		PatchGlibc:       flags.patchGlibc,
 		Outputs:          flags.outputs,
+		Groups:           flags.groups,
 	})
 }
@@ -0,0 +1,112 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+)
+
+type auditCmdFlags struct {
+	config configFlags
+	opts   devopt.AuditOpts
+	json   bool
+}
+
+func auditCmd() *cobra.Command {
+	flags := auditCmdFlags{}
+	command := &cobra.Command{
+		Use:   "audit",
+		Short: "Check the packages in your devbox environment for known vulnerabilities",
+		Long: heredoc.Doc(`
+			Check the packages in devbox.lock for known vulnerabilities.
+
+			Devbox reports the vulnerabilities that nixpkgs lists in each
+			package's meta.knownVulnerabilities. With --advisories, it also
+			matches package versions against an offline file or directory of
+			advisories in the OSV format (https://ossf.github.io/osv-schema/).
+			Advisories apply to packages in the "Nix" ecosystem or with a
+			pkg:nix purl. With --closure, every store path in the packages'
+			runtime closures is matched against the advisories as well.
+
+			The command exits with a non-zero status if it finds any
+			vulnerabilities at or above the --severity threshold.
+		`),
+		Args:    cobra.ExactArgs(0),
+		PreRunE: ensureNixInstalled,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return auditCmdFunc(cmd, flags)
+		},
+	}
+
+	flags.config.register(command)
+	command.Flags().StringVar(
+		&flags.opts.Advisories, "advisories", "",
+		"path to an OSV advisory JSON file or a directory of them",
+	)
+	command.Flags().BoolVar(
+		&flags.opts.Closure, "closure", false,
+		"also check every package in the runtime closure (requires installing packages)",
+	)
+	command.Flags().StringVar(
+		&flags.opts.Severity, "severity", "low",
+		"minimum severity to report: low, medium, high or critical",
+	)
+	command.Flags().BoolVar(&flags.json, "json", false, "output in json format")
+	return command
+}
+
+func auditCmdFunc(cmd *cobra.Command, flags auditCmdFlags) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:         flags.config.path,
+		Environment: flags.config.environment,
+		Stderr:      cmd.ErrOrStderr(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	report, err := box.Audit(cmd.Context(), flags.opts)
+	if err != nil {
+		return err
+	}
+
+	if flags.json {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		printAuditReport(cmd.OutOrStdout(), report)
+	}
+	if len(report.Findings) > 0 {
+		return usererr.New("Found %d known vulnerabilities.", len(report.Findings))
+	}
+	return nil
+}
+
+func printAuditReport(w io.Writer, report *devbox.AuditReport) {
+	if len(report.Findings) == 0 {
+		fmt.Fprintln(w, "No known vulnerabilities found.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tPACKAGE\tVERSION\tID\tFIXED IN\tSOURCE")
+	for _, f := range report.Findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			f.Severity, f.Package, f.Version, f.ID, f.Fixed, f.Source)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/ociimage"
+)
+
+type buildImageCmdFlags struct {
+	config configFlags
+	opts   devopt.BuildImageOpts
+}
+
+func buildCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "build",
+		Short: "Build artifacts from your devbox environment",
+	}
+	command.AddCommand(buildImageCmd())
+	return command
+}
+
+func buildImageCmd() *cobra.Command {
+	flags := buildImageCmdFlags{}
+	command := &cobra.Command{
+		Use:   "image",
+		Short: "Build a container image of your devbox environment without Docker",
+		Long: heredoc.Doc(`
+			Build a container image that contains your devbox packages and their
+			dependencies, copied directly from the Nix store. The image doesn't
+			need Nix, and building it doesn't need Docker. The image's environment
+			is the one of devbox shell, without secrets and the variables of the
+			host.
+
+			The default "archive" format can be loaded with "docker load -i" or
+			"podman load -i". The "oci" format writes an OCI image layout
+			directory that tools like skopeo and crane can push to a registry.
+		`),
+		Args:    cobra.ExactArgs(0),
+		PreRunE: ensureNixInstalled,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.opts.Output == "" {
+				flags.opts.Output = "devbox-image"
+				if flags.opts.Format == devbox.ImageFormatArchive {
+					flags.opts.Output += ".tar"
+				}
+			}
+			box, err := devbox.Open(&devopt.Opts{
+				Dir:         flags.config.path,
+				Environment: flags.config.environment,
+				Stderr:      cmd.ErrOrStderr(),
+			})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return box.BuildImage(cmd.Context(), flags.opts)
+		},
+	}
+
+	flags.config.register(command)
+	command.Flags().StringVarP(
+		&flags.opts.Output, "output", "o", "",
+		"path to write the image to (default \"devbox-image.tar\" or \"devbox-image\" for oci)",
+	)
+	command.Flags().StringVar(
+		&flags.opts.Format, "format", devbox.ImageFormatArchive,
+		"image format, either archive (docker load compatible tarball) or oci (image layout directory)",
+	)
+	command.Flags().BoolVarP(
+		&flags.opts.Force, "force", "f", false, "overwrite the output if it already exists",
+	)
+	command.Flags().StringVarP(
+		&flags.opts.Tag, "tag", "t", "devbox:latest", "name and tag of the image",
+	)
+	command.Flags().StringSliceVar(
+		&flags.opts.Entrypoint, "entrypoint", nil, "image entrypoint",
+	)
+	command.Flags().StringSliceVar(
+		&flags.opts.Cmd, "cmd", nil,
+		"image command (defaults to a shell from your packages when no entrypoint is set)",
+	)
+	command.Flags().StringVar(
+		&flags.opts.WorkingDir, "workdir", "", "working directory of the image",
+	)
+	command.Flags().IntVar(
+		&flags.opts.MaxLayers, "max-layers", ociimage.DefaultMaxLayers,
+		"maximum number of image layers",
+	)
+	return command
+}
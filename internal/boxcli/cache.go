@@ -16,6 +16,8 @@ import (
 	"go.jetpack.io/devbox/internal/devbox"
 	"go.jetpack.io/devbox/internal/devbox/devopt"
 	"go.jetpack.io/devbox/internal/devbox/providers/nixcache"
+	"go.jetpack.io/devbox/internal/devpkg"
+	"go.jetpack.io/devbox/internal/ux"
 	nixv1alpha1 "go.jetpack.io/pkg/api/gen/priv/nix/v1alpha1"
 )
 
@@ -44,8 +46,15 @@ func cacheCmd() *cobra.Command {
 			Upload specified nix installable or nix packages in current project to cache.
 			If [installable] is provided, only that installable will be uploaded.
 			Otherwise, all packages in the project will be uploaded.
-			To upload to specific cache, use --to flag. Otherwise, a cache from
-			the cache provider will be used, if available.
+			Only paths that the cache doesn't have are uploaded.
+
+			To upload to a specific cache, use the --to flag. Otherwise, the first
+			binary cache with "upload": true in devbox.json or in the global
+			devbox.json is used, and then a cache from the cache provider, if
+			available. Self-managed caches, such as S3-compatible buckets
+			(s3://bucket?endpoint=minio.example.com) and local directories
+			(file:///path/to/cache), use the credentials in your environment and
+			are signed with their secret_key_file.
 		`),
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -61,7 +70,17 @@ func cacheCmd() *cobra.Command {
 			if err != nil {
 				return errors.WithStack(err)
 			}
-			return box.UploadProjectToCache(cmd.Context(), flags.to)
+			summary, err := box.UploadProjectToCache(cmd.Context(), flags.to)
+			if err != nil {
+				return err
+			}
+			if summary.Uploaded == 0 {
+				ux.Fsuccess(cmd.ErrOrStderr(), "%s already has all %d paths.\n", summary.Cache, summary.Paths)
+				return nil
+			}
+			ux.Fsuccess(cmd.ErrOrStderr(), "Uploaded %d of %d paths (%s) to %s.\n",
+				summary.Uploaded, summary.Paths, formatBytes(summary.UploadedBytes), summary.Cache)
+			return nil
 		},
 	}
 
@@ -70,6 +89,7 @@ func cacheCmd() *cobra.Command {
 		&flags.to, "to", "", "URI of the cache to copy to")
 
 	cacheCommand.AddCommand(uploadCommand)
+	cacheCommand.AddCommand(cacheClearCmd())
 	cacheCommand.AddCommand(cacheConfigureCmd())
 	cacheCommand.AddCommand(cacheCredentialsCmd())
 	cacheCommand.AddCommand(cacheInfoCmd())
@@ -77,6 +97,30 @@ func cacheCmd() *cobra.Command {
 	return cacheCommand
 }
 
+func cacheClearCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Clear the local record of which packages are in binary caches",
+		Long: heredoc.Doc(`
+			Devbox remembers which store paths it found in binary caches, so it
+			doesn't need to check them every time your environment changes. Paths
+			that were found are remembered for a year, and paths that weren't found
+			are checked again after 15 minutes. Clear the record to check every
+			binary cache again, for example after you push packages to a cache.
+		`),
+		Args: cobra.ExactArgs(0),
+		// Clearing the cache doesn't need nix.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := devpkg.ClearNarInfoCache(); err != nil {
+				return err
+			}
+			ux.Fsuccess(cmd.ErrOrStderr(), "Cleared the binary cache lookups.\n")
+			return nil
+		},
+	}
+}
+
 func cacheConfigureCmd() *cobra.Command {
 	username := ""
 	cmd := &cobra.Command{
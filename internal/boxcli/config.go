@@ -5,6 +5,8 @@ package boxcli
 
 import (
 	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/devbox/devopt"
 )
 
 // to be composed into xyzCmdFlags structs
@@ -43,3 +45,23 @@ func (flags *pathFlag) registerPersistent(cmd *cobra.Command) {
 		&flags.path, "config", "c", "", "path to directory containing a devbox.json config file",
 	)
 }
+
+// groupsFlag is a flag for selecting the package groups to install
+type groupsFlag struct {
+	with []string
+	only []string
+}
+
+func (flags *groupsFlag) register(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(
+		&flags.with, "with", nil, "package groups to install in addition to the default group",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.only, "only", nil, "package groups to install instead of the default group",
+	)
+	cmd.MarkFlagsMutuallyExclusive("with", "only")
+}
+
+func (flags *groupsFlag) opts() devopt.PackageGroups {
+	return devopt.PackageGroups{With: flags.with, Only: flags.only}
+}
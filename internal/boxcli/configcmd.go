@@ -0,0 +1,217 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+)
+
+type configSetCmdFlags struct {
+	config pathFlag
+	json   bool
+}
+
+type configValidateCmdFlags struct {
+	config pathFlag
+	json   bool
+}
+
+func configCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Read and edit devbox.json",
+		Long: heredoc.Doc(`
+			Read and edit values in devbox.json while preserving its comments
+			and formatting.
+
+			Values are addressed by a path of object keys separated by dots,
+			with array indexes in brackets. Keys that contain dots, such as
+			versioned package names, can be quoted in brackets:
+
+			  env.GOFLAGS
+			  shell.scripts.test
+			  shell.init_hook[0]
+			  packages["python@3.12"].platforms
+		`),
+	}
+	command.AddCommand(configGetCmd())
+	command.AddCommand(configSchemaCmd())
+	command.AddCommand(configSetCmd())
+	command.AddCommand(configUnsetCmd())
+	command.AddCommand(configValidateCmd())
+	return command
+}
+
+func configGetCmd() *cobra.Command {
+	flags := pathFlag{}
+	command := &cobra.Command{
+		Use:   "get <path>",
+		Short: "Print a value from devbox.json",
+		Long:  "Print a value from devbox.json. Strings are printed as-is and other values as JSON.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := devbox.OpenConfigFile(flags.path)
+			if err != nil {
+				return err
+			}
+			val, ok, err := cfg.GetPath(args[0])
+			if err != nil {
+				return usererr.New("Unable to read %s from devbox.json: %v", args[0], err)
+			}
+			if !ok {
+				return usererr.New("%s is not set in devbox.json", args[0])
+			}
+
+			var str string
+			if err := json.Unmarshal(val, &str); err == nil {
+				fmt.Fprintln(cmd.OutOrStdout(), str)
+				return nil
+			}
+			out := &bytes.Buffer{}
+			if err := json.Indent(out, val, "", "  "); err != nil {
+				return errors.WithStack(err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), out)
+			return nil
+		},
+	}
+	flags.register(command)
+	return command
+}
+
+func configSetCmd() *cobra.Command {
+	flags := configSetCmdFlags{}
+	command := &cobra.Command{
+		Use:   "set <path> <value>",
+		Short: "Set a value in devbox.json",
+		Long: heredoc.Doc(`
+			Set a value in devbox.json, creating any missing objects in the path.
+			The value is a string unless --json is set, in which case it's parsed
+			as JSON.
+		`),
+		Example: heredoc.Doc(`
+			devbox config set env.GOFLAGS -- -mod=mod
+			devbox config set shell.init_hook --json '["echo hello"]'
+			devbox config set packages.go.platforms --json '["x86_64-linux"]'
+			devbox config set 'packages["python@3.12"].platforms' --json '["x86_64-linux"]'
+		`),
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value := []byte(args[1])
+			if !flags.json {
+				// json.Marshal can't fail for a string.
+				value, _ = json.Marshal(args[1])
+			}
+
+			cfg, err := devbox.OpenConfigFile(flags.config.path)
+			if err != nil {
+				return err
+			}
+			if err := cfg.SetPath(args[0], value); err != nil {
+				return usererr.New("Unable to set %s in devbox.json: %v", args[0], err)
+			}
+			return cfg.SaveTo(filepath.Dir(cfg.AbsRootPath))
+		},
+	}
+	flags.config.register(command)
+	command.Flags().BoolVar(&flags.json, "json", false, "parse the value as JSON instead of a string")
+	return command
+}
+
+func configUnsetCmd() *cobra.Command {
+	flags := pathFlag{}
+	command := &cobra.Command{
+		Use:   "unset <path>",
+		Short: "Remove a value from devbox.json",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := devbox.OpenConfigFile(flags.path)
+			if err != nil {
+				return err
+			}
+			removed, err := cfg.UnsetPath(args[0])
+			if err != nil {
+				return usererr.New("Unable to unset %s in devbox.json: %v", args[0], err)
+			}
+			if !removed {
+				return usererr.New("%s is not set in devbox.json", args[0])
+			}
+			return cfg.SaveTo(filepath.Dir(cfg.AbsRootPath))
+		},
+	}
+	flags.register(command)
+	return command
+}
+
+func configSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON schema for devbox.json",
+		Long: heredoc.Doc(`
+			Print the JSON schema for devbox.json. The schema is generated from
+			the same definitions that devbox uses to load and validate
+			devbox.json, so it always matches this version of devbox.
+		`),
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := cmd.OutOrStdout().Write(configfile.SchemaJSON())
+			return errors.WithStack(err)
+		},
+	}
+}
+
+func configValidateCmd() *cobra.Command {
+	flags := configValidateCmdFlags{}
+	command := &cobra.Command{
+		Use:   "validate",
+		Short: "Check devbox.json for mistakes",
+		Long: heredoc.Doc(`
+			Check devbox.json for unknown fields, values of the wrong type,
+			invalid platforms, invalid package names and includes that can't be
+			parsed. Each problem is reported with its line and column.
+
+			The command exits with a non-zero status if it finds any problems.
+		`),
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, problems, err := devbox.ValidateConfig(flags.config.path)
+			if err != nil {
+				return err
+			}
+
+			if flags.json {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(problems); err != nil {
+					return errors.WithStack(err)
+				}
+			} else {
+				for _, p := range problems {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s:%s\n", path, p)
+				}
+			}
+			if len(problems) > 0 {
+				return usererr.New("Found %d problems in %s.", len(problems), path)
+			}
+			if !flags.json {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s is valid.\n", path)
+			}
+			return nil
+		},
+	}
+	flags.config.register(command)
+	command.Flags().BoolVar(&flags.json, "json", false, "output in json format")
+	return command
+}
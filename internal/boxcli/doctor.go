@@ -0,0 +1,107 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+)
+
+type doctorCmdFlags struct {
+	config configFlags
+	json   bool
+}
+
+func doctorCmd() *cobra.Command {
+	flags := doctorCmdFlags{}
+	command := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose problems with Nix, your shell and your devbox project",
+		Long: heredoc.Doc(`
+			Diagnose common problems with your Nix installation, your shell and
+			the devbox project in the current directory.
+
+			devbox doctor checks that Nix is installed and recent enough, that
+			the Nix daemon is reachable, that Nix trusts your user and has the
+			experimental features that devbox uses, and that the Nix store is
+			writable. In a project, it also checks direnv, whether the installed
+			environment is up to date, and whether other directories in PATH
+			shadow the project's packages. Each check passes, warns or fails,
+			with a suggested fix.
+
+			With --json, devbox doctor prints a report that leaves out paths,
+			usernames and other private values, so that you can attach it to a
+			bug report or support request.
+
+			The command exits with a non-zero status if any check fails.
+		`),
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doctorCmdFunc(cmd, flags)
+		},
+	}
+
+	flags.config.register(command)
+	command.Flags().BoolVar(
+		&flags.json, "json", false,
+		"output a redacted report in json format, for sharing in bug reports")
+	return command
+}
+
+func doctorCmdFunc(cmd *cobra.Command, flags doctorCmdFlags) error {
+	report := devbox.Doctor(cmd.Context(), &devopt.Opts{
+		Dir:         flags.config.path,
+		Environment: flags.config.environment,
+		Stderr:      cmd.ErrOrStderr(),
+	})
+
+	if flags.json {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		// Keep placeholders like <redacted string> readable.
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(report.Redacted()); err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		printDoctorReport(cmd.OutOrStdout(), report)
+	}
+	if failed := report.Failed(); failed > 0 {
+		return usererr.New("%d of devbox doctor's checks failed.", failed)
+	}
+	return nil
+}
+
+var doctorStatusColors = map[devbox.DoctorStatus]*color.Color{
+	devbox.DoctorPass: color.New(color.FgHiGreen),
+	devbox.DoctorWarn: color.New(color.FgHiYellow),
+	devbox.DoctorFail: color.New(color.FgHiRed),
+	devbox.DoctorSkip: color.New(color.Faint),
+}
+
+func printDoctorReport(w io.Writer, report *devbox.DoctorReport) {
+	fmt.Fprintf(w, "Devbox %s (%s)\n\n", report.DevboxVersion, report.Platform)
+
+	width := 0
+	for _, c := range report.Checks {
+		width = max(width, len(c.Name))
+	}
+	for _, c := range report.Checks {
+		doctorStatusColors[c.Status].Fprintf(w, "[%s]", c.Status)
+		fmt.Fprintf(w, " %-*s  %s\n", width, c.Name, c.Message)
+		if c.Fix != "" && (c.Status == devbox.DoctorWarn || c.Status == devbox.DoctorFail) {
+			fmt.Fprintf(w, "       %-*s  Fix: %s\n", width, "", c.Fix)
+		}
+	}
+}
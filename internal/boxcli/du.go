@@ -0,0 +1,145 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+)
+
+type closureFlags struct {
+	top  int
+	why  string
+	json bool
+}
+
+func (flags *closureFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVar(
+		&flags.top, "top", 10,
+		"number of heaviest dependencies to show for each package (-1 shows all)",
+	)
+	cmd.Flags().StringVar(
+		&flags.why, "why", "",
+		"show why a dependency (name prefix, store hash or store name) is in the closure",
+	)
+	cmd.Flags().BoolVar(&flags.json, "json", false, "output in json format")
+}
+
+type duCmdFlags struct {
+	config  configFlags
+	closure closureFlags
+}
+
+func duCmd() *cobra.Command {
+	flags := duCmdFlags{}
+	command := &cobra.Command{
+		Use:   "du [pkg]...",
+		Short: "Show the disk usage of the packages in your devbox environment",
+		Long: heredoc.Doc(`
+			Show the Nix store closure size of each package in your devbox
+			environment along with its heaviest transitive dependencies.
+			If one or more packages are given, only those packages are reported.
+		`),
+		PreRunE: ensureNixInstalled,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return closureCmdFunc(cmd, args, flags.config, flags.closure)
+		},
+	}
+
+	flags.config.register(command)
+	flags.closure.register(command)
+	return command
+}
+
+func closureCmdFunc(
+	cmd *cobra.Command,
+	pkgs []string,
+	config configFlags,
+	flags closureFlags,
+) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:         config.path,
+		Environment: config.environment,
+		Stderr:      cmd.ErrOrStderr(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	report, err := box.Closures(cmd.Context(), devopt.ClosureOpts{
+		Pkgs: pkgs,
+		Top:  flags.top,
+		Why:  flags.why,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if flags.json {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printClosureReport(cmd.OutOrStdout(), report, flags.why)
+	return nil
+}
+
+func printClosureReport(w io.Writer, report *devbox.ClosureReport, why string) {
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tSIZE\tCLOSURE SIZE")
+	for _, pkg := range report.Packages {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", pkg.Package, formatBytes(pkg.Size), formatBytes(pkg.ClosureSize))
+	}
+	tw.Flush()
+	if len(report.Packages) > 1 {
+		fmt.Fprintf(w, "\nTotal closure size (shared dependencies counted once): %s\n",
+			formatBytes(report.ClosureSize))
+	}
+
+	for _, pkg := range report.Packages {
+		if len(pkg.Heaviest) > 0 {
+			fmt.Fprintf(w, "\nHeaviest dependencies of %s:\n", pkg.Package)
+			tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+			for _, dep := range pkg.Heaviest {
+				fmt.Fprintf(tw, "  %s\t%s\n", formatBytes(dep.Size), dep.StorePath)
+			}
+			tw.Flush()
+		}
+		if why == "" {
+			continue
+		}
+		if len(pkg.Why) == 0 {
+			fmt.Fprintf(w, "\n%s does not depend on %q\n", pkg.Package, why)
+			continue
+		}
+		fmt.Fprintf(w, "\nWhy %s depends on %q:\n", pkg.Package, why)
+		for _, path := range pkg.Why {
+			fmt.Fprintf(w, "  %s\n", strings.Join(path, "\n    -> "))
+		}
+	}
+}
+
+// formatBytes formats a byte count using binary (1024) units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+type gcCmdFlags struct {
+	config   configFlags
+	global   bool
+	dryRun   bool
+	nixStore bool
+	cacheAge time.Duration
+}
+
+func gcCmd() *cobra.Command {
+	flags := gcCmdFlags{}
+	command := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove old profile generations and stale caches to free disk space",
+		Long: heredoc.Doc(`
+			Remove the files that devbox no longer needs and report the disk space
+			that was reclaimed.
+
+			In a project, devbox gc removes the old generations of the project's
+			profile. If the environment is out of date, it also removes the
+			generated files in .devbox/gen and the cached environment, which are
+			recomputed the next time they're needed.
+
+			With --global, devbox gc removes the old generations of the global and
+			utility profiles, the profiles of projects whose devbox.json was deleted,
+			and files in devbox's cache directory that weren't modified recently.
+
+			Old generations keep their packages in the Nix store. Use --nix-store to
+			run nix store gc afterwards, which deletes the store paths that nothing
+			uses anymore.
+		`),
+		Args: cobra.ExactArgs(0),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if flags.nixStore {
+				return ensureNixInstalled(cmd, args)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return gcCmdFunc(cmd, flags)
+		},
+	}
+
+	flags.config.register(command)
+	command.Flags().BoolVar(
+		&flags.global, "global", false,
+		"clean up the global and utility profiles, deleted projects and devbox's caches")
+	command.Flags().BoolVar(
+		&flags.dryRun, "dry-run", false, "report what would be removed without removing anything")
+	command.Flags().BoolVar(
+		&flags.nixStore, "nix-store", false, "run nix store gc to delete unused store paths")
+	command.Flags().DurationVar(
+		&flags.cacheAge, "cache-age", devbox.DefaultGCCacheAge,
+		"with --global, remove cache files that weren't modified for this long")
+	return command
+}
+
+func gcCmdFunc(cmd *cobra.Command, flags gcCmdFlags) error {
+	opts := devopt.GCOpts{
+		DryRun:   flags.dryRun,
+		NixStore: flags.nixStore,
+		CacheAge: flags.cacheAge,
+	}
+
+	var report *devbox.GCReport
+	var err error
+	if flags.global {
+		report, err = devbox.GlobalGC(cmd.Context(), cmd.ErrOrStderr(), opts)
+	} else {
+		var box *devbox.Devbox
+		box, err = devbox.Open(&devopt.Opts{
+			Dir:         flags.config.path,
+			Environment: flags.config.environment,
+			Stderr:      cmd.ErrOrStderr(),
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		report, err = box.GC(cmd.Context(), cmd.ErrOrStderr(), opts)
+	}
+	if report != nil {
+		printGCReport(cmd.OutOrStdout(), report)
+		printGCSummary(cmd.ErrOrStderr(), report, flags)
+	}
+	return err
+}
+
+func printGCReport(w io.Writer, report *devbox.GCReport) {
+	if len(report.Items) == 0 {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SIZE\tREASON\tPATH")
+	for _, item := range report.Items {
+		size := "-"
+		if !item.GCRoot {
+			size = formatBytes(item.Size)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", size, item.Reason, item.Path)
+	}
+	_ = tw.Flush()
+}
+
+func printGCSummary(w io.Writer, report *devbox.GCReport, flags gcCmdFlags) {
+	if len(report.Items) == 0 {
+		ux.Fsuccess(w, "Nothing to remove.\n")
+		return
+	}
+	verb := "Removed"
+	if flags.dryRun {
+		verb = "Would remove"
+	}
+	ux.Fsuccess(w, "%s %d items (%s).\n",
+		verb, len(report.Items), formatBytes(report.ReclaimedBytes()))
+	if report.GCRoots() > 0 && !flags.nixStore {
+		ux.Finfo(w,
+			"The removed profiles still have packages in the Nix store. "+
+				"Run `devbox gc --nix-store` to delete the ones that nothing else uses.\n")
+	}
+}
@@ -60,6 +60,7 @@ func generateCmd() *cobra.Command {
 	command.AddCommand(dockerfileCmd())
 	command.AddCommand(debugCmd())
 	command.AddCommand(direnvCmd())
+	command.AddCommand(flakeCmd())
 	command.AddCommand(genReadmeCmd())
 	command.AddCommand(sshConfigCmd())
 	flags.config.register(command)
@@ -158,6 +159,26 @@ func direnvCmd() *cobra.Command {
 	return command
 }
 
+func flakeCmd() *cobra.Command {
+	flags := &generateCmdFlags{}
+	command := &cobra.Command{
+		Use:   "flake",
+		Short: "Generate a flake.nix that reproduces devbox shell with nix develop",
+		Long: "Generate a standalone flake.nix and flake.lock in the project directory. " +
+			"The flake's default dev shell has the project's packages, env, init_hook " +
+			"and plugin files, so it can be used with nix develop or Nix-based CI " +
+			"without installing devbox.",
+		Args: cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGenerateCmd(cmd, flags)
+		},
+	}
+	command.Flags().BoolVarP(
+		&flags.force, "force", "f", false, "force overwrite existing files")
+	flags.config.register(command)
+	return command
+}
+
 func sshConfigCmd() *cobra.Command {
 	flags := &generateCmdFlags{}
 	command := &cobra.Command{
@@ -285,6 +306,8 @@ func runGenerateCmd(cmd *cobra.Command, flags *generateCmdFlags) error {
 		return box.Generate(cmd.Context())
 	case "devcontainer":
 		return box.GenerateDevcontainer(cmd.Context(), generateOpts)
+	case "flake":
+		return box.GenerateFlake(cmd.Context(), generateOpts)
 	}
 	return nil
 }
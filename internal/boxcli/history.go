@@ -0,0 +1,118 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+type historyCmdFlags struct {
+	config configFlags
+}
+
+func historyCmd() *cobra.Command {
+	flags := historyCmdFlags{}
+	command := &cobra.Command{
+		Use:   "history",
+		Short: "List the recorded states of your devbox environment",
+		Long: heredoc.Doc(`
+			List the states of your devbox environment that devbox add, rm and
+			update recorded. Use devbox rollback to restore one of them.
+		`),
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			box, err := devbox.Open(&devopt.Opts{
+				Dir:         flags.config.path,
+				Environment: flags.config.environment,
+				Stderr:      cmd.ErrOrStderr(),
+			})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			entries, err := box.History()
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				fmt.Fprintln(cmd.ErrOrStderr(), "No history recorded yet.")
+				return nil
+			}
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tTIME\tGENERATION\tCOMMAND")
+			for i := len(entries) - 1; i >= 0; i-- {
+				entry := entries[i]
+				generation := "-"
+				if entry.Generation > 0 {
+					generation = strconv.Itoa(entry.Generation)
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n",
+					entry.ID, entry.Time.Local().Format("2006-01-02 15:04:05"), generation, entry.Command)
+			}
+			return errors.WithStack(tw.Flush())
+		},
+	}
+
+	flags.config.register(command)
+	return command
+}
+
+type rollbackCmdFlags struct {
+	config configFlags
+}
+
+func rollbackCmd() *cobra.Command {
+	flags := rollbackCmdFlags{}
+	command := &cobra.Command{
+		Use:   "rollback [id]",
+		Short: "Restore a previous state of your devbox environment",
+		Long: heredoc.Doc(`
+			Restore devbox.json, devbox.lock and the project's Nix profile from an
+			entry of devbox history. Without an ID, the state before the current
+			one is restored, so running devbox rollback again keeps going back.
+
+			The profile is switched to the generation that the entry recorded, so
+			nothing needs to be downloaded or built. If the generation was removed
+			by devbox gc, the packages are installed again.
+		`),
+		Args:    cobra.MaximumNArgs(1),
+		PreRunE: ensureNixInstalled,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id := 0
+			if len(args) > 0 {
+				var err error
+				if id, err = strconv.Atoi(args[0]); err != nil || id < 1 {
+					return usererr.New("Invalid history ID %q. Run `devbox history` to list them.", args[0])
+				}
+			}
+			box, err := devbox.Open(&devopt.Opts{
+				Dir:         flags.config.path,
+				Environment: flags.config.environment,
+				Stderr:      cmd.ErrOrStderr(),
+			})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			entry, err := box.Rollback(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			ux.Fsuccess(cmd.ErrOrStderr(), "Rolled back to history entry %d: %s\n", entry.ID, entry.Command)
+			return nil
+		},
+	}
+
+	flags.config.register(command)
+	return command
+}
@@ -9,13 +9,16 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
 	"go.jetpack.io/devbox/internal/devbox"
 	"go.jetpack.io/devbox/internal/devbox/devopt"
 )
 
 type infoCmdFlags struct {
-	config   configFlags
-	markdown bool
+	config      configFlags
+	markdown    bool
+	showClosure bool
+	closure     closureFlags
 }
 
 func infoCmd() *cobra.Command {
@@ -32,10 +35,24 @@ func infoCmd() *cobra.Command {
 
 	flags.config.register(command)
 	command.Flags().BoolVar(&flags.markdown, "markdown", false, "output in markdown format")
+	command.Flags().BoolVar(
+		&flags.showClosure, "closure", false,
+		"show the closure size and heaviest dependencies of a package in your project",
+	)
+	flags.closure.register(command)
 	return command
 }
 
 func infoCmdFunc(cmd *cobra.Command, pkg string, flags infoCmdFlags) error {
+	if flags.showClosure {
+		return closureCmdFunc(cmd, []string{pkg}, flags.config, flags.closure)
+	}
+	for _, name := range []string{"top", "why", "json"} {
+		if cmd.Flags().Changed(name) {
+			return usererr.New("--%s can only be used with --closure", name)
+		}
+	}
+
 	box, err := devbox.Open(&devopt.Opts{
 		Dir:         flags.config.path,
 		Environment: flags.config.environment,
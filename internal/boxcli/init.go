@@ -4,31 +4,160 @@
 package boxcli
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/mattn/go-isatty"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
+	"go.jetpack.io/devbox/internal/autodetect"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
 	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+	"go.jetpack.io/devbox/internal/fileutil"
 )
 
+type initCmdFlags struct {
+	auto bool
+}
+
 func initCmd() *cobra.Command {
+	flags := initCmdFlags{}
 	command := &cobra.Command{
 		Use:   "init [<dir>]",
 		Short: "Initialize a directory as a devbox project",
 		Long: "Initialize a directory as a devbox project. " +
 			"This will create an empty devbox.json in the current directory. " +
-			"You can then add packages using `devbox add`",
+			"You can then add packages using `devbox add`.\n\n" +
+			"With --auto, devbox looks for files such as go.mod, package.json, " +
+			".nvmrc, .python-version, pyproject.toml, Gemfile, .ruby-version, " +
+			"Cargo.toml, rust-toolchain and .tool-versions, and proposes " +
+			"matching packages and scripts for you to confirm.",
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runInitCmd(args)
+			return runInitCmd(cmd, args, flags)
 		},
 	}
 
+	command.Flags().BoolVar(
+		&flags.auto, "auto", false,
+		"detect the packages and scripts the project needs from its files",
+	)
 	return command
 }
 
-func runInitCmd(args []string) error {
+func runInitCmd(cmd *cobra.Command, args []string, flags initCmdFlags) error {
 	path := pathArg(args)
+	if !flags.auto {
+		_, err := devbox.InitConfig(path)
+		return errors.WithStack(err)
+	}
+
+	dir := path
+	if dir == "" {
+		dir = "."
+	}
+	if fileutil.Exists(filepath.Join(dir, configfile.DefaultName)) {
+		return usererr.New("%s already exists in %s.", configfile.DefaultName, dir)
+	}
+
+	proposal, err := autodetect.Detect(dir)
+	if err != nil {
+		return usererr.WithUserMessage(err, "Unable to detect the project's packages.")
+	}
+	if proposal.Empty() {
+		fmt.Fprintln(cmd.ErrOrStderr(), "No supported project files found. Creating a default devbox.json.")
+		_, err := devbox.InitConfig(path)
+		return errors.WithStack(err)
+	}
+
+	if isatty.IsTerminal(os.Stdin.Fd()) {
+		if err := confirmProposal(proposal); err != nil {
+			return err
+		}
+	} else {
+		printProposal(cmd.ErrOrStderr(), proposal)
+	}
+
+	if _, err := devbox.InitConfigFromProposal(path, proposal); err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Fprintf(
+		cmd.ErrOrStderr(),
+		"Created %s with %d packages and %d scripts. Run `devbox shell` to start using them.\n",
+		configfile.DefaultName, len(proposal.Packages), len(proposal.Scripts),
+	)
+	return nil
+}
 
-	_, err := devbox.InitConfig(path)
-	return errors.WithStack(err)
+// confirmProposal asks the user which of the proposed packages and scripts
+// to keep, and removes the rest from the proposal.
+func confirmProposal(proposal *autodetect.Proposal) error {
+	if len(proposal.Packages) > 0 {
+		options := make([]string, len(proposal.Packages))
+		for i, pkg := range proposal.Packages {
+			options[i] = fmt.Sprintf("%s (from %s)", pkg, pkg.Source)
+		}
+		keep, err := askMultiSelect("Select the packages to add:", options)
+		if err != nil {
+			return err
+		}
+		proposal.Packages = filterByIndex(proposal.Packages, keep)
+	}
+
+	if len(proposal.Scripts) > 0 {
+		options := make([]string, len(proposal.Scripts))
+		for i, script := range proposal.Scripts {
+			options[i] = fmt.Sprintf("%s: %s (from %s)", script.Name, script.Command, script.Source)
+		}
+		keep, err := askMultiSelect("Select the scripts to add:", options)
+		if err != nil {
+			return err
+		}
+		proposal.Scripts = filterByIndex(proposal.Scripts, keep)
+	}
+	return nil
+}
+
+// askMultiSelect prompts the user to select from options, all of which are
+// selected by default. It returns the indexes of the selected options.
+func askMultiSelect(message string, options []string) ([]int, error) {
+	selected := []string{}
+	prompt := &survey.MultiSelect{
+		Message: message,
+		Options: options,
+		Default: options,
+	}
+	if err := survey.AskOne(prompt, &selected); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	indexes := make([]int, 0, len(selected))
+	for _, s := range selected {
+		indexes = append(indexes, slices.Index(options, s))
+	}
+	return indexes, nil
+}
+
+func filterByIndex[T any](items []T, keep []int) []T {
+	filtered := make([]T, 0, len(keep))
+	for i, item := range items {
+		if slices.Contains(keep, i) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+func printProposal(w io.Writer, proposal *autodetect.Proposal) {
+	for _, pkg := range proposal.Packages {
+		fmt.Fprintf(w, "Adding package %s (from %s)\n", pkg, pkg.Source)
+	}
+	for _, script := range proposal.Scripts {
+		fmt.Fprintf(w, "Adding script %s: %s (from %s)\n", script.Name, script.Command, script.Source)
+	}
 }
@@ -16,8 +16,11 @@ import (
 func installCmd() *cobra.Command {
 	flags := runCmdFlags{}
 	command := &cobra.Command{
-		Use:     "install",
-		Short:   "Install all packages mentioned in devbox.json",
+		Use:   "install",
+		Short: "Install all packages mentioned in devbox.json",
+		Long: "Install all packages mentioned in devbox.json.\n\n" +
+			"Only packages in the default package group are installed unless " +
+			"--with or --only selects other groups.",
 		Args:    cobra.MaximumNArgs(0),
 		PreRunE: ensureNixInstalled,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -26,15 +29,21 @@ func installCmd() *cobra.Command {
 	}
 
 	flags.config.register(command)
+	flags.groups.register(command)
+	flags.progress.register(command)
 
 	return command
 }
 
 func installCmdFunc(cmd *cobra.Command, flags runCmdFlags) error {
+	if err := flags.progress.init(cmd); err != nil {
+		return err
+	}
 	// Check the directory exists.
 	box, err := devbox.Open(&devopt.Opts{
 		Dir:         flags.config.path,
 		Environment: flags.config.environment,
+		Groups:      flags.groups.opts(),
 		Stderr:      cmd.ErrOrStderr(),
 	})
 	if err != nil {
@@ -0,0 +1,102 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+)
+
+type licensesCmdFlags struct {
+	config configFlags
+	json   bool
+}
+
+func licensesCmd() *cobra.Command {
+	flags := licensesCmdFlags{}
+	command := &cobra.Command{
+		Use:   "licenses",
+		Short: "Show the licenses of the packages in your devbox environment",
+		Long: heredoc.Doc(`
+			Show the licenses of the packages in your devbox environment from
+			their nixpkgs metadata, and check them against the license_policy
+			in devbox.json.
+
+			The command exits with a non-zero status if any package doesn't
+			comply with the policy.
+		`),
+		Args:    cobra.ExactArgs(0),
+		PreRunE: ensureNixInstalled,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return licensesCmdFunc(cmd, flags)
+		},
+	}
+
+	flags.config.register(command)
+	command.Flags().BoolVar(&flags.json, "json", false, "output in json format")
+	return command
+}
+
+func licensesCmdFunc(cmd *cobra.Command, flags licensesCmdFlags) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:         flags.config.path,
+		Environment: flags.config.environment,
+		Stderr:      cmd.ErrOrStderr(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	report, err := box.Licenses(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if flags.json {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		printLicenseReport(cmd.OutOrStdout(), report)
+	}
+
+	violating := lo.CountBy(report.Packages, func(p devbox.PackageLicenses) bool {
+		return len(p.Violations) > 0
+	})
+	if violating > 0 {
+		return usererr.New("%d packages don't comply with the license_policy in devbox.json.", violating)
+	}
+	return nil
+}
+
+func printLicenseReport(w io.Writer, report *devbox.LicenseReport) {
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tLICENSES\tUNFREE\tPOLICY")
+	for _, pkg := range report.Packages {
+		licenses := strings.Join(pkg.Licenses, ", ")
+		if licenses == "" {
+			licenses = "unknown"
+		}
+		policy := "ok"
+		if len(pkg.Violations) > 0 {
+			policy = strings.Join(pkg.Violations, "; ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%s\n", pkg.Package, licenses, pkg.Unfree, policy)
+	}
+	tw.Flush()
+}
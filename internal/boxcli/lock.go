@@ -0,0 +1,110 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+type lockCmdFlags struct {
+	config         configFlags
+	checkPlatforms bool
+}
+
+func lockCmd() *cobra.Command {
+	flags := lockCmdFlags{}
+	command := &cobra.Command{
+		Use:   "lock",
+		Short: "Resolve the project's packages and update devbox.lock without installing them",
+		Long: heredoc.Doc(`
+			Resolve the project's packages and update devbox.lock without
+			installing them.
+
+			If devbox.json has a platforms field, devbox lock also records the
+			outputs of each package for every system in it, so that the lockfile
+			works for everyone on the team. Packages are only locked for the
+			systems that their platforms and excluded_platforms fields enable.
+
+			With --check-platforms, devbox lock fails if a package has no outputs
+			for one of the systems. Run it in CI to catch packages that aren't
+			available on another developer's system.
+		`),
+		Args:    cobra.ExactArgs(0),
+		PreRunE: ensureNixInstalled,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lockCmdFunc(cmd, flags)
+		},
+	}
+
+	flags.config.register(command)
+	command.Flags().BoolVar(
+		&flags.checkPlatforms, "check-platforms", false,
+		"fail if a package has no outputs for a system in the platforms field of devbox.json")
+	return command
+}
+
+func lockCmdFunc(cmd *cobra.Command, flags lockCmdFlags) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:         flags.config.path,
+		Environment: flags.config.environment,
+		Stderr:      cmd.ErrOrStderr(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	report, err := box.Lock(cmd.Context(), devopt.LockOpts{CheckPlatforms: flags.checkPlatforms})
+	if err != nil {
+		return err
+	}
+	if !flags.checkPlatforms {
+		ux.Fsuccess(cmd.ErrOrStderr(), "Updated devbox.lock.\n")
+		return nil
+	}
+
+	if len(report.Unchecked) > 0 {
+		ux.Fwarning(cmd.ErrOrStderr(),
+			"devbox.lock doesn't record the outputs of these packages per system, so they weren't checked: %s\n",
+			strings.Join(report.Unchecked, ", "))
+	}
+	if len(report.Gaps) > 0 {
+		printPlatformGaps(cmd.OutOrStdout(), report.Gaps)
+		return usererr.New(
+			"%d packages aren't available on all of the project's platforms. "+
+				"Change their versions, or use their platforms or excluded_platforms fields "+
+				"to install them only where they're available.",
+			countGapPackages(report.Gaps))
+	}
+	ux.Fsuccess(cmd.ErrOrStderr(),
+		"All packages are available on %s.\n", strings.Join(report.Platforms, ", "))
+	return nil
+}
+
+func printPlatformGaps(w io.Writer, gaps []devbox.PlatformGap) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tSYSTEM\tPROBLEM")
+	for _, gap := range gaps {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", gap.Package, gap.System, gap.Problem)
+	}
+	_ = tw.Flush()
+}
+
+func countGapPackages(gaps []devbox.PlatformGap) int {
+	packages := map[string]bool{}
+	for _, gap := range gaps {
+		packages[gap.Package] = true
+	}
+	return len(packages)
+}
@@ -99,10 +99,12 @@ func getPackagesAndCommitHash(c *cobra.Command) ([]string, string) {
 		path = configFlag.Value.String()
 	}
 
+	// Telemetry only reads the project, so it shouldn't modernize it.
 	box, err := devbox.Open(&devopt.Opts{
-		Dir:            path,
-		Stderr:         os.Stderr,
-		IgnoreWarnings: true,
+		Dir:             path,
+		Stderr:          os.Stderr,
+		IgnoreWarnings:  true,
+		SkipAutoMigrate: true,
 	})
 	if err != nil {
 		return []string{}, ""
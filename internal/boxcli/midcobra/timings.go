@@ -0,0 +1,147 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package midcobra
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+// TimingsMiddleware records the spans of debug timers while a command runs,
+// and reports where the command spent its time when it exits.
+type TimingsMiddleware struct {
+	printFlag  *pflag.Flag
+	exportFlag *pflag.Flag
+	formatFlag *pflag.Flag
+
+	start time.Time
+}
+
+var _ Middleware = (*TimingsMiddleware)(nil)
+
+func (t *TimingsMiddleware) AttachToFlags(flags *pflag.FlagSet) {
+	flags.Bool("timings", false, "print how long each part of the command took")
+	flags.String("timings-export", "",
+		"write the timings to a file, or send them to an OpenTelemetry (OTLP/HTTP) endpoint if it's a URL")
+	flags.String("timings-format", timingsFormatChrome,
+		"format of the --timings-export file: chrome (trace event JSON) or otlp (OpenTelemetry JSON)")
+	t.printFlag = flags.Lookup("timings")
+	t.exportFlag = flags.Lookup("timings-export")
+	t.formatFlag = flags.Lookup("timings-format")
+}
+
+func (t *TimingsMiddleware) preRun(_ *cobra.Command, args []string) {
+	if t == nil || !timingsRequested(args) {
+		return
+	}
+	debug.RecordTimings()
+	t.start = time.Now()
+}
+
+// timingsRequested returns true if args have one of the timings flags. They
+// can't be read from the flags yet, because the root command stops parsing
+// them at the first flag of a subcommand.
+func timingsRequested(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		if name == "--timings" || name == "--timings-export" {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TimingsMiddleware) postRun(cmd *cobra.Command, args []string, _ error) {
+	if t.start.IsZero() {
+		return
+	}
+	name := cmd.CommandPath()
+	if sub, _, err := cmd.Find(args); err == nil {
+		name = sub.CommandPath()
+	}
+	tree := buildTimingsTree(debug.Span{Name: name, Start: t.start, End: time.Now()}, debug.Timings())
+
+	w := cmd.ErrOrStderr()
+	if t.printFlag.Value.String() == "true" {
+		printTimings(w, tree)
+	}
+	if target := t.exportFlag.Value.String(); target != "" {
+		if err := exportTimings(cmd.Context(), tree, target, t.formatFlag.Value.String()); err != nil {
+			ux.Fwarning(w, "Failed to export timings: %v\n", err)
+		}
+	}
+}
+
+// timingsNode is a span and the spans that ran during it.
+type timingsNode struct {
+	debug.Span
+	children []*timingsNode
+}
+
+// buildTimingsTree nests each span under the innermost span that contains it.
+// Timers don't know which function called them, so the hierarchy is inferred
+// from their times. Spans that run concurrently, such as resolving packages,
+// are siblings unless one happens to run entirely during another.
+func buildTimingsTree(root debug.Span, spans []debug.Span) *timingsNode {
+	// Timers record their spans when they end, so of two spans with the
+	// same times, the later one is the parent.
+	spans = slices.Clone(spans)
+	slices.Reverse(spans)
+	slices.SortStableFunc(spans, func(a, b debug.Span) int {
+		if c := a.Start.Compare(b.Start); c != 0 {
+			return c
+		}
+		// Longer spans come first so that they become the parents.
+		return b.End.Compare(a.End)
+	})
+
+	tree := &timingsNode{Span: root}
+	stack := []*timingsNode{tree}
+	for _, span := range spans {
+		for len(stack) > 1 && !stack[len(stack)-1].contains(span) {
+			stack = stack[:len(stack)-1]
+		}
+		node := &timingsNode{Span: span}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+		stack = append(stack, node)
+	}
+	return tree
+}
+
+func (n *timingsNode) contains(span debug.Span) bool {
+	return !span.Start.Before(n.Start) && !span.End.After(n.End)
+}
+
+func (n *timingsNode) duration() time.Duration {
+	return n.End.Sub(n.Start)
+}
+
+// printTimings prints the tree with the spans that took at least a millisecond.
+func printTimings(w io.Writer, tree *timingsNode) {
+	fmt.Fprintln(w, "\nTimings:")
+	var printNode func(n *timingsNode, depth int)
+	printNode = func(n *timingsNode, depth int) {
+		fmt.Fprintf(w, "%10s  %s%s\n",
+			n.duration().Round(time.Millisecond), strings.Repeat("  ", depth), n.Name)
+		for _, child := range n.children {
+			if child.duration() >= time.Millisecond {
+				printNode(child, depth+1)
+			}
+		}
+	}
+	printNode(tree, 0)
+}
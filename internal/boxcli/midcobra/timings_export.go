@@ -0,0 +1,199 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package midcobra
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.jetpack.io/devbox/internal/build"
+	"go.jetpack.io/devbox/internal/redact"
+)
+
+const (
+	timingsFormatChrome = "chrome"
+	timingsFormatOTLP   = "otlp"
+)
+
+// exportTimings writes the tree to a file in the given format. If target is
+// an http or https URL, the spans are sent to it as OTLP/HTTP JSON instead.
+func exportTimings(ctx context.Context, tree *timingsNode, target, format string) error {
+	if u, err := url.Parse(target); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		return postOTLP(ctx, u, otlpTraces(tree))
+	}
+
+	var data any
+	switch format {
+	case timingsFormatChrome:
+		data = chromeTrace(tree)
+	case timingsFormatOTLP:
+		data = otlpTraces(tree)
+	default:
+		return redact.Errorf("unknown timings format %q, use chrome or otlp", format)
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return redact.Errorf("marshal timings: %v", err)
+	}
+	return os.WriteFile(target, b, 0o644)
+}
+
+// chromeEvent is a complete event of the Chrome trace event format, which
+// chrome://tracing, Perfetto and speedscope can open.
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type chromeEvent struct {
+	Name  string `json:"name"`
+	Phase string `json:"ph"`
+	// Timestamp and Duration are in microseconds. Timestamps are relative
+	// to the start of the command.
+	Timestamp int64 `json:"ts"`
+	Duration  int64 `json:"dur"`
+	PID       int   `json:"pid"`
+	TID       int   `json:"tid"`
+}
+
+func chromeTrace(tree *timingsNode) any {
+	tid := 1
+	return map[string]any{
+		"displayTimeUnit": "ms",
+		"traceEvents":     tree.chromeEvents(tree.Start, 1, &tid, nil),
+	}
+}
+
+// chromeEvents appends the events of n and its children to events. The trace
+// viewers expect the events of a thread to nest, so each child that overlaps
+// an earlier sibling, such as a package that's resolved concurrently, goes on
+// a thread of its own.
+func (n *timingsNode) chromeEvents(origin time.Time, tid int, lastTID *int, events []chromeEvent) []chromeEvent {
+	events = append(events, chromeEvent{
+		Name:      n.Name,
+		Phase:     "X",
+		Timestamp: n.Start.Sub(origin).Microseconds(),
+		Duration:  n.duration().Microseconds(),
+		PID:       1,
+		TID:       tid,
+	})
+
+	type lane struct {
+		tid int
+		end time.Time
+	}
+	lanes := []lane{{tid: tid}}
+	for _, child := range n.children {
+		i := slices.IndexFunc(lanes, func(l lane) bool { return !child.Start.Before(l.end) })
+		if i == -1 {
+			*lastTID++
+			lanes = append(lanes, lane{tid: *lastTID})
+			i = len(lanes) - 1
+		}
+		lanes[i].end = child.End
+		events = child.chromeEvents(origin, lanes[i].tid, lastTID, events)
+	}
+	return events
+}
+
+// otlpSpan is a span in the JSON encoding of the OpenTelemetry protocol.
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type otlpSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId,omitempty"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	// The times are 64-bit integers, which the JSON encoding represents as
+	// strings.
+	StartTimeUnixNano string `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string `json:"endTimeUnixNano"`
+}
+
+// otlpSpanKindInternal is SPAN_KIND_INTERNAL.
+const otlpSpanKindInternal = 1
+
+func otlpTraces(tree *timingsNode) any {
+	traceID := randomHex(16)
+	var spans []otlpSpan
+	var addSpans func(n *timingsNode, parentID string)
+	addSpans = func(n *timingsNode, parentID string) {
+		span := otlpSpan{
+			TraceID:           traceID,
+			SpanID:            randomHex(8),
+			ParentSpanID:      parentID,
+			Name:              n.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(n.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(n.End.UnixNano(), 10),
+		}
+		spans = append(spans, span)
+		for _, child := range n.children {
+			addSpans(child, span.SpanID)
+		}
+	}
+	addSpans(tree, "")
+
+	stringAttr := func(key, value string) map[string]any {
+		return map[string]any{"key": key, "value": map[string]any{"stringValue": value}}
+	}
+	return map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []any{
+					stringAttr("service.name", "devbox"),
+					stringAttr("service.version", build.Version),
+				},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "go.jetpack.io/devbox"},
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+// postOTLP sends traces to an OTLP/HTTP endpoint. If the URL has no path, the
+// traces are sent to the default /v1/traces path.
+func postOTLP(ctx context.Context, endpoint *url.URL, traces any) error {
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		endpoint = endpoint.JoinPath("v1", "traces")
+	}
+	body, err := json.Marshal(traces)
+	if err != nil {
+		return redact.Errorf("marshal timings: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s responded with %s: %s", endpoint.Redacted(), resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	// crypto/rand doesn't fail on the systems that devbox supports.
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
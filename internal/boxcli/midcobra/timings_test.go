@@ -0,0 +1,140 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package midcobra
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.jetpack.io/devbox/internal/debug"
+)
+
+func testSpan(name string, start, end int) debug.Span {
+	origin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return debug.Span{
+		Name:  name,
+		Start: origin.Add(time.Duration(start) * time.Millisecond),
+		End:   origin.Add(time.Duration(end) * time.Millisecond),
+	}
+}
+
+// testTree is a command that opens a project and resolves two packages
+// concurrently.
+func testTree() *timingsNode {
+	// Timers record their spans when they end, so children come first.
+	return buildTimingsTree(testSpan("devbox add", 0, 100), []debug.Span{
+		testSpan("devconfig.Open", 1, 5),
+		testSpan("devbox.Open", 0, 10),
+		testSpan("resolve go@latest", 20, 60),
+		testSpan("resolve jq@latest", 25, 50),
+		testSpan("lock.(*File).ResolveAll", 20, 60),
+	})
+}
+
+func TestBuildTimingsTree(t *testing.T) {
+	var got []string
+	var walk func(n *timingsNode, prefix string)
+	walk = func(n *timingsNode, prefix string) {
+		got = append(got, prefix+n.Name)
+		for _, child := range n.children {
+			walk(child, prefix+"  ")
+		}
+	}
+	walk(testTree(), "")
+
+	want := []string{
+		"devbox add",
+		"  devbox.Open",
+		"    devconfig.Open",
+		"  lock.(*File).ResolveAll",
+		"    resolve go@latest",
+		// It ran concurrently, but entirely while go@latest resolved.
+		"      resolve jq@latest",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got tree\n%q\nwant\n%q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got tree\n%q\nwant\n%q", got, want)
+			break
+		}
+	}
+}
+
+func TestChromeEventsOverlappingSiblings(t *testing.T) {
+	tree := buildTimingsTree(testSpan("devbox update", 0, 100), []debug.Span{
+		testSpan("resolve go@latest", 10, 40),
+		testSpan("resolve jq@latest", 20, 50),
+		testSpan("resolve vim@latest", 45, 60),
+	})
+	tid := 1
+	events := tree.chromeEvents(tree.Start, 1, &tid, nil)
+
+	want := map[string]int{
+		"devbox update":      1,
+		"resolve go@latest":  1,
+		"resolve jq@latest":  2,
+		"resolve vim@latest": 1,
+	}
+	for _, e := range events {
+		if e.TID != want[e.Name] {
+			t.Errorf("event %s is on thread %d, want %d", e.Name, e.TID, want[e.Name])
+		}
+	}
+	if got := events[2]; got.Timestamp != 20000 || got.Duration != 30000 {
+		t.Errorf("got ts %d and dur %d for %s, want 20000 and 30000", got.Timestamp, got.Duration, got.Name)
+	}
+}
+
+func TestPostOTLP(t *testing.T) {
+	var got struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []otlpSpan
+			}
+		}
+	}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	endpoint, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := postOTLP(context.Background(), endpoint, otlpTraces(testTree())); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v1/traces" {
+		t.Errorf("got path %q, want /v1/traces", path)
+	}
+
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 6 {
+		t.Fatalf("got %d spans, want 6", len(spans))
+	}
+	ids := map[string]string{}
+	for _, span := range spans {
+		ids[span.Name] = span.SpanID
+		if span.TraceID != spans[0].TraceID {
+			t.Errorf("span %s has trace ID %s, want %s", span.Name, span.TraceID, spans[0].TraceID)
+		}
+	}
+	for _, span := range spans {
+		if span.Name == "devconfig.Open" && span.ParentSpanID != ids["devbox.Open"] {
+			t.Errorf("devconfig.Open has parent %s, want devbox.Open's ID %s", span.ParentSpanID, ids["devbox.Open"])
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+type migrateCmdFlags struct {
+	config configFlags
+	check  bool
+}
+
+func migrateCmd() *cobra.Command {
+	flags := migrateCmdFlags{}
+	command := &cobra.Command{
+		Use:   "migrate",
+		Short: "Rewrite legacy devbox.json and devbox.lock settings in the current format",
+		Long: heredoc.Doc(`
+			Find settings in devbox.json, devbox.lock and local plugins that use a
+			legacy format, explain why each one is deprecated, and rewrite them in
+			the current format. Unversioned packages are resolved to explicit
+			versions with the Devbox search index.
+
+			Use --check in CI to fail if the project has legacy settings without
+			changing any files.
+		`),
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return migrateCmdFunc(cmd, flags)
+		},
+	}
+
+	flags.config.register(command)
+	command.Flags().BoolVar(
+		&flags.check, "check", false,
+		"report legacy settings without changing any files, and fail if there are any")
+	return command
+}
+
+func migrateCmdFunc(cmd *cobra.Command, flags migrateCmdFlags) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:            flags.config.path,
+		Environment:    flags.config.environment,
+		Stderr:         cmd.ErrOrStderr(),
+		IgnoreWarnings: true,
+		// Migrate reports and applies the automatic migrations itself.
+		SkipAutoMigrate: true,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	migrations, err := box.Migrate(cmd.Context(), devopt.MigrateOpts{Check: flags.check})
+	printMigrations(cmd.OutOrStdout(), migrations)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(migrations) == 0:
+		ux.Fsuccess(cmd.ErrOrStderr(), "The project doesn't use any legacy settings.\n")
+	case flags.check:
+		return usererr.New(
+			"Found %d legacy settings. Run `devbox migrate` to rewrite them in the current format.",
+			len(migrations))
+	default:
+		ux.Fsuccess(cmd.ErrOrStderr(), "Migrated %d legacy settings. Please commit the changes.\n", len(migrations))
+	}
+	return nil
+}
+
+func printMigrations(w io.Writer, migrations []*devbox.Migration) {
+	for i, m := range migrations {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\n  %s\n", m.Title, m.Reason)
+		for _, change := range m.Changes {
+			fmt.Fprintf(w, "  - %s\n", change)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package multi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+)
+
+// RunOpts are the options for running a script in the members of a
+// workspace.
+type RunOpts struct {
+	Script string
+	Args   []string
+
+	// Parallel is the maximum number of members that run the script at the
+	// same time. Values less than 1 run every member at once.
+	Parallel int
+
+	// Output receives the output of every member. Each line is prefixed
+	// with the member's directory.
+	Output io.Writer
+}
+
+// RunResult is the outcome of running a script in a workspace member.
+type RunResult struct {
+	Member   string
+	Skipped  bool
+	Err      error
+	Duration time.Duration
+}
+
+// RunScript runs a script in every workspace member that defines it. Each
+// member runs in its own devbox run process, so members don't share their
+// environment. It returns a result for every member, in the order of
+// MemberDirs, even if the script fails in some of them.
+func (ws *Workspace) RunScript(ctx context.Context, opts RunOpts) ([]RunResult, error) {
+	defer debug.FunctionTimer().End()
+
+	boxes, err := ws.Open(&devopt.Opts{Stderr: io.Discard, IgnoreWarnings: true})
+	if err != nil {
+		return nil, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	dirs, err := ws.MemberDirs()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]RunResult, len(dirs))
+	out := &syncWriter{w: opts.Output}
+	group, ctx := errgroup.WithContext(ctx)
+	if opts.Parallel > 0 {
+		group.SetLimit(opts.Parallel)
+	}
+	for i, dir := range dirs {
+		results[i].Member = dir
+		if !slices.Contains(boxes[dir].ListScripts(), opts.Script) {
+			results[i].Skipped = true
+			continue
+		}
+		group.Go(func() error {
+			start := time.Now()
+			args := append([]string{"run", "--config", filepath.Join(ws.Root, dir), "--", opts.Script}, opts.Args...)
+			cmd := exec.CommandContext(ctx, exe, args...)
+			w := out.prefixed(dir)
+			cmd.Stdout = w
+			cmd.Stderr = w
+			results[i].Err = cmd.Run()
+			results[i].Duration = time.Since(start)
+			w.flush()
+			// Failures are reported in the results, so they don't stop
+			// the other members.
+			return nil
+		})
+	}
+	return results, group.Wait()
+}
+
+// syncWriter serializes writes to w from members that run in parallel.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) prefixed(prefix string) *prefixWriter {
+	return &prefixWriter{out: s, prefix: []byte("[" + prefix + "] ")}
+}
+
+// prefixWriter writes complete lines to a syncWriter with a prefix, so that
+// the output of different members isn't interleaved within a line.
+type prefixWriter struct {
+	out    *syncWriter
+	prefix []byte
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	i := bytes.LastIndexByte(p.buf, '\n')
+	if i < 0 {
+		return len(b), nil
+	}
+	if err := p.writeLines(p.buf[:i+1]); err != nil {
+		return 0, err
+	}
+	p.buf = slices.Clone(p.buf[i+1:])
+	return len(b), nil
+}
+
+// flush writes the last line if it doesn't end with a newline.
+func (p *prefixWriter) flush() {
+	if len(p.buf) > 0 {
+		_ = p.writeLines(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) writeLines(lines []byte) error {
+	var prefixed bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+		if len(line) > 0 {
+			prefixed.Write(p.prefix)
+			prefixed.Write(line)
+		}
+	}
+	p.out.mu.Lock()
+	defer p.out.mu.Unlock()
+	_, err := p.out.w.Write(prefixed.Bytes())
+	return errors.WithStack(err)
+}
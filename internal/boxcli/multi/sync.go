@@ -17,7 +17,10 @@ func SyncLockfiles(pkgs []string) error {
 	if err != nil {
 		return err
 	}
+	return syncLockfiles(lockfilePaths, pkgs)
+}
 
+func syncLockfiles(lockfilePaths, pkgs []string) error {
 	latestPackages, err := latestPackages(lockfilePaths)
 	if err != nil {
 		return err
@@ -0,0 +1,176 @@
+package multi
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+)
+
+// WorkspaceFileName is the name of the file at the root of a workspace that
+// lists its member projects.
+const WorkspaceFileName = "devbox-workspace.json"
+
+// Workspace is a group of Devbox projects, such as the services of a
+// monorepo, that are managed together.
+type Workspace struct {
+	// Root is the directory with the workspace file.
+	Root string `json:"-"`
+
+	// Members are the directories of the member projects, relative to
+	// Root. They can be glob patterns, such as "services/*", which match
+	// every directory with a devbox.json.
+	Members []string `json:"members"`
+}
+
+// FindWorkspace finds the workspace file in dir or the closest parent
+// directory of dir.
+func FindWorkspace(dir string) (*Workspace, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for {
+		path := filepath.Join(dir, WorkspaceFileName)
+		if _, err := os.Stat(path); err == nil {
+			return LoadWorkspace(path)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, usererr.New(
+				"No %s found in this directory or its parents. "+
+					"Run `devbox workspace init` to create one.", WorkspaceFileName)
+		}
+		dir = parent
+	}
+}
+
+// LoadWorkspace reads a workspace file.
+func LoadWorkspace(path string) (*Workspace, error) {
+	ws := &Workspace{}
+	if err := cuecfg.ParseFile(path, ws); err != nil {
+		return nil, usererr.WithUserMessage(err, "Failed to parse %s.", path)
+	}
+	ws.Root = filepath.Dir(path)
+	return ws, nil
+}
+
+// InitWorkspace creates a workspace file in dir whose members are the
+// projects in dir and its subdirectories. It returns false if the file
+// already exists.
+func InitWorkspace(dir string) (*Workspace, bool, error) {
+	members, err := findProjects(dir)
+	if err != nil {
+		return nil, false, err
+	}
+	ws := &Workspace{Root: dir, Members: members}
+	created, err := cuecfg.InitFile(filepath.Join(dir, WorkspaceFileName), ws)
+	return ws, created, err
+}
+
+// MemberDirs returns the directories of the member projects relative to the
+// workspace root, sorted and without duplicates.
+func (ws *Workspace) MemberDirs() ([]string, error) {
+	dirs := []string{}
+	for _, member := range ws.Members {
+		pattern := filepath.Join(ws.Root, filepath.FromSlash(member))
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, usererr.New("Invalid member %q in %s: %v", member, WorkspaceFileName, err)
+		}
+		found := false
+		for _, match := range matches {
+			if _, err := os.Stat(filepath.Join(match, configfile.DefaultName)); err != nil {
+				continue
+			}
+			rel, err := filepath.Rel(ws.Root, match)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			dirs = append(dirs, filepath.ToSlash(rel))
+			found = true
+		}
+		// A glob can match no projects, but a plain directory that's
+		// missing its devbox.json is probably a mistake.
+		if !found && !hasGlobMeta(member) {
+			return nil, usererr.New(
+				"Member %q in %s doesn't have a %s.", member, WorkspaceFileName, configfile.DefaultName)
+		}
+	}
+	slices.Sort(dirs)
+	return slices.Compact(dirs), nil
+}
+
+// Open opens the member projects of the workspace.
+func (ws *Workspace) Open(opts *devopt.Opts) (map[string]*devbox.Devbox, error) {
+	dirs, err := ws.MemberDirs()
+	if err != nil {
+		return nil, err
+	}
+	boxes := map[string]*devbox.Devbox{}
+	for _, dir := range dirs {
+		optsCopy := *opts
+		optsCopy.Dir = filepath.Join(ws.Root, dir)
+		box, err := devbox.Open(&optsCopy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "open workspace member %s", dir)
+		}
+		boxes[dir] = box
+	}
+	return boxes, nil
+}
+
+// SyncLockfiles aligns the versions of packages in the lockfiles of the
+// workspace members to the latest version that any member has locked. If
+// pkgs isn't empty, only those packages are synced.
+func (ws *Workspace) SyncLockfiles(pkgs []string) error {
+	dirs, err := ws.MemberDirs()
+	if err != nil {
+		return err
+	}
+	lockfilePaths := []string{}
+	for _, dir := range dirs {
+		path := filepath.Join(ws.Root, dir, "devbox.lock")
+		if _, err := os.Stat(path); err == nil {
+			lockfilePaths = append(lockfilePaths, path)
+		}
+	}
+	return syncLockfiles(lockfilePaths, pkgs)
+}
+
+// findProjects returns the directories under dir that have a devbox.json,
+// relative to dir. Hidden directories such as .git and .devbox are skipped.
+func findProjects(dir string) ([]string, error) {
+	projects := []string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && path != dir && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !entry.IsDir() && entry.Name() == configfile.DefaultName {
+			rel, err := filepath.Rel(dir, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+			projects = append(projects, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	slices.Sort(projects)
+	return projects, errors.WithStack(err)
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
@@ -0,0 +1,88 @@
+package multi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeProjects(t *testing.T, root string, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dir, "devbox.json"), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWorkspaceMemberDirs(t *testing.T) {
+	root := t.TempDir()
+	writeProjects(t, root, "services/api", "services/web", "tools/cli")
+	if err := os.MkdirAll(filepath.Join(root, "services", "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := &Workspace{Root: root, Members: []string{"tools/cli", "services/*", "services/api"}}
+	got, err := ws.MemberDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"services/api", "services/web", "tools/cli"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("got wrong member dirs (-want +got):\n%s", diff)
+	}
+
+	ws.Members = []string{"services/docs"}
+	if _, err := ws.MemberDirs(); err == nil {
+		t.Error("got nil error for a member without a devbox.json")
+	}
+}
+
+func TestInitAndFindWorkspace(t *testing.T) {
+	root := t.TempDir()
+	writeProjects(t, root, ".", "a", "b/c", ".devbox/virtenv/x")
+
+	ws, created, err := InitWorkspace(root)
+	if err != nil || !created {
+		t.Fatalf("got InitWorkspace() = %v, %v, want a new workspace", created, err)
+	}
+	if diff := cmp.Diff([]string{".", "a", "b/c"}, ws.Members); diff != "" {
+		t.Errorf("got wrong members (-want +got):\n%s", diff)
+	}
+	if _, created, _ := InitWorkspace(root); created {
+		t.Error("got InitWorkspace() = true for an existing workspace")
+	}
+
+	found, err := FindWorkspace(filepath.Join(root, "b", "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Root != root {
+		t.Errorf("got workspace root %q, want %q", found.Root, root)
+	}
+	if diff := cmp.Diff(ws.Members, found.Members); diff != "" {
+		t.Errorf("got wrong members (-want +got):\n%s", diff)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	out := &strings.Builder{}
+	s := &syncWriter{w: out}
+	api, web := s.prefixed("api"), s.prefixed("web")
+	api.Write([]byte("building"))
+	web.Write([]byte("one\ntwo\nth"))
+	api.Write([]byte(" api\n"))
+	web.Write([]byte("ree"))
+	web.flush()
+
+	want := "[web] one\n[web] two\n[api] building api\n[web] three\n"
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("got wrong output (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/ux/progress"
+)
+
+// progressFlags selects how the progress of long operations is shown.
+type progressFlags struct {
+	output string
+}
+
+func (flags *progressFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&flags.output, "output", string(progress.FormatText),
+		"format of progress output: text or json. With json, progress events "+
+			"and messages are written to stderr as one JSON object per line",
+	)
+}
+
+// init starts reporting progress in the selected format. It must be called
+// before the command writes to stderr, which goes through the progress
+// renderer from then on.
+func (flags *progressFlags) init(cmd *cobra.Command) error {
+	format, err := progress.ParseFormat(flags.output)
+	if err != nil {
+		return err
+	}
+	cmd.Root().SetErr(progress.Init(format, cmd.ErrOrStderr()))
+	return nil
+}
@@ -11,7 +11,8 @@ import (
 )
 
 type removeCmdFlags struct {
-	config configFlags
+	config   configFlags
+	progress progressFlags
 }
 
 func removeCmd() *cobra.Command {
@@ -27,10 +28,14 @@ func removeCmd() *cobra.Command {
 	}
 
 	flags.config.register(command)
+	flags.progress.register(command)
 	return command
 }
 
 func runRemoveCmd(cmd *cobra.Command, args []string, flags removeCmdFlags) error {
+	if err := flags.progress.init(cmd); err != nil {
+		return err
+	}
 	box, err := devbox.Open(&devopt.Opts{
 		Dir:         flags.config.path,
 		Environment: flags.config.environment,
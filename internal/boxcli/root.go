@@ -25,8 +25,9 @@ import (
 type cobraFunc func(cmd *cobra.Command, args []string) error
 
 var (
-	debugMiddleware = &midcobra.DebugMiddleware{}
-	traceMiddleware = &midcobra.TraceMiddleware{}
+	debugMiddleware   = &midcobra.DebugMiddleware{}
+	timingsMiddleware = &midcobra.TimingsMiddleware{}
+	traceMiddleware   = &midcobra.TraceMiddleware{}
 )
 
 type rootCmdFlags struct {
@@ -56,22 +57,36 @@ func RootCmd() *cobra.Command {
 
 	// Stable commands
 	command.AddCommand(addCmd())
+	command.AddCommand(auditCmd())
 	if featureflag.Auth.Enabled() {
 		command.AddCommand(authCmd())
 	}
+	command.AddCommand(buildCmd())
 	command.AddCommand(cacheCmd())
+	command.AddCommand(configCmd())
 	command.AddCommand(createCmd())
 	command.AddCommand(secretsCmd())
+	command.AddCommand(doctorCmd())
+	command.AddCommand(duCmd())
+	command.AddCommand(exportCmd())
+	command.AddCommand(gcCmd())
 	command.AddCommand(generateCmd())
 	command.AddCommand(globalCmd())
+	command.AddCommand(historyCmd())
+	command.AddCommand(importCmd())
 	command.AddCommand(infoCmd())
 	command.AddCommand(initCmd())
 	command.AddCommand(installCmd())
 	command.AddCommand(integrateCmd())
+	command.AddCommand(licensesCmd())
 	command.AddCommand(listCmd())
+	command.AddCommand(lockCmd())
 	command.AddCommand(logCmd())
+	command.AddCommand(migrateCmd())
 	command.AddCommand(removeCmd())
+	command.AddCommand(rollbackCmd())
 	command.AddCommand(runCmd())
+	command.AddCommand(sbomCmd())
 	command.AddCommand(searchCmd())
 	command.AddCommand(servicesCmd())
 	command.AddCommand(setupCmd())
@@ -79,6 +94,7 @@ func RootCmd() *cobra.Command {
 	command.AddCommand(shellEnvCmd())
 	command.AddCommand(updateCmd())
 	command.AddCommand(versionCmd())
+	command.AddCommand(workspaceCmd())
 	// Preview commands
 	command.AddCommand(cloudCmd())
 	// Internal commands
@@ -99,6 +115,7 @@ func RootCmd() *cobra.Command {
 		&flags.quiet, "quiet", "q", false, "suppresses logs")
 	debugMiddleware.AttachToFlag(command.PersistentFlags(), "debug")
 	traceMiddleware.AttachToFlag(command.PersistentFlags(), "trace")
+	timingsMiddleware.AttachToFlags(command.PersistentFlags())
 
 	return command
 }
@@ -107,6 +124,8 @@ func Execute(ctx context.Context, args []string) int {
 	defer debug.Recover()
 	rootCmd := RootCmd()
 	exe := midcobra.New(rootCmd)
+	// Added first so that its report comes after the output of the others.
+	exe.AddMiddleware(timingsMiddleware)
 	exe.AddMiddleware(traceMiddleware)
 	exe.AddMiddleware(midcobra.Telemetry())
 	exe.AddMiddleware(debugMiddleware)
@@ -22,6 +22,8 @@ import (
 type runCmdFlags struct {
 	envFlag
 	config      configFlags
+	groups      groupsFlag
+	progress    progressFlags
 	pure        bool
 	listScripts bool
 }
@@ -46,6 +48,8 @@ func runCmd() *cobra.Command {
 
 	flags.envFlag.register(command)
 	flags.config.register(command)
+	flags.groups.register(command)
+	flags.progress.register(command)
 	command.Flags().BoolVar(
 		&flags.pure, "pure", false, "if this flag is specified, devbox runs the script in an isolated environment inheriting almost no variables from the current environment. A few variables, in particular HOME, USER and DISPLAY, are retained.")
 	command.Flags().BoolVarP(
@@ -63,6 +67,9 @@ func listScripts(cmd *cobra.Command, flags runCmdFlags) []string {
 		Stderr:         cmd.ErrOrStderr(),
 		Pure:           flags.pure,
 		IgnoreWarnings: true,
+		// Listing scripts happens whenever the CLI starts, so it
+		// shouldn't modify the project.
+		SkipAutoMigrate: true,
 	})
 	if err != nil {
 		debug.Log("failed to open devbox: %v", err)
@@ -86,6 +93,10 @@ func runScriptCmd(cmd *cobra.Command, args []string, flags runCmdFlags) error {
 		return nil
 	}
 
+	if err := flags.progress.init(cmd); err != nil {
+		return err
+	}
+
 	path, script, scriptArgs, err := parseScriptArgs(args, flags)
 	if err != nil {
 		return redact.Errorf("error parsing script arguments: %w", err)
@@ -105,6 +116,7 @@ func runScriptCmd(cmd *cobra.Command, args []string, flags runCmdFlags) error {
 		Stderr:      cmd.ErrOrStderr(),
 		Pure:        flags.pure,
 		Env:         env,
+		Groups:      flags.groups.opts(),
 	})
 	if err != nil {
 		return redact.Errorf("error reading devbox.json: %w", err)
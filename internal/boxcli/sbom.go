@@ -0,0 +1,98 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"os"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/sbom"
+)
+
+type sbomCmdFlags struct {
+	config  configFlags
+	format  string
+	closure bool
+	output  string
+}
+
+func sbomCmd() *cobra.Command {
+	flags := sbomCmdFlags{}
+	command := &cobra.Command{
+		Use:   "sbom",
+		Short: "Generate a software bill of materials for your devbox environment",
+		Long: heredoc.Doc(`
+			Generate a software bill of materials (SBOM) that lists every package
+			in devbox.lock with its version, resolved flake reference and output
+			store paths for each system.
+
+			With --closure, devbox installs the packages and also lists every
+			store path in their runtime closure with its NAR hash, along with the
+			licenses and homepages from the packages' Nix metadata.
+
+			The output is deterministic. The creation time is taken from
+			SOURCE_DATE_EPOCH if it's set or from the lockfile otherwise.
+		`),
+		Args: cobra.ExactArgs(0),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if flags.closure {
+				return ensureNixInstalled(cmd, args)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sbomCmdFunc(cmd, flags)
+		},
+	}
+
+	flags.config.register(command)
+	command.Flags().StringVar(
+		&flags.format, "format", sbom.FormatSPDX,
+		"output format, either spdx-json or cyclonedx-json",
+	)
+	command.Flags().BoolVar(
+		&flags.closure, "closure", false,
+		"include the runtime closure, store hashes and license metadata of every package",
+	)
+	command.Flags().StringVarP(
+		&flags.output, "output", "o", "", "file to write the SBOM to (default stdout)",
+	)
+	return command
+}
+
+func sbomCmdFunc(cmd *cobra.Command, flags sbomCmdFlags) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:         flags.config.path,
+		Environment: flags.config.environment,
+		Stderr:      cmd.ErrOrStderr(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	opts := devopt.SBOMOpts{
+		Format:  flags.format,
+		Closure: flags.closure,
+		Writer:  cmd.OutOrStdout(),
+	}
+	if flags.output == "" {
+		return box.SBOM(cmd.Context(), opts)
+	}
+
+	f, err := os.Create(flags.output)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	opts.Writer = f
+	if err := box.SBOM(cmd.Context(), opts); err != nil {
+		return err
+	}
+	return errors.WithStack(f.Close())
+}
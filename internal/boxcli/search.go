@@ -33,14 +33,14 @@ func searchCmd() *cobra.Command {
 			query := args[0]
 			name, version, isVersioned := searcher.ParseVersionedPackage(query)
 			if !isVersioned {
-				results, err := searcher.Client().Search(query)
+				results, err := searcher.Client().Search(cmd.Context(), query)
 				if err != nil {
 					return err
 				}
 				return printSearchResults(
 					cmd.OutOrStdout(), query, results, flags.showAll)
 			}
-			packageVersion, err := searcher.Client().Resolve(name, version)
+			packageVersion, err := searcher.Client().Resolve(cmd.Context(), name, version)
 			if err != nil {
 				// This is not ideal. Search service should return valid response we
 				// can parse
@@ -18,6 +18,7 @@ import (
 type shellCmdFlags struct {
 	envFlag
 	config   configFlags
+	groups   groupsFlag
 	printEnv bool
 	pure     bool
 }
@@ -44,6 +45,7 @@ func shellCmd() *cobra.Command {
 
 	flags.config.register(command)
 	flags.envFlag.register(command)
+	flags.groups.register(command)
 	return command
 }
 
@@ -58,6 +60,7 @@ func runShellCmd(cmd *cobra.Command, flags shellCmdFlags) error {
 		Env:         env,
 		Environment: flags.config.environment,
 		Pure:        flags.pure,
+		Groups:      flags.groups.opts(),
 		Stderr:      cmd.ErrOrStderr(),
 	})
 	if err != nil {
@@ -16,6 +16,7 @@ import (
 type shellEnvCmdFlags struct {
 	envFlag
 	config            configFlags
+	groups            groupsFlag
 	install           bool
 	noRefreshAlias    bool
 	preservePathStack bool
@@ -70,6 +71,7 @@ func shellEnvCmd() *cobra.Command {
 
 	flags.config.register(command)
 	flags.envFlag.register(command)
+	flags.groups.register(command)
 
 	return command
 }
@@ -89,6 +91,7 @@ func shellEnvFunc(
 		PreservePathStack: flags.preservePathStack,
 		Pure:              flags.pure,
 		Env:               env,
+		Groups:            flags.groups.opts(),
 	})
 	if err != nil {
 		return "", err
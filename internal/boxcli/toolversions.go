@@ -0,0 +1,161 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/devbox"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+type toolVersionsCmdFlags struct {
+	config  configFlags
+	mapping string
+}
+
+func (flags *toolVersionsCmdFlags) register(cmd *cobra.Command) {
+	flags.config.register(cmd)
+	cmd.Flags().StringVar(
+		&flags.mapping, "mapping", "",
+		"path to a JSON file that adds to or overrides the mapping from asdf tools to nixpkgs packages",
+	)
+}
+
+const toolVersionsMappingHelp = `
+The mapping from asdf tool names to nixpkgs packages ships with devbox. Use
+--mapping to add tools or override entries with a JSON file in the same
+format:
+
+  {
+    "nodejs": {"packages": ["nodejs"], "precision": 1, "aliases": ["node"]}
+  }
+
+precision is the number of version components to keep when a tool's exact
+version isn't available in nixpkgs.
+`
+
+func importCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "import",
+		Short: "Import packages from other tools into devbox.json",
+	}
+	command.AddCommand(importToolVersionsCmd())
+	return command
+}
+
+func importToolVersionsCmd() *cobra.Command {
+	flags := toolVersionsCmdFlags{}
+	command := &cobra.Command{
+		Use:   "tool-versions [<file>]",
+		Short: "Add the tools in an asdf or mise .tool-versions file to devbox.json",
+		Long: heredoc.Doc(`
+			Add the tools in an asdf or mise .tool-versions file to devbox.json.
+			The file defaults to the .tool-versions in the project directory.
+
+			Each tool's version is resolved to a nixpkgs package version. If the
+			exact version isn't available, devbox uses the latest version that
+			matches the tool's major or minor version.
+		`) + toolVersionsMappingHelp,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			box, err := devbox.Open(&devopt.Opts{
+				Dir:         flags.config.path,
+				Environment: flags.config.environment,
+				Stderr:      cmd.ErrOrStderr(),
+			})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return box.ImportToolVersions(cmd.Context(), devopt.ToolVersionsOpts{
+				Path:    pathArg(args),
+				Mapping: flags.mapping,
+			})
+		},
+	}
+	flags.register(command)
+	return command
+}
+
+func exportCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "Export devbox.json packages to other tools",
+	}
+	command.AddCommand(exportToolVersionsCmd())
+	return command
+}
+
+type exportToolVersionsCmdFlags struct {
+	toolVersionsCmdFlags
+	output string
+}
+
+func exportToolVersionsCmd() *cobra.Command {
+	flags := exportToolVersionsCmdFlags{}
+	command := &cobra.Command{
+		Use:   "tool-versions",
+		Short: "Write the packages in devbox.json to an asdf or mise .tool-versions file",
+		Long: heredoc.Doc(`
+			Write the packages in devbox.json to an asdf or mise .tool-versions
+			file. Versions are the exact versions in devbox.lock, so run devbox
+			install first. Packages without an asdf tool are skipped.
+
+			If the file already exists, tools that aren't in devbox.json are
+			kept and the versions of the rest are updated.
+		`) + toolVersionsMappingHelp,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return exportToolVersionsCmdFunc(cmd, flags)
+		},
+	}
+	flags.register(command)
+	command.Flags().StringVarP(
+		&flags.output, "output", "o", "",
+		"file to write to, or - for stdout. Defaults to .tool-versions in the project directory",
+	)
+	return command
+}
+
+func exportToolVersionsCmdFunc(cmd *cobra.Command, flags exportToolVersionsCmdFlags) error {
+	box, err := devbox.Open(&devopt.Opts{
+		Dir:         flags.config.path,
+		Environment: flags.config.environment,
+		Stderr:      cmd.ErrOrStderr(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Merge into the file that's written to so that its other tools are
+	// kept.
+	output := flags.output
+	if output == "" {
+		output = filepath.Join(box.ProjectDir(), ".tool-versions")
+	}
+	opts := devopt.ToolVersionsOpts{Mapping: flags.mapping}
+	if output != "-" {
+		opts.Path = output
+	}
+	b, err := box.ExportToolVersions(opts)
+	if err != nil {
+		return err
+	}
+
+	if output == "-" {
+		_, err := cmd.OutOrStdout().Write(b)
+		return errors.WithStack(err)
+	}
+	if err := os.WriteFile(output, b, 0o644); err != nil {
+		return errors.WithStack(err)
+	}
+	ux.Fsuccess(cmd.ErrOrStderr(), "Wrote %s\n", output)
+	return nil
+}
@@ -15,6 +15,7 @@ import (
 
 type updateCmdFlags struct {
 	config      configFlags
+	progress    progressFlags
 	sync        bool
 	allProjects bool
 }
@@ -49,6 +50,7 @@ func updateCmd() *cobra.Command {
 		false,
 		"update all projects in the working directory, recursively.",
 	)
+	flags.progress.register(command)
 	return command
 }
 
@@ -57,6 +59,10 @@ func updateCmdFunc(cmd *cobra.Command, args []string, flags *updateCmdFlags) err
 		return usererr.New("cannot specify both a package and --sync")
 	}
 
+	if err := flags.progress.init(cmd); err != nil {
+		return err
+	}
+
 	if flags.allProjects {
 		return updateAllProjects(cmd, args)
 	}
@@ -0,0 +1,193 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package boxcli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"go.jetpack.io/devbox/internal/boxcli/multi"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+type workspaceRunFlags struct {
+	parallel int
+}
+
+func workspaceCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "workspace",
+		Short: "Manage a workspace of devbox projects",
+		Long: heredoc.Doc(`
+			Manage a workspace of devbox projects, such as the services of a
+			monorepo. A workspace is defined by a devbox-workspace.json file at
+			its root, which lists the directories of its member projects:
+
+			  {"members": ["services/*", "tools/cli"]}
+
+			Workspace commands can run from any directory in the workspace.
+		`),
+	}
+
+	command.AddCommand(workspaceInitCmd())
+	command.AddCommand(workspaceLsCmd())
+	command.AddCommand(workspaceRunCmd())
+	command.AddCommand(workspaceSyncCmd())
+	return command
+}
+
+func workspaceInitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "init [dir]",
+		Short: "Create a devbox-workspace.json with the projects in a directory",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+			ws, created, err := multi.InitWorkspace(dir)
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(dir, multi.WorkspaceFileName)
+			if !created {
+				return usererr.New("%s already exists.", path)
+			}
+			ux.Fsuccess(cmd.ErrOrStderr(), "Created %s with %d members.\n", path, len(ws.Members))
+			return nil
+		},
+	}
+}
+
+func workspaceLsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ls",
+		Short: "List the projects in the workspace",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := multi.FindWorkspace(".")
+			if err != nil {
+				return err
+			}
+			boxes, err := ws.Open(&devopt.Opts{Stderr: cmd.ErrOrStderr(), IgnoreWarnings: true})
+			if err != nil {
+				return err
+			}
+			dirs, err := ws.MemberDirs()
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PROJECT\tPACKAGES\tSCRIPTS")
+			for _, dir := range dirs {
+				box := boxes[dir]
+				fmt.Fprintf(w, "%s\t%d\t%s\n",
+					dir, len(box.Config().Packages(false)), strings.Join(box.ListScripts(), ", "))
+			}
+			return errors.WithStack(w.Flush())
+		},
+	}
+}
+
+func workspaceRunCmd() *cobra.Command {
+	flags := workspaceRunFlags{}
+	command := &cobra.Command{
+		Use:   "run <script> [args]...",
+		Short: "Run a script in every project of the workspace that defines it",
+		Long: heredoc.Doc(`
+			Run a script in every project of the workspace that defines it. The
+			projects run in parallel and their output is prefixed with the
+			project's directory. A summary is printed when all projects finish,
+			and the command fails if the script failed in any project.
+		`),
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: ensureNixInstalled,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return workspaceRunCmdFunc(cmd, args, flags)
+		},
+	}
+	command.Flags().IntVarP(
+		&flags.parallel, "parallel", "p", 0,
+		"maximum number of projects to run at the same time (0 runs all of them)")
+	// Flags after the script are passed to the script.
+	command.Flags().SetInterspersed(false)
+	return command
+}
+
+func workspaceRunCmdFunc(cmd *cobra.Command, args []string, flags workspaceRunFlags) error {
+	ws, err := multi.FindWorkspace(".")
+	if err != nil {
+		return err
+	}
+	results, err := ws.RunScript(cmd.Context(), multi.RunOpts{
+		Script:   args[0],
+		Args:     args[1:],
+		Parallel: flags.parallel,
+		Output:   cmd.OutOrStdout(),
+	})
+	if err != nil {
+		return err
+	}
+
+	ran, failed := printRunSummary(cmd.ErrOrStderr(), results)
+	if ran == 0 {
+		return usererr.New("No project in the workspace defines the script %q.", args[0])
+	}
+	if failed > 0 {
+		return usererr.New("Script %q failed in %d of %d projects.", args[0], failed, ran)
+	}
+	return nil
+}
+
+func printRunSummary(w io.Writer, results []multi.RunResult) (ran, failed int) {
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tRESULT\tDURATION")
+	for _, result := range results {
+		status := "ok"
+		switch {
+		case result.Skipped:
+			status = "skipped"
+		case result.Err != nil:
+			status = "failed"
+			failed++
+		}
+		duration := "-"
+		if !result.Skipped {
+			ran++
+			duration = result.Duration.Round(time.Millisecond).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Member, status, duration)
+	}
+	_ = tw.Flush()
+	return ran, failed
+}
+
+func workspaceSyncCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync [pkg]...",
+		Short: "Align package versions across the projects of the workspace",
+		Long: "Update the devbox.lock of every project in the workspace so that " +
+			"packages use the latest version that any project has locked. " +
+			"If packages are given, only those packages are synced.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := multi.FindWorkspace(".")
+			if err != nil {
+				return err
+			}
+			return ws.SyncLockfiles(args)
+		},
+	}
+}
@@ -6,9 +6,11 @@ package debug
 import (
 	"fmt"
 	"os"
+	"path"
 	"runtime"
+	"slices"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,13 +20,51 @@ const devboxPrintExecTime = "DEVBOX_PRINT_EXEC_TIME"
 
 var headerPrinted = false
 
+// Span is the time that a timer measured.
+type Span struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+var (
+	spansMu sync.Mutex
+	// spans is nil unless timings are recorded.
+	spans []Span
+)
+
+// RecordTimings makes timers record their spans, so that a command can report
+// where it spent its time.
+func RecordTimings() {
+	spansMu.Lock()
+	defer spansMu.Unlock()
+	if spans == nil {
+		spans = []Span{}
+	}
+}
+
+// TimingsEnabled returns true if timers record their spans.
+func TimingsEnabled() bool {
+	spansMu.Lock()
+	defer spansMu.Unlock()
+	return spans != nil
+}
+
+// Timings returns the spans of the timers that ended, in the order that they
+// ended.
+func Timings() []Span {
+	spansMu.Lock()
+	defer spansMu.Unlock()
+	return slices.Clone(spans)
+}
+
 type timer struct {
 	name string
 	time time.Time
 }
 
 func Timer(name string) *timer {
-	if !timerEnabled {
+	if !timerEnabled && !TimingsEnabled() {
 		return nil
 	}
 	return &timer{
@@ -33,27 +73,38 @@ func Timer(name string) *timer {
 	}
 }
 
+// FunctionTimer returns a timer named after the calling function and its
+// package, such as "devbox.(*Devbox).Shell".
 func FunctionTimer() *timer {
-	if !timerEnabled {
+	if !timerEnabled && !TimingsEnabled() {
 		return nil
 	}
 	pc := make([]uintptr, 15)
 	n := runtime.Callers(2, pc)
 	frames := runtime.CallersFrames(pc[:n])
 	frame, _ := frames.Next()
-	parts := strings.Split(frame.Function, ".")
-	return Timer(parts[len(parts)-1])
+	return Timer(path.Base(frame.Function))
 }
 
 func (t *timer) End() {
 	if t == nil {
 		return
 	}
+	end := time.Now()
+	spansMu.Lock()
+	if spans != nil {
+		spans = append(spans, Span{Name: t.name, Start: t.time, End: end})
+	}
+	spansMu.Unlock()
+
+	if !timerEnabled {
+		return
+	}
 	if !headerPrinted {
 		fmt.Fprintln(os.Stderr, "\nExec times over 1ms:")
 		headerPrinted = true
 	}
-	if time.Since(t.time) >= time.Millisecond {
-		fmt.Fprintf(os.Stderr, "\"%s\" took %s\n", t.name, time.Since(t.time))
+	if end.Sub(t.time) >= time.Millisecond {
+		fmt.Fprintf(os.Stderr, "\"%s\" took %s\n", t.name, end.Sub(t.time))
 	}
 }
@@ -0,0 +1,170 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"cmp"
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/fileutil"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/osv"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+const (
+	AuditSourceNixpkgs = "nixpkgs"
+	AuditSourceOSV     = "osv"
+)
+
+// AuditReport lists the known vulnerabilities in a project's packages.
+type AuditReport struct {
+	Findings []AuditFinding `json:"findings"`
+}
+
+// AuditFinding is a single vulnerability in a package.
+type AuditFinding struct {
+	// Package is the lockfile key of a devbox package or the store path of a
+	// package in the closure.
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// ID is the advisory ID. For vulnerabilities from nixpkgs it's the
+	// entry in meta.knownVulnerabilities, which is usually a CVE ID.
+	ID       string       `json:"id"`
+	Aliases  []string     `json:"aliases,omitempty"`
+	Summary  string       `json:"summary,omitempty"`
+	Severity osv.Severity `json:"severity"`
+	Fixed    string       `json:"fixed,omitempty"`
+	Source   string       `json:"source"`
+}
+
+// Audit checks the project's packages for known vulnerabilities. It reports
+// the meta.knownVulnerabilities of every nixpkgs package and, if an advisory
+// file is given, matches package versions against it. With
+// [devopt.AuditOpts.Closure] it also matches every store path in the
+// packages' runtime closures against the advisories.
+//
+// nixpkgs refuses to build packages with known vulnerabilities unless they're
+// explicitly allowed, so those findings are reported as high severity.
+func (d *Devbox) Audit(ctx context.Context, opts devopt.AuditOpts) (*AuditReport, error) {
+	defer debug.FunctionTimer().End()
+
+	minSeverity, err := osv.ParseSeverity(opts.Severity)
+	if err != nil {
+		return nil, usererr.New("Unknown severity %q. Use low, medium, high or critical.", opts.Severity)
+	}
+	db := &osv.Database{}
+	if opts.Advisories != "" {
+		if db, err = osv.Load(opts.Advisories); err != nil {
+			return nil, usererr.WithUserMessage(err, "Unable to load advisories from %s.", opts.Advisories)
+		}
+	}
+
+	report := &AuditReport{Findings: []AuditFinding{}}
+	keys := lo.Keys(d.lockfile.Packages)
+	slices.Sort(keys)
+	for _, key := range keys {
+		pkg := d.lockfile.Packages[key]
+		name := lockfilePackageName(key)
+		report.addOSVFindings(db, key, name, pkg.Version)
+
+		if !strings.Contains(pkg.Resolved, "#") {
+			continue
+		}
+		meta, err := nix.EvalPackageMeta(ctx, pkg.Resolved)
+		if err != nil {
+			ux.Fwarning(d.stderr, "unable to get the metadata for %s: %v\n", key, err)
+			continue
+		}
+		for _, vuln := range meta.KnownVulnerabilities {
+			report.Findings = append(report.Findings, AuditFinding{
+				Package:  key,
+				Name:     name,
+				Version:  pkg.Version,
+				ID:       vuln,
+				Severity: osv.SeverityHigh,
+				Source:   AuditSourceNixpkgs,
+			})
+		}
+	}
+
+	if opts.Closure {
+		if err := d.auditClosure(ctx, db, report); err != nil {
+			return nil, err
+		}
+	}
+
+	report.Findings = lo.Filter(report.Findings, func(f AuditFinding, _ int) bool {
+		return f.Severity >= minSeverity
+	})
+	slices.SortFunc(report.Findings, func(a, b AuditFinding) int {
+		return cmp.Or(
+			cmp.Compare(b.Severity, a.Severity),
+			strings.Compare(a.Package, b.Package),
+			strings.Compare(a.ID, b.ID),
+		)
+	})
+	return report, nil
+}
+
+// auditClosure matches every store path in the runtime closure of the
+// project's packages against the advisories.
+func (d *Devbox) auditClosure(ctx context.Context, db *osv.Database, report *AuditReport) error {
+	if err := d.ensureStateIsUpToDate(ctx, ensure); err != nil {
+		return err
+	}
+
+	system := nix.System()
+	paths := []string{}
+	for _, pkg := range d.lockfile.Packages {
+		if info := pkg.Systems[system]; info != nil {
+			for _, out := range info.Outputs {
+				// Only installed outputs are in the closure.
+				if fileutil.Exists(out.Path) {
+					paths = append(paths, out.Path)
+				}
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	infos, err := nix.PathInfos(ctx, paths...)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		name, version := splitStoreName(filepath.Base(info.Path))
+		if version != "" {
+			report.addOSVFindings(db, info.Path, name, version)
+		}
+	}
+	return nil
+}
+
+func (r *AuditReport) addOSVFindings(db *osv.Database, pkg, name, version string) {
+	for _, match := range db.Match(name, version) {
+		r.Findings = append(r.Findings, AuditFinding{
+			Package:  pkg,
+			Name:     name,
+			Version:  version,
+			ID:       match.Advisory.ID,
+			Aliases:  match.Advisory.Aliases,
+			Summary:  match.Advisory.Summary,
+			Severity: match.Severity,
+			Fixed:    match.Fixed,
+			Source:   AuditSourceOSV,
+		})
+	}
+}
@@ -4,63 +4,175 @@ import (
 	"context"
 	"errors"
 	"io"
+	"slices"
+	"strings"
 
 	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/devbox/providers/nixcache"
+	"go.jetpack.io/devbox/internal/devconfig"
 	"go.jetpack.io/devbox/internal/nix"
 	"go.jetpack.io/devbox/internal/ux"
 	"go.jetpack.io/pkg/auth"
 )
 
+// UploadSummary describes what devbox cache upload copied to a cache.
+type UploadSummary struct {
+	// Cache is the URL of the cache.
+	Cache string
+	// Paths is the number of store paths in the closure of the project.
+	Paths int
+	// Uploaded is the number of paths that weren't in the cache.
+	Uploaded int
+	// UploadedBytes is the NAR size of the uploaded paths.
+	UploadedBytes int64
+}
+
+// uploadTarget is the cache that devbox cache upload copies to, and the
+// environment that nix needs to write to it.
+type uploadTarget struct {
+	cache nix.BinaryCache
+	env   []string
+}
+
+// UploadProjectToCache uploads the closure of the project's packages to a
+// cache. Paths that the cache already has aren't uploaded again.
+//
+// The cache is cacheURI if it's set, then the first binary cache with upload
+// set in devbox.json or in the global devbox.json, and then the Jetify cache
+// of the user's organization.
 func (d *Devbox) UploadProjectToCache(
 	ctx context.Context,
 	cacheURI string,
-) error {
-	if cacheURI == "" {
-		var err error
-		cacheURI, err = getWriteCacheURI(ctx, d.stderr, d.providers.NixCache)
-		if err != nil {
-			return err
-		}
-	}
-
-	creds, err := d.providers.NixCache.Credentials(ctx)
-	if err != nil && !errors.Is(err, auth.ErrNotLoggedIn) {
-		return err
+) (*UploadSummary, error) {
+	target, err := resolveUploadTarget(
+		ctx, d.stderr, d.providers.NixCache, cacheURI, d.cfg.BinaryCaches())
+	if err != nil {
+		return nil, err
 	}
 	profilePath, err := d.profilePath()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Ensure state is up to date before uploading to cache.
 	// TODO: we may be able to do this more efficiently, not sure everything needs
 	// to be installed.
 	if err = d.ensureStateIsUpToDate(ctx, ensure); err != nil {
-		return err
+		return nil, err
+	}
+
+	infos, err := nix.PathInfos(ctx, profilePath)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(infos))
+	for i, info := range infos {
+		paths[i] = info.Path
+	}
+	missing, err := nix.MissingPaths(ctx, target.cache.URL, paths, target.env)
+	if err != nil {
+		return nil, err
 	}
 
-	return nix.CopyInstallableToCache(ctx, d.stderr, cacheURI, profilePath, creds.Env())
+	summary := &UploadSummary{
+		Cache:    target.cache.URL,
+		Paths:    len(paths),
+		Uploaded: len(missing),
+	}
+	for _, info := range infos {
+		if slices.Contains(missing, info.Path) {
+			summary.UploadedBytes += info.NarSize
+		}
+	}
+	if len(missing) == 0 {
+		return summary, nil
+	}
+	err = nix.CopyPathsToCache(ctx, d.stderr, target.cache.UploadURL(), missing, target.env)
+	return summary, err
 }
 
+// UploadInstallableToCache uploads an installable and its closure to a cache.
+// The cache is chosen like UploadProjectToCache does, without the binary
+// caches of a project.
 func UploadInstallableToCache(
 	ctx context.Context,
 	stderr io.Writer,
 	cacheURI, installable string,
 ) error {
+	target, err := resolveUploadTarget(ctx, stderr, *nixcache.Get(), cacheURI, nil)
+	if err != nil {
+		return err
+	}
+	return nix.CopyInstallableToCache(ctx, stderr, target.cache.UploadURL(), installable, target.env)
+}
+
+// resolveUploadTarget picks the cache to upload to. Self-managed caches from
+// devbox.json or the global devbox.json use the credentials in the user's
+// environment, such as AWS_PROFILE for S3. Only other caches use the Jetify
+// credentials of the logged in user.
+func resolveUploadTarget(
+	ctx context.Context,
+	w io.Writer,
+	provider nixcache.Provider,
+	cacheURI string,
+	projectCaches []nix.BinaryCache,
+) (*uploadTarget, error) {
+	caches := slices.Concat(projectCaches, globalBinaryCaches())
 	if cacheURI == "" {
+		if cache, ok := firstUploadCache(caches); ok {
+			return &uploadTarget{cache: cache}, nil
+		}
 		var err error
-		cacheURI, err = getWriteCacheURI(ctx, stderr, *nixcache.Get())
+		cacheURI, err = getWriteCacheURI(ctx, w, provider)
 		if err != nil {
-			return err
+			return nil, err
+		}
+	} else {
+		for _, cache := range caches {
+			if strings.TrimSuffix(cache.URL, "/") == strings.TrimSuffix(cacheURI, "/") {
+				return &uploadTarget{cache: cache}, nil
+			}
 		}
 	}
 
-	creds, err := nixcache.Get().Credentials(ctx)
+	creds, err := provider.Credentials(ctx)
 	if err != nil && !errors.Is(err, auth.ErrNotLoggedIn) {
-		return err
+		return nil, err
+	}
+	target := &uploadTarget{cache: nix.BinaryCache{URL: cacheURI}}
+	// Empty credentials would override the user's own AWS credentials.
+	if creds.AccessKeyID != "" {
+		target.env = creds.Env()
+	}
+	return target, nil
+}
+
+// firstUploadCache returns the preferred cache that has upload set, which is
+// the one with the lowest priority. Of caches with the same priority, the
+// first one is preferred.
+func firstUploadCache(caches []nix.BinaryCache) (nix.BinaryCache, bool) {
+	for _, cache := range nix.BinaryCaches(caches) {
+		if cache.Upload {
+			return cache, true
+		}
+	}
+	return nix.BinaryCache{}, false
+}
+
+// globalBinaryCaches returns the binary caches in the global devbox.json. It
+// returns nil if there's no global config.
+func globalBinaryCaches() []nix.BinaryCache {
+	path, err := GlobalDataPath()
+	if err != nil {
+		return nil
+	}
+	cfg, err := devconfig.Open(path)
+	if err != nil {
+		debug.Log("no global binary caches: %v", err)
+		return nil
 	}
-	return nix.CopyInstallableToCache(ctx, stderr, cacheURI, installable, creds.Env())
+	return cfg.Root.BinaryCaches
 }
 
 func getWriteCacheURI(
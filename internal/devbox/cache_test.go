@@ -0,0 +1,24 @@
+package devbox
+
+import (
+	"testing"
+
+	"go.jetpack.io/devbox/internal/nix"
+)
+
+func TestFirstUploadCache(t *testing.T) {
+	caches := []nix.BinaryCache{
+		{URL: "s3://declared-first", Upload: true},
+		{URL: "s3://no-upload", Priority: 10},
+		{URL: "s3://preferred", Priority: 20, Upload: true},
+		{URL: "s3://same-priority", Priority: 20, Upload: true},
+	}
+	got, ok := firstUploadCache(caches)
+	if !ok || got.URL != "s3://preferred" {
+		t.Errorf("got cache %q, %v, want s3://preferred", got.URL, ok)
+	}
+
+	if _, ok := firstUploadCache([]nix.BinaryCache{{URL: "s3://no-upload"}}); ok {
+		t.Error("got an upload cache from caches without upload set")
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"cmp"
+	"context"
+	"path/filepath"
+	"slices"
+
+	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/devpkg"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/nix/nixstore"
+	"go.jetpack.io/devbox/internal/redact"
+)
+
+// ClosureReport describes the Nix store closure of one or more packages.
+type ClosureReport struct {
+	Packages []PackageClosure `json:"packages"`
+
+	// ClosureSize is the size of the combined closure of every package in the
+	// report. It's usually smaller than the sum of each package's closure
+	// because packages share dependencies.
+	ClosureSize int64 `json:"closure_size"`
+}
+
+// PackageClosure describes the closure of a single devbox package.
+type PackageClosure struct {
+	Package     string          `json:"package"`
+	StorePaths  []string        `json:"store_paths"`
+	Size        int64           `json:"size"`
+	ClosureSize int64           `json:"closure_size"`
+	Heaviest    []StorePathSize `json:"heaviest_dependencies"`
+
+	// Why lists the shortest dependency chain to every package in the closure
+	// that matched [devopt.ClosureOpts.Why].
+	Why [][]string `json:"why,omitempty"`
+}
+
+// StorePathSize is the size of a single store path, excluding its
+// dependencies.
+type StorePathSize struct {
+	StorePath string `json:"store_path"`
+	Size      int64  `json:"size"`
+}
+
+// Closures installs the project's packages and reports the size and
+// composition of their closures.
+func (d *Devbox) Closures(ctx context.Context, opts devopt.ClosureOpts) (*ClosureReport, error) {
+	defer debug.FunctionTimer().End()
+
+	packages := lo.Filter(d.InstallablePackages(), devpkg.IsNix)
+	if len(opts.Pkgs) > 0 {
+		packages = make([]*devpkg.Package, 0, len(opts.Pkgs))
+		for _, name := range opts.Pkgs {
+			pkg, err := d.findPackageByName(name)
+			if err != nil {
+				return nil, err
+			}
+			packages = append(packages, pkg)
+		}
+	}
+
+	if err := d.ensureStateIsUpToDate(ctx, ensure); err != nil {
+		return nil, err
+	}
+
+	stores := map[string]*nixstore.Root{}
+	report := &ClosureReport{Packages: make([]PackageClosure, 0, len(packages))}
+	allStorePkgs := []*nixstore.Package{}
+	for _, pkg := range packages {
+		storePkgs, err := storePackages(ctx, stores, pkg)
+		if err != nil {
+			return nil, err
+		}
+		allStorePkgs = append(allStorePkgs, storePkgs...)
+
+		pkgClosure, err := newPackageClosure(pkg, storePkgs, opts)
+		if err != nil {
+			return nil, err
+		}
+		report.Packages = append(report.Packages, pkgClosure)
+	}
+
+	var err error
+	report.ClosureSize, err = nixstore.ClosureSize(allStorePkgs...)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(report.Packages, func(a, b PackageClosure) int {
+		return cmp.Compare(b.ClosureSize, a.ClosureSize)
+	})
+	return report, nil
+}
+
+// storePackages resolves the outputs of a devbox package to the packages in
+// its Nix store. It reuses the store indexes in stores to avoid re-listing
+// the store for every package.
+func storePackages(
+	ctx context.Context,
+	stores map[string]*nixstore.Root,
+	pkg *devpkg.Package,
+) ([]*nixstore.Package, error) {
+	installables, err := pkg.Installables()
+	if err != nil {
+		return nil, err
+	}
+
+	storePkgs := []*nixstore.Package{}
+	for _, installable := range installables {
+		storePaths, err := nix.StorePathsFromInstallable(ctx, installable, pkg.HasAllowInsecure())
+		if err != nil {
+			return nil, packageInstallErrorHandler(err, pkg, installable)
+		}
+		for _, storePath := range storePaths {
+			storeDir := filepath.Dir(storePath)
+			root := stores[storeDir]
+			if root == nil {
+				if root, err = nixstore.Local(storeDir); err != nil {
+					return nil, err
+				}
+				stores[storeDir] = root
+			}
+			storePkg, err := root.Package(filepath.Base(storePath))
+			if err != nil {
+				return nil, redact.Errorf("read store path of package %s: %w", redact.Safe(pkg.Raw), err)
+			}
+			storePkgs = append(storePkgs, storePkg)
+		}
+	}
+	return storePkgs, nil
+}
+
+func newPackageClosure(
+	pkg *devpkg.Package,
+	storePkgs []*nixstore.Package,
+	opts devopt.ClosureOpts,
+) (PackageClosure, error) {
+	pkgClosure := PackageClosure{
+		Package:    pkg.Versioned(),
+		StorePaths: lo.Map(storePkgs, func(p *nixstore.Package, _ int) string { return p.StoreName }),
+		Heaviest:   []StorePathSize{},
+	}
+
+	isOutput := lo.SliceToMap(storePkgs, func(p *nixstore.Package) (*nixstore.Package, bool) {
+		return p, true
+	})
+	for _, dep := range nixstore.Closure(storePkgs...) {
+		size, err := dep.Size()
+		if err != nil {
+			return PackageClosure{}, err
+		}
+		pkgClosure.ClosureSize += size
+		if isOutput[dep] {
+			pkgClosure.Size += size
+			continue
+		}
+		pkgClosure.Heaviest = append(
+			pkgClosure.Heaviest,
+			StorePathSize{StorePath: dep.StoreName, Size: size},
+		)
+	}
+	slices.SortStableFunc(pkgClosure.Heaviest, func(a, b StorePathSize) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), cmp.Compare(a.StorePath, b.StorePath))
+	})
+	if opts.Top >= 0 && len(pkgClosure.Heaviest) > opts.Top {
+		pkgClosure.Heaviest = pkgClosure.Heaviest[:opts.Top]
+	}
+
+	if opts.Why == "" {
+		return pkgClosure, nil
+	}
+	for _, storePkg := range storePkgs {
+		for _, match := range nixstore.FindInClosure(storePkg, opts.Why) {
+			path := nixstore.DependencyPath(storePkg, match)
+			pkgClosure.Why = append(pkgClosure.Why, lo.Map(
+				path, func(p *nixstore.Package, _ int) string { return p.StoreName },
+			))
+		}
+	}
+	return pkgClosure, nil
+}
@@ -7,6 +7,7 @@ package devbox
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"maps"
@@ -19,11 +20,10 @@ import (
 	"strconv"
 	"strings"
 	"text/tabwriter"
-	"time"
 
-	"github.com/briandowns/spinner"
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/autodetect"
 	"go.jetpack.io/devbox/internal/cachehash"
 	"go.jetpack.io/devbox/internal/devbox/envpath"
 	"go.jetpack.io/devbox/internal/devbox/generate"
@@ -41,6 +41,7 @@ import (
 	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/devbox/devopt"
 	"go.jetpack.io/devbox/internal/devconfig"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
 	"go.jetpack.io/devbox/internal/envir"
 	"go.jetpack.io/devbox/internal/fileutil"
 	"go.jetpack.io/devbox/internal/lock"
@@ -49,6 +50,7 @@ import (
 	"go.jetpack.io/devbox/internal/redact"
 	"go.jetpack.io/devbox/internal/services"
 	"go.jetpack.io/devbox/internal/ux"
+	"go.jetpack.io/devbox/internal/ux/progress"
 )
 
 const (
@@ -72,17 +74,48 @@ type Devbox struct {
 	pure                     bool
 	customProcessComposeFile string
 
+	// groups are the selected package groups, which are the only ones that
+	// are installed.
+	groups []string
+
+	// historyDepth is the number of history-tracked commands that are
+	// running, so that nested commands aren't recorded separately.
+	historyDepth int
+
 	// This is needed because of the --quiet flag.
 	stderr io.Writer
 }
 
-var legacyPackagesWarningHasBeenShown = false
+var (
+	legacyPackagesWarningHasBeenShown = false
+	configProblemsWarningHasBeenShown = false
+)
 
 func InitConfig(dir string) (bool, error) {
 	return devconfig.Init(dir)
 }
 
+// InitConfigFromProposal creates a devbox.json in dir that has the packages
+// and scripts from an autodetect proposal in addition to the defaults. It
+// returns false if dir already has a devbox.json.
+func InitConfigFromProposal(dir string, proposal *autodetect.Proposal) (bool, error) {
+	cfg := devconfig.DefaultConfig()
+	for _, pkg := range proposal.Packages {
+		cfg.Root.PackagesMutator.Add(pkg.String())
+	}
+	for _, script := range proposal.Scripts {
+		// json.Marshal can't fail for a string.
+		command, _ := json.Marshal(script.Command)
+		path := fmt.Sprintf("shell.scripts[%q]", script.Name)
+		if err := cfg.Root.SetPath(path, command); err != nil {
+			return false, err
+		}
+	}
+	return devconfig.InitWithConfig(dir, cfg)
+}
+
 func Open(opts *devopt.Opts) (*Devbox, error) {
+	defer debug.FunctionTimer().End()
 	projectDir, err := findProjectDir(opts.Dir)
 	if err != nil {
 		return nil, err
@@ -119,17 +152,21 @@ func Open(opts *devopt.Opts) (*Devbox, error) {
 	if err := cfg.LoadRecursive(lock); err != nil {
 		return nil, err
 	}
-
+	if err := box.selectGroups(opts.Groups); err != nil {
+		return nil, err
+	}
 	// if lockfile has any allow insecure, we need to set the env var to ensure
-	// all nix commands work.
-	if err := box.moveAllowInsecureFromLockfile(box.stderr, lock, cfg); err != nil {
-		ux.Fwarning(
-			box.stderr,
-			"Failed to move allow_insecure from devbox.lock to devbox.json. An insecure package may "+
-				"not work until you invoke `devbox add <pkg> --allow-insecure=<packages>` again: %s\n",
-			err,
-		)
-		// continue on, since we do not want to block user.
+	// all nix commands work. devbox migrate --check reports it instead.
+	if !opts.SkipAutoMigrate {
+		if err := box.moveAllowInsecureFromLockfile(box.stderr, lock, cfg); err != nil {
+			ux.Fwarning(
+				box.stderr,
+				"Failed to move allow_insecure from devbox.lock to devbox.json. An insecure package may "+
+					"not work until you invoke `devbox add <pkg> --allow-insecure=<packages>` again: %s\n",
+				err,
+			)
+			// continue on, since we do not want to block user.
+		}
 	}
 
 	box.pluginManager.ApplyOptions(
@@ -138,6 +175,13 @@ func Open(opts *devopt.Opts) (*Devbox, error) {
 	)
 	box.lockfile = lock
 
+	if !opts.IgnoreWarnings && !configProblemsWarningHasBeenShown {
+		configProblemsWarningHasBeenShown = true
+		for _, problem := range cfg.Root.Validate() {
+			ux.Fwarning(box.stderr, "%s:%s\n", configfile.DefaultName, problem)
+		}
+	}
+
 	if !opts.IgnoreWarnings &&
 		!legacyPackagesWarningHasBeenShown &&
 		// HasDeprecatedPackages required nix to be installed. Since not all
@@ -180,6 +224,21 @@ func (d *Devbox) ConfigHash() (string, error) {
 	buf.WriteString(h)
 	for _, pkg := range d.AllPackages() {
 		buf.WriteString(pkg.Hash())
+		// Patch files are copied into the generated flake, so editing
+		// one must regenerate it.
+		if pkg.Override != nil {
+			for _, patch := range pkg.Override.Patches {
+				h, err := cachehash.File(patch)
+				if err != nil {
+					return "", err
+				}
+				buf.WriteString(h)
+			}
+		}
+	}
+	// Selecting different groups installs different packages.
+	if !slices.Equal(d.groups, []string{configfile.DefaultGroup}) {
+		buf.WriteString(strings.Join(d.groups, ","))
 	}
 	for _, pluginConfig := range d.cfg.IncludedPluginConfigs() {
 		h, err := pluginConfig.Hash()
@@ -235,7 +294,10 @@ func (d *Devbox) Shell(ctx context.Context) error {
 		return err
 	}
 
-	return shell.Run()
+	stopWatchingHooks := watchInitHooks(envs)
+	err = shell.Run()
+	stopWatchingHooks(err)
+	return err
 }
 
 func (d *Devbox) RunScript(ctx context.Context, cmdName string, cmdArgs []string) error {
@@ -246,6 +308,7 @@ func (d *Devbox) RunScript(ctx context.Context, cmdName string, cmdArgs []string
 		return err
 	}
 
+	d.addScriptGroups(cmdName)
 	lock.SetIgnoreShellMismatch(true)
 	env, err := d.ensureStateIsUpToDateAndComputeEnv(ctx)
 	if err != nil {
@@ -284,7 +347,10 @@ func (d *Devbox) RunScript(ctx context.Context, cmdName string, cmdArgs []string
 		env["DEVBOX_RUN_CMD"] = strings.Join(append([]string{cmdName}, cmdArgs...), " ")
 	}
 
-	return nix.RunScript(d.projectDir, strings.Join(cmdWithArgs, " "), env)
+	stopWatchingHooks := watchInitHooks(env)
+	err = nix.RunScript(d.projectDir, strings.Join(cmdWithArgs, " "), env)
+	stopWatchingHooks(err)
+	return err
 }
 
 // Install ensures that all the packages in the config are installed
@@ -293,6 +359,9 @@ func (d *Devbox) Install(ctx context.Context) error {
 	ctx, task := trace.NewTask(ctx, "devboxInstall")
 	defer task.End()
 
+	if err := d.checkLicensePolicy(ctx, d.InstallablePackages()); err != nil {
+		return err
+	}
 	return d.ensureStateIsUpToDate(ctx, ensure)
 }
 
@@ -380,7 +449,7 @@ func (d *Devbox) Info(ctx context.Context, pkg string, markdown bool) (string, e
 		version = "latest"
 	}
 
-	packageVersion, err := searcher.Client().Resolve(name, version)
+	packageVersion, err := searcher.Client().Resolve(ctx, name, version)
 	if err != nil {
 		if !errors.Is(err, searcher.ErrNotFound) {
 			return "", usererr.WithUserMessage(err, "Package %q not found\n", pkg)
@@ -500,6 +569,35 @@ func (d *Devbox) GenerateDockerfile(ctx context.Context, generateOpts devopt.Gen
 	}))
 }
 
+// GenerateFlake writes a flake.nix and flake.lock to the project directory
+// that reproduce the Devbox environment for nix develop.
+func (d *Devbox) GenerateFlake(ctx context.Context, generateOpts devopt.GenerateOpts) error {
+	ctx, task := trace.NewTask(ctx, "devboxGenerateFlake")
+	defer task.End()
+
+	flakePath := filepath.Join(d.projectDir, shellgen.StandaloneFlakeName)
+	if !generateOpts.Force && fileutil.Exists(flakePath) {
+		return usererr.New(
+			"flake.nix is already present in the project directory. " +
+				"Remove it or use --force to overwrite it.",
+		)
+	}
+	for _, pkg := range d.InstallablePackages() {
+		if pkg.IsRunX() {
+			ux.Fwarning(d.stderr, "Skipping %s because only Nix packages can be exported to a flake.\n", pkg.Raw)
+		}
+	}
+
+	if err := shellgen.WriteStandaloneFlake(ctx, d); err != nil {
+		return err
+	}
+	if err := nix.FlakeLock(ctx, d.stderr, d.projectDir); err != nil {
+		return err
+	}
+	ux.Fsuccess(d.stderr, "Generated flake.nix and flake.lock. Add them to git to use them with nix develop.\n")
+	return nil
+}
+
 func PrintEnvrcContent(w io.Writer, envFlags devopt.EnvFlags) error {
 	return generate.EnvrcContent(w, envFlags)
 }
@@ -840,11 +938,21 @@ func (d *Devbox) StartProcessManager(
 // some additional processing. The computeEnv environment won't necessarily
 // represent the final "devbox run" or "devbox shell" environments.
 func (d *Devbox) computeEnv(ctx context.Context, usePrintDevEnvCache bool) (map[string]string, error) {
+	return d.computeEnvFrom(ctx, os.Environ(), usePrintDevEnvCache, true /*includeSecrets*/)
+}
+
+// computeEnvFrom is like computeEnv, but starts from hostEnv instead of the
+// current environment. Secrets from Jetify Cloud are only added if
+// includeSecrets is true.
+func (d *Devbox) computeEnvFrom(
+	ctx context.Context,
+	hostEnv []string,
+	usePrintDevEnvCache, includeSecrets bool,
+) (map[string]string, error) {
 	defer trace.StartRegion(ctx, "devboxComputeEnv").End()
 
 	// Append variables from current env if --pure is not passed
-	currentEnv := os.Environ()
-	env, err := d.parseEnvAndExcludeSpecialCases(currentEnv)
+	env, err := d.parseEnvAndExcludeSpecialCases(hostEnv)
 	if err != nil {
 		return nil, err
 	}
@@ -862,12 +970,9 @@ func (d *Devbox) computeEnv(ctx context.Context, usePrintDevEnvCache bool) (map[
 	originalEnv := make(map[string]string, len(env))
 	maps.Copy(originalEnv, env)
 
-	var spinny *spinner.Spinner
+	var task *progress.Task
 	if !usePrintDevEnvCache {
-		spinny = spinner.New(spinner.CharSets[11], 100*time.Millisecond, spinner.WithWriter(d.stderr))
-		spinny.FinalMSG = "✓ Computed the Devbox environment.\n"
-		spinny.Suffix = " Computing the Devbox environment...\n"
-		spinny.Start()
+		task = progress.Start(progress.Environment, "Computing the Devbox environment")
 	}
 
 	vaf, err := d.nix.PrintDevEnv(ctx, &nix.PrintDevEnvArgs{
@@ -875,8 +980,8 @@ func (d *Devbox) computeEnv(ctx context.Context, usePrintDevEnvCache bool) (map[
 		PrintDevEnvCachePath: d.nixPrintDevEnvCachePath(),
 		UsePrintDevEnvCache:  usePrintDevEnvCache,
 	})
-	if spinny != nil {
-		spinny.Stop()
+	if task != nil {
+		task.End(err)
 	}
 	if err != nil {
 		return nil, err
@@ -928,9 +1033,10 @@ func (d *Devbox) computeEnv(ctx context.Context, usePrintDevEnvCache bool) (map[
 	env["DEVBOX_PROJECT_ROOT"] = d.projectDir
 	env["DEVBOX_CONFIG_DIR"] = d.projectDir + "/devbox.d"
 	env["DEVBOX_PACKAGES_DIR"] = d.projectDir + "/" + nix.ProfilePath
+	env[packageGroupsEnv] = strings.Join(d.groups, ",")
 
 	// Include env variables in devbox.json
-	configEnv, err := d.configEnvs(ctx, env)
+	configEnv, err := d.configEnvs(ctx, env, includeSecrets)
 	if err != nil {
 		return nil, err
 	}
@@ -994,7 +1100,7 @@ func (d *Devbox) computeEnv(ctx context.Context, usePrintDevEnvCache bool) (map[
 
 	if !d.pure {
 		// preserve the original XDG_DATA_DIRS by prepending to it
-		env["XDG_DATA_DIRS"] = envpath.JoinPathLists(env["XDG_DATA_DIRS"], os.Getenv("XDG_DATA_DIRS"))
+		env["XDG_DATA_DIRS"] = envpath.JoinPathLists(env["XDG_DATA_DIRS"], originalEnv["XDG_DATA_DIRS"])
 	}
 
 	for k, v := range d.env {
@@ -1014,7 +1120,7 @@ func (d *Devbox) ensureStateIsUpToDateAndComputeEnv(
 
 	// When ensureStateIsUpToDate is called with ensure=true, it always
 	// returns early if the lockfile is up to date. So we don't need to check here
-	if err := d.ensureStateIsUpToDate(ctx, ensure); isConnectionError(err) {
+	if err := d.ensureStateIsUpToDate(ctx, ensure); searcher.IsConnectionError(err) {
 		if !fileutil.Exists(d.nixPrintDevEnvCachePath()) {
 			ux.Ferror(
 				d.stderr,
@@ -1062,6 +1168,12 @@ func (d *Devbox) AllPackageNamesIncludingRemovedTriggerPackages() []string {
 	return result
 }
 
+// BinaryCaches returns cache.nixos.org and the binary caches of the project
+// and its includes, ordered by priority.
+func (d *Devbox) BinaryCaches() []nix.BinaryCache {
+	return nix.BinaryCaches(d.cfg.BinaryCaches())
+}
+
 // AllPackages returns the packages that are defined in devbox.json and
 // recursively added by plugins.
 // NOTE: This will not return packages removed by their plugin with the
@@ -1075,9 +1187,11 @@ func (d *Devbox) TopLevelPackages() []*devpkg.Package {
 	return devpkg.PackagesFromConfig(d.cfg.Root.TopLevelPackages(), d.lockfile)
 }
 
-// InstallablePackages returns the packages that are to be installed
+// InstallablePackages returns the packages that are to be installed, which
+// are the packages in the selected groups that are enabled on this platform.
 func (d *Devbox) InstallablePackages() []*devpkg.Package {
-	return lo.Filter(d.AllPackages(), func(pkg *devpkg.Package, _ int) bool {
+	packages := devpkg.PackagesFromConfig(d.selectedPackages(), d.lockfile)
+	return lo.Filter(packages, func(pkg *devpkg.Package, _ int) bool {
 		return pkg.IsInstallable()
 	})
 }
@@ -1149,14 +1263,18 @@ func (d *Devbox) checkOldEnvrc() error {
 // that are referenced by $VAR or ${VAR} and replaces them with
 // their value in the existing env variables. Note, this doesn't
 // allow env variables from outside the shell to be referenced so
-// no leaked variables are caused by this function.
+// no leaked variables are caused by this function. Secrets are only added if
+// includeSecrets is true.
 func (d *Devbox) configEnvs(
 	ctx context.Context,
 	existingEnv map[string]string,
+	includeSecrets bool,
 ) (map[string]string, error) {
 	defer debug.FunctionTimer().End()
 	env := map[string]string{}
-	if d.cfg.IsEnvsecEnabled() {
+	if d.cfg.IsEnvsecEnabled() && !includeSecrets {
+		debug.Log("leaving out the secrets from jetify cloud")
+	} else if d.cfg.IsEnvsecEnabled() {
 		secrets, err := d.Secrets(ctx)
 		// TODO: replace this with error.Is check once envsec exports it.
 		if err != nil && !strings.Contains(err.Error(), "project not initialized") {
@@ -1311,7 +1429,7 @@ func (d *Devbox) RunXPaths(ctx context.Context) (string, error) {
 		if !pkg.IsRunX() {
 			continue
 		}
-		lockedPkg, err := d.lockfile.Resolve(pkg.Raw)
+		lockedPkg, err := d.lockfile.Resolve(ctx, pkg.Raw)
 		if err != nil {
 			return "", err
 		}
@@ -2,6 +2,7 @@ package devopt
 
 import (
 	"io"
+	"time"
 )
 
 // Naming Convention:
@@ -16,7 +17,14 @@ type Opts struct {
 	Pure                     bool
 	IgnoreWarnings           bool
 	CustomProcessComposeFile string
+	Groups                   PackageGroups
 	Stderr                   io.Writer
+
+	// SkipAutoMigrate opens the project without modernizing legacy
+	// settings in devbox.json and devbox.lock, which otherwise happens
+	// automatically. It's used by devbox migrate, which reports and applies
+	// the migrations itself, and by devbox doctor.
+	SkipAutoMigrate bool
 }
 
 type GenerateOpts struct {
@@ -50,6 +58,7 @@ type AddOpts struct {
 	DisablePlugin    bool
 	PatchGlibc       bool
 	Outputs          []string
+	Groups           []string
 }
 
 type UpdateOpts struct {
@@ -62,3 +71,80 @@ type EnvExportsOpts struct {
 	NoRefreshAlias           bool
 	RunHooks                 bool
 }
+
+type ClosureOpts struct {
+	Pkgs []string
+	// Top limits the number of heaviest dependencies reported for each
+	// package. A negative value reports all of them.
+	Top int
+	// Why is the name or store path of a dependency to explain.
+	Why string
+}
+
+type BuildImageOpts struct {
+	Output     string
+	Format     string
+	Force      bool
+	Tag        string
+	Entrypoint []string
+	Cmd        []string
+	WorkingDir string
+	MaxLayers  int
+}
+
+type SBOMOpts struct {
+	Format string
+	// Closure includes the runtime closure of every package, along with store
+	// hashes and license metadata. It requires installing the packages.
+	Closure bool
+	Writer  io.Writer
+}
+
+type AuditOpts struct {
+	// Advisories is the path to an OSV advisory file or directory.
+	Advisories string
+	// Closure also checks every store path in the packages' runtime closures.
+	Closure bool
+	// Severity is the minimum severity to report.
+	Severity string
+}
+
+type ToolVersionsOpts struct {
+	// Path is the .tool-versions file to read. It defaults to the one in
+	// the project directory.
+	Path string
+	// Mapping is the path to a JSON file that overrides the default mapping
+	// from asdf tools to nixpkgs packages.
+	Mapping string
+}
+
+type MigrateOpts struct {
+	// Check reports the legacy constructs without rewriting any files.
+	Check bool
+}
+
+type GCOpts struct {
+	// DryRun reports what would be removed without removing anything.
+	DryRun bool
+	// NixStore runs nix store gc after removing devbox's gc roots, which
+	// deletes the store paths that nothing else uses.
+	NixStore bool
+	// CacheAge is how long a file in devbox's cache directory can go
+	// unmodified before devbox gc --global removes it.
+	CacheAge time.Duration
+}
+
+type LockOpts struct {
+	// CheckPlatforms reports the systems in the platforms field of
+	// devbox.json that a package has no outputs for.
+	CheckPlatforms bool
+}
+
+// PackageGroups selects the package groups that are installed. Without
+// either field, only the default group is installed.
+type PackageGroups struct {
+	// With adds groups to the default group.
+	With []string
+	// Only installs only these groups, which may include the default group.
+	Only []string
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/pkg/errors"
 	"go.jetpack.io/devbox/internal/boxcli/usererr"
 	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devconfig"
 	"go.jetpack.io/devbox/internal/devconfig/configfile"
 	"go.jetpack.io/devbox/internal/fileutil"
 )
@@ -111,3 +112,38 @@ func missingConfigError(path string, didCheckParents bool) error {
 func configExistsIn(path string) bool {
 	return fileutil.Exists(filepath.Join(path, configfile.DefaultName))
 }
+
+// OpenConfigFile finds the devbox.json in dir, or in its parent directories
+// if dir is empty, and reads it. Unlike Open, it doesn't load the project's
+// includes and plugins, so it's cheap to use for editing the file.
+func OpenConfigFile(dir string) (*configfile.ConfigFile, error) {
+	projectDir, err := findProjectDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := devconfig.Open(projectDir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &cfg.Root, nil
+}
+
+// ValidateConfig finds the devbox.json in dir, or in its parent directories
+// if dir is empty, and checks it against the devbox.json schema. It returns
+// the path to the devbox.json along with its problems.
+func ValidateConfig(dir string) (string, []configfile.Problem, error) {
+	projectDir, err := findProjectDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	path := filepath.Join(projectDir, configfile.DefaultName)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	problems, err := configfile.Validate(b)
+	if err != nil {
+		return "", nil, usererr.New("Unable to parse %s: %v", path, err)
+	}
+	return path, problems, nil
+}
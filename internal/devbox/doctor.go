@@ -0,0 +1,398 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+
+	"go.jetpack.io/devbox/internal/build"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/envir"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/redact"
+)
+
+// DoctorStatus is the result of a diagnostic check.
+type DoctorStatus string
+
+const (
+	DoctorPass DoctorStatus = "pass"
+	DoctorWarn DoctorStatus = "warn"
+	DoctorFail DoctorStatus = "fail"
+	// DoctorSkip means that the check doesn't apply, for example because
+	// it needs Nix or a project.
+	DoctorSkip DoctorStatus = "skip"
+)
+
+// DoctorReport is the result of devbox doctor.
+type DoctorReport struct {
+	DevboxVersion string        `json:"devbox_version"`
+	Platform      string        `json:"platform"`
+	Checks        []DoctorCheck `json:"checks"`
+}
+
+// DoctorCheck is a single diagnostic check.
+type DoctorCheck struct {
+	Name    string       `json:"name"`
+	Status  DoctorStatus `json:"status"`
+	Message string       `json:"message"`
+	// Fix suggests how to resolve a warning or failure.
+	Fix string `json:"fix,omitempty"`
+
+	// detail is Message as an error created by redact.Errorf, so that
+	// Redacted can leave out paths, usernames and command output.
+	detail error
+}
+
+func doctorCheck(name string, status DoctorStatus, fix, format string, a ...any) DoctorCheck {
+	detail := redact.Errorf(format, a...)
+	return DoctorCheck{Name: name, Status: status, Message: detail.Error(), Fix: fix, detail: detail}
+}
+
+// Failed returns the number of checks that failed.
+func (r *DoctorReport) Failed() int {
+	failed := 0
+	for _, c := range r.Checks {
+		if c.Status == DoctorFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Redacted returns a copy of the report that can be shared in a support
+// request. Its messages keep versions and statuses, but not values that may be
+// private, such as paths and usernames.
+func (r *DoctorReport) Redacted() *DoctorReport {
+	redacted := *r
+	redacted.Checks = slices.Clone(r.Checks)
+	for i, c := range redacted.Checks {
+		if c.detail != nil {
+			redacted.Checks[i].Message = redact.Error(c.detail).Error()
+		}
+	}
+	return &redacted
+}
+
+// Doctor diagnoses common problems with the Nix installation, the user's shell
+// and, if opts finds one, the devbox project. Unlike other commands, it doesn't
+// need Nix or a project, and it doesn't modify either.
+func Doctor(ctx context.Context, opts *devopt.Opts) *DoctorReport {
+	defer debug.FunctionTimer().End()
+
+	report := &DoctorReport{
+		DevboxVersion: build.Version,
+		Platform:      fmt.Sprintf("%s_%s", runtime.GOOS, runtime.GOARCH),
+	}
+	report.Checks = append(report.Checks, doctorNix(ctx)...)
+	report.Checks = append(report.Checks, doctorShell())
+
+	// Opening the project must not migrate devbox.json or devbox.lock.
+	openOpts := *opts
+	openOpts.SkipAutoMigrate = true
+
+	var box *Devbox
+	if _, err := findProjectDir(opts.Dir); err != nil {
+		report.Checks = append(report.Checks, doctorCheck("project", DoctorSkip, "",
+			"no devbox.json found"))
+	} else if box, err = Open(&openOpts); err != nil {
+		report.Checks = append(report.Checks, doctorCheck("project", DoctorFail,
+			"Fix the error in devbox.json.",
+			"can't open the project: %v", err))
+	} else {
+		report.Checks = append(report.Checks, doctorCheck("project", DoctorPass, "",
+			"%s", box.projectDir))
+	}
+	if box == nil {
+		for _, name := range []string{"direnv", "environment", "PATH"} {
+			report.Checks = append(report.Checks, doctorCheck(name, DoctorSkip, "", "not in a devbox project"))
+		}
+		return report
+	}
+	report.Checks = append(report.Checks, box.doctorDirenv(), box.doctorState(), box.doctorPath())
+	return report
+}
+
+// doctorNix checks the Nix installation. Each check needs the ones before it
+// to pass, so the rest are skipped after a failure.
+func doctorNix(ctx context.Context) []DoctorCheck {
+	names := []string{"nix", "nix daemon", "trusted user", "experimental features", "nix store"}
+	checks := make([]DoctorCheck, 0, len(names))
+	skipRest := func(reason string) []DoctorCheck {
+		for _, name := range names[len(checks):] {
+			checks = append(checks, doctorCheck(name, DoctorSkip, "", "%s", redact.Safe(reason)))
+		}
+		return checks
+	}
+
+	if !nix.BinaryInstalled() {
+		checks = append(checks, doctorCheck("nix", DoctorFail,
+			"Run `devbox setup nix` to install Nix.",
+			"Nix isn't installed or isn't in PATH"))
+		return skipRest("Nix isn't installed")
+	}
+	info, err := nix.Version()
+	if err != nil {
+		checks = append(checks, doctorCheck("nix", DoctorFail,
+			"Reinstall Nix with `devbox setup nix`.",
+			"can't get the Nix version: %v", err))
+		return skipRest("Nix isn't working")
+	}
+	if !info.AtLeast(nix.MinVersion) {
+		checks = append(checks, doctorCheck("nix", DoctorFail,
+			"Upgrade Nix, for example with `sudo nix upgrade-nix`.",
+			"Nix %s is older than %s, the oldest version that devbox supports",
+			redact.Safe(info.Version), redact.Safe(nix.MinVersion)))
+		return skipRest("Nix is too old")
+	}
+	checks = append(checks, doctorCheck("nix", DoctorPass, "",
+		"Nix %s (%s)", redact.Safe(info.Version), redact.Safe(info.System)))
+
+	daemonCheck, hasDaemon := doctorNixDaemon(ctx, info)
+	checks = append(checks, daemonCheck)
+	if daemonCheck.Status == DoctorFail {
+		return skipRest("the Nix daemon isn't reachable")
+	}
+
+	cfg, err := nix.CurrentConfig(ctx)
+	if err != nil {
+		checks = append(checks, doctorCheck("trusted user", DoctorFail,
+			"Check nix.conf for errors.",
+			"can't read the Nix configuration: %v", err))
+		return skipRest("the Nix configuration can't be read")
+	}
+	checks = append(checks,
+		doctorTrustedUser(ctx, cfg, hasDaemon),
+		doctorExperimentalFeatures(cfg),
+		doctorNixStore(info, hasDaemon),
+	)
+	return checks
+}
+
+// doctorNixDaemon checks that the Nix daemon is reachable. It also returns
+// whether Nix is a multi-user installation, which uses a daemon.
+func doctorNixDaemon(ctx context.Context, info nix.VersionInfo) (check DoctorCheck, hasDaemon bool) {
+	socket := filepath.Join(cmp.Or(info.StateDir, "/nix/var/nix"), "daemon-socket", "socket")
+	if _, err := os.Stat(socket); errors.Is(err, fs.ErrNotExist) {
+		return doctorCheck("nix daemon", DoctorPass, "",
+			"no daemon, Nix is a single-user installation"), false
+	}
+
+	restart := "Restart it with `sudo systemctl restart nix-daemon`."
+	if runtime.GOOS == "darwin" {
+		restart = "Restart it with `sudo launchctl kickstart -k system/org.nixos.nix-daemon`."
+	}
+	version, err := nix.DaemonVersion(ctx)
+	if err != nil {
+		return doctorCheck("nix daemon", DoctorFail,
+			"Check that the Nix daemon is running. "+restart,
+			"can't connect to the Nix daemon: %v", err), true
+	}
+	if version != info.Version {
+		return doctorCheck("nix daemon", DoctorWarn,
+			"The daemon may still be running an old version after an upgrade. "+restart,
+			"the Nix daemon is version %s, but the nix command is %s",
+			redact.Safe(version), redact.Safe(info.Version)), true
+	}
+	return doctorCheck("nix daemon", DoctorPass, "",
+		"Nix daemon %s is reachable", redact.Safe(version)), true
+}
+
+// doctorTrustedUser checks that Nix trusts the current user, which it
+// requires to use the binary caches and substituters that devbox configures.
+func doctorTrustedUser(ctx context.Context, cfg nix.Config, hasDaemon bool) DoctorCheck {
+	if !hasDaemon {
+		return doctorCheck("trusted user", DoctorPass, "",
+			"the user owns the single-user installation")
+	}
+	u, err := user.Current()
+	if err != nil {
+		return doctorCheck("trusted user", DoctorWarn, "",
+			"can't look up the current user: %v", err)
+	}
+	trusted, err := cfg.IsUserTrusted(ctx, u.Username)
+	if err != nil {
+		return doctorCheck("trusted user", DoctorWarn, "",
+			"can't check whether %s is a trusted Nix user: %v", u.Username, err)
+	}
+	if !trusted {
+		return doctorCheck("trusted user", DoctorWarn,
+			"Run `devbox cache configure`, or add your user to trusted-users in "+
+				"nix.conf and restart the Nix daemon.",
+			"%s isn't a trusted Nix user, so Nix ignores the binary caches that devbox configures",
+			u.Username)
+	}
+	return doctorCheck("trusted user", DoctorPass, "", "%s is a trusted Nix user", u.Username)
+}
+
+// doctorExperimentalFeatures checks that the features that devbox's generated
+// flakes use are enabled for nix commands that users run themselves. Devbox
+// enables them for its own commands.
+func doctorExperimentalFeatures(cfg nix.Config) DoctorCheck {
+	var missing []string
+	for _, feature := range []string{"nix-command", "flakes"} {
+		if !slices.Contains(cfg.ExperimentalFeatures.Value, feature) {
+			missing = append(missing, feature)
+		}
+	}
+	if len(missing) > 0 {
+		return doctorCheck("experimental features", DoctorWarn,
+			`Add "experimental-features = nix-command flakes" to ~/.config/nix/nix.conf.`,
+			"%s not enabled. Devbox enables them for its own commands, but "+
+				"running nix directly, for example in scripts, needs them",
+			redact.Safe(strings.Join(missing, " and ")))
+	}
+	return doctorCheck("experimental features", DoctorPass, "",
+		"nix-command and flakes are enabled")
+}
+
+// accessWrite is the W_OK mode of access(2).
+const accessWrite = 0x2
+
+// doctorNixStore checks that Nix can write to the store. In a multi-user
+// installation, only the daemon writes to it.
+func doctorNixStore(info nix.VersionInfo, hasDaemon bool) DoctorCheck {
+	storeDir := cmp.Or(info.StoreDir, "/nix/store")
+	if _, err := os.Stat(storeDir); err != nil {
+		return doctorCheck("nix store", DoctorFail,
+			"Reinstall Nix with `devbox setup nix`.",
+			"can't access the Nix store: %v", err)
+	}
+	if hasDaemon {
+		return doctorCheck("nix store", DoctorPass, "",
+			"%s is managed by the Nix daemon", storeDir)
+	}
+	for _, dir := range []string{storeDir, cmp.Or(info.StateDir, "/nix/var/nix")} {
+		if err := syscall.Access(dir, accessWrite); err != nil {
+			return doctorCheck("nix store", DoctorFail,
+				"Make your user the owner of the single-user Nix installation "+
+					"with `sudo chown -R $USER /nix`.",
+				"%s isn't writable: %v", dir, err)
+		}
+	}
+	return doctorCheck("nix store", DoctorPass, "", "%s is writable", storeDir)
+}
+
+// doctorShell checks that devbox shell recognizes the user's shell, so that
+// it can load the shell's init file.
+func doctorShell() DoctorCheck {
+	path := os.Getenv(envir.Shell)
+	if path == "" {
+		return doctorCheck("shell", DoctorWarn,
+			"Set SHELL to the path of your shell.",
+			"SHELL isn't set, so devbox shell starts bash from nixpkgs")
+	}
+	sh := initShellBinaryFields(path)
+	if sh.name == shUnknown {
+		return doctorCheck("shell", DoctorWarn,
+			"Use bash, zsh, fish, ksh or a POSIX shell such as dash.",
+			"devbox doesn't recognize %s, so devbox shell won't load its init file",
+			redact.Safe(filepath.Base(path)))
+	}
+	return doctorCheck("shell", DoctorPass, "",
+		"%s, with init file %s", redact.Safe(sh.name), sh.userShellrcPath)
+}
+
+// doctorDirenv checks that direnv loads the project's environment if the
+// project has an .envrc.
+func (d *Devbox) doctorDirenv() DoctorCheck {
+	if _, err := os.Stat(filepath.Join(d.projectDir, ".envrc")); err != nil {
+		return doctorCheck("direnv", DoctorSkip, "", "the project has no .envrc")
+	}
+	if _, err := exec.LookPath("direnv"); err != nil {
+		return doctorCheck("direnv", DoctorWarn,
+			"Install direnv from https://direnv.net, or use `devbox shell` instead.",
+			"the project has an .envrc, but direnv isn't installed")
+	}
+	if !d.IsDirenvActive() {
+		return doctorCheck("direnv", DoctorWarn,
+			"Run `direnv allow` in the project, and check that your shell's init "+
+				"file has direnv's hook.",
+			"direnv is installed, but isn't active in the project")
+	}
+	return doctorCheck("direnv", DoctorPass, "", "direnv is active in the project")
+}
+
+// doctorState checks that the installed environment matches devbox.json and
+// devbox.lock, using the hashes that devbox saves after installing.
+func (d *Devbox) doctorState() DoctorCheck {
+	upToDate, err := d.lockfile.IsUpToDateAndInstalled(isFishShell())
+	if err != nil {
+		return doctorCheck("environment", DoctorWarn,
+			"Run `devbox install` to update it.",
+			"can't check whether the environment is up to date: %v", err)
+	}
+	if !upToDate {
+		return doctorCheck("environment", DoctorWarn,
+			"Run `devbox install` to update it.",
+			"the installed environment doesn't match devbox.json and devbox.lock")
+	}
+	return doctorCheck("environment", DoctorPass, "",
+		"the installed environment matches devbox.json and devbox.lock")
+}
+
+// doctorPath checks that the package binaries that a devbox shell for the
+// project puts in PATH aren't shadowed by other directories, such as ones that
+// version managers add in the user's shell init file.
+func (d *Devbox) doctorPath() DoctorCheck {
+	if os.Getenv("DEVBOX_PROJECT_ROOT") != d.projectDir && !d.IsDirenvActive() {
+		return doctorCheck("PATH", DoctorSkip, "", "not in a devbox shell for the project")
+	}
+	binDir := nix.ProfileBinPath(d.projectDir)
+	entries, err := os.ReadDir(binDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return doctorCheck("PATH", DoctorSkip, "", "no packages are installed")
+	}
+	if err != nil {
+		return doctorCheck("PATH", DoctorWarn, "", "can't list the package binaries: %v", err)
+	}
+
+	devboxDir := filepath.Join(d.projectDir, ".devbox") + string(filepath.Separator)
+	var conflicts []string
+	for _, entry := range entries {
+		found, err := exec.LookPath(entry.Name())
+		if err != nil {
+			conflicts = append(conflicts, entry.Name()+" (not in PATH)")
+			continue
+		}
+		if strings.HasPrefix(found, devboxDir) || isSameFile(found, filepath.Join(binDir, entry.Name())) {
+			continue
+		}
+		conflicts = append(conflicts, fmt.Sprintf("%s (%s)", entry.Name(), found))
+	}
+	if len(conflicts) > 0 {
+		return doctorCheck("PATH", DoctorWarn,
+			"Make sure that your shell's init file doesn't add directories to the "+
+				"front of PATH after devbox, or use `devbox shell --pure`.",
+			"%d package binaries resolve outside the devbox environment: %s",
+			redact.Safe(len(conflicts)), strings.Join(conflicts, ", "))
+	}
+	return doctorCheck("PATH", DoctorPass, "",
+		"all %d package binaries resolve to the devbox environment", redact.Safe(len(entries)))
+}
+
+func isSameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
@@ -0,0 +1,88 @@
+package devbox
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/redact"
+)
+
+func TestDoctorReportRedacted(t *testing.T) {
+	report := &DoctorReport{Checks: []DoctorCheck{
+		doctorCheck("trusted user", DoctorWarn, "Run `devbox cache configure`.",
+			"%s isn't a trusted Nix user", "alex"),
+		doctorCheck("nix", DoctorFail, "", "Nix %s is too old", redact.Safe("2.11.0")),
+	}}
+
+	redacted := report.Redacted()
+	if got := redacted.Checks[0].Message; strings.Contains(got, "alex") {
+		t.Errorf("redacted message %q contains the username", got)
+	}
+	if got, want := redacted.Checks[1].Message, "Nix 2.11.0 is too old"; got != want {
+		t.Errorf("got redacted message %q, want %q", got, want)
+	}
+	if got := redacted.Checks[0].Fix; got != report.Checks[0].Fix {
+		t.Errorf("got redacted fix %q, want it unchanged", got)
+	}
+	if got, want := report.Checks[0].Message, "alex isn't a trusted Nix user"; got != want {
+		t.Errorf("Redacted changed the original message to %q, want %q", got, want)
+	}
+	if report.Failed() != 1 {
+		t.Errorf("got %d failed checks, want 1", report.Failed())
+	}
+}
+
+func TestDoctorShell(t *testing.T) {
+	tests := []struct {
+		shell string
+		want  DoctorStatus
+	}{
+		{"/bin/zsh", DoctorPass},
+		{"/usr/local/bin/fish", DoctorPass},
+		{"/usr/bin/xonsh", DoctorWarn},
+		{"", DoctorWarn},
+	}
+	for _, test := range tests {
+		t.Run(test.shell, func(t *testing.T) {
+			t.Setenv("SHELL", test.shell)
+			if got := doctorShell(); got.Status != test.want {
+				t.Errorf("got status %s (%s), want %s", got.Status, got.Message, test.want)
+			}
+		})
+	}
+}
+
+func TestDoctorNixStore(t *testing.T) {
+	dir := t.TempDir()
+	info := nix.VersionInfo{
+		StoreDir: filepath.Join(dir, "store"),
+		StateDir: filepath.Join(dir, "var"),
+	}
+	if got := doctorNixStore(info, false); got.Status != DoctorFail {
+		t.Errorf("got status %s for a missing store, want fail", got.Status)
+	}
+
+	for _, path := range []string{info.StoreDir, info.StateDir} {
+		if err := os.Mkdir(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := doctorNixStore(info, false); got.Status != DoctorPass {
+		t.Errorf("got status %s (%s) for a writable store, want pass", got.Status, got.Message)
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	if err := os.Chmod(info.StateDir, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	if got := doctorNixStore(info, false); got.Status != DoctorFail {
+		t.Errorf("got status %s for a read-only state directory, want fail", got.Status)
+	}
+	if got := doctorNixStore(info, true); got.Status != DoctorPass {
+		t.Errorf("got status %s with a daemon, want pass", got.Status)
+	}
+}
@@ -0,0 +1,249 @@
+package devbox
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+	"go.jetpack.io/devbox/internal/fileutil"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/xdg"
+)
+
+// DefaultGCCacheAge is how long a file in devbox's cache directory can go
+// unmodified before devbox gc --global removes it.
+const DefaultGCCacheAge = 30 * 24 * time.Hour
+
+// GCItem is a file or directory that devbox gc removes.
+type GCItem struct {
+	Path   string
+	Reason string
+	// GCRoot is true if the item is a link that keeps store paths alive.
+	// Removing it lets nix store gc delete them.
+	GCRoot bool
+	// Size is the disk space that removing the item reclaims, not counting
+	// store paths.
+	Size int64
+}
+
+// GCReport lists what devbox gc removed.
+type GCReport struct {
+	Items []GCItem
+}
+
+// ReclaimedBytes returns the disk space that removing the items reclaimed,
+// not counting store paths.
+func (r *GCReport) ReclaimedBytes() int64 {
+	total := int64(0)
+	for _, item := range r.Items {
+		total += item.Size
+	}
+	return total
+}
+
+// GCRoots returns the number of gc roots that were removed.
+func (r *GCReport) GCRoots() int {
+	roots := 0
+	for _, item := range r.Items {
+		if item.GCRoot {
+			roots++
+		}
+	}
+	return roots
+}
+
+func (r *GCReport) add(path, reason string, gcRoot, dryRun bool) error {
+	size, err := diskUsage(path)
+	if err != nil {
+		return err
+	}
+	if !dryRun {
+		if err := os.RemoveAll(path); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	r.Items = append(r.Items, GCItem{Path: path, Reason: reason, GCRoot: gcRoot, Size: size})
+	return nil
+}
+
+func (r *GCReport) addOldGenerations(profilePath, reason string, dryRun bool) error {
+	generations, err := nix.OldProfileGenerations(profilePath)
+	if err != nil {
+		return err
+	}
+	for _, generation := range generations {
+		if err := r.add(generation, reason, true, dryRun); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GC removes the old generations of the project's profile. If the project's
+// environment is out of date, it also removes the generated files and the
+// cached environment, which are recomputed the next time they're needed.
+func (d *Devbox) GC(ctx context.Context, w io.Writer, opts devopt.GCOpts) (*GCReport, error) {
+	defer debug.FunctionTimer().End()
+
+	report := &GCReport{}
+	profilePath := filepath.Join(d.projectDir, nix.ProfilePath)
+	if err := report.addOldGenerations(profilePath, "old profile generation", opts.DryRun); err != nil {
+		return nil, err
+	}
+
+	upToDate, err := d.lockfile.IsUpToDateAndInstalled(isFishShell())
+	if err != nil {
+		return nil, err
+	}
+	if !upToDate {
+		for _, path := range []string{d.nixPrintDevEnvCachePath(), filepath.Join(d.projectDir, ".devbox/gen")} {
+			if fileutil.Exists(path) {
+				if err := report.add(path, "stale environment cache", false, opts.DryRun); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if opts.NixStore {
+		return report, nix.StoreGC(ctx, w, opts.DryRun)
+	}
+	return report, nil
+}
+
+// GlobalGC removes the old generations of the global and utility profiles,
+// the profiles of directories that are no longer devbox projects, and files in
+// devbox's cache directory that are older than opts.CacheAge.
+func GlobalGC(ctx context.Context, w io.Writer, opts devopt.GCOpts) (*GCReport, error) {
+	defer debug.FunctionTimer().End()
+
+	report := &GCReport{}
+	globalPath, err := GlobalDataPath()
+	if err != nil {
+		return nil, err
+	}
+	utilityProfilePath, err := utilityNixProfilePath()
+	if err != nil {
+		return nil, err
+	}
+	for _, profilePath := range []string{filepath.Join(globalPath, nix.ProfilePath), utilityProfilePath} {
+		if err := report.addOldGenerations(profilePath, "old profile generation", opts.DryRun); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := addOrphanedProfiles(report, opts.DryRun); err != nil {
+		return nil, err
+	}
+
+	cacheAge := opts.CacheAge
+	if cacheAge == 0 {
+		cacheAge = DefaultGCCacheAge
+	}
+	if err := addStaleCacheFiles(report, xdg.CacheSubpath("devbox"), cacheAge, opts.DryRun); err != nil {
+		return nil, err
+	}
+
+	if opts.NixStore {
+		return report, nix.StoreGC(ctx, w, opts.DryRun)
+	}
+	return report, nil
+}
+
+// addOrphanedProfiles adds the profile generations of projects whose
+// devbox.json was deleted. Roots of projects whose directory was deleted are
+// dangling, and nix store gc removes them on its own.
+func addOrphanedProfiles(report *GCReport, dryRun bool) error {
+	roots, err := nix.AutoGCRoots()
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		projectDir, ok := nix.ProjectDirOfProfile(root)
+		if !ok || !fileutil.Exists(projectDir) || !fileutil.Exists(root) {
+			continue
+		}
+		if fileutil.Exists(filepath.Join(projectDir, configfile.DefaultName)) {
+			continue
+		}
+		if err := report.add(root, "profile of a deleted project", true, dryRun); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filecacheDirs match the directories in the devbox cache that filecache
+// manages, relative to the cache. Their entries expire when their own TTL
+// ends, which can be longer than the cache age, such as for narinfo hits.
+var filecacheDirs = []string{
+	"narinfo",     // devpkg.narInfoDiskCache
+	"nix",         // nix.SearchNixpkgsAttribute
+	"plugin",      // plugin.githubCache
+	"*/providers", // nixcache.Provider.Credentials, per devbox version
+}
+
+// addStaleCacheFiles adds the files in dir that weren't modified for maxAge,
+// except for the files that filecache manages.
+func addStaleCacheFiles(report *GCReport, dir string, maxAge time.Duration, dryRun bool) error {
+	cutoff := time.Now().Add(-maxAge)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			for _, pattern := range filecacheDirs {
+				if ok, _ := filepath.Match(pattern, filepath.ToSlash(rel)); ok {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().Before(cutoff) {
+			return report.add(path, "stale cache", false, dryRun)
+		}
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// diskUsage returns the size of the files in path without following
+// symlinks.
+func diskUsage(path string) (int64, error) {
+	size := int64(0)
+	err := filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, errors.WithStack(err)
+}
@@ -0,0 +1,58 @@
+package devbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddStaleCacheFiles(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "includes", "stale")
+	fresh := filepath.Join(dir, "fresh")
+	// filecache expires the entries of these on its own.
+	narinfo := filepath.Join(dir, "narinfo", "stale")
+	credentials := filepath.Join(dir, "0.10.7", "providers", "nixcache", "stale")
+	for _, path := range []string{stale, fresh, narinfo, credentials} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("cached"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for _, path := range []string{stale, narinfo, credentials} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dryRun := &GCReport{}
+	if err := addStaleCacheFiles(dryRun, dir, 24*time.Hour, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(dryRun.Items) != 1 || dryRun.Items[0].Path != stale {
+		t.Fatalf("got items %+v, want only %s", dryRun.Items, stale)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Errorf("dry run removed %s", stale)
+	}
+
+	report := &GCReport{}
+	if err := addStaleCacheFiles(report, dir, 24*time.Hour, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := report.ReclaimedBytes(); got != int64(len("cached")) {
+		t.Errorf("got %d reclaimed bytes, want %d", got, len("cached"))
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("%s wasn't removed", stale)
+	}
+	for _, path := range []string{fresh, narinfo, credentials} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed", path)
+		}
+	}
+}
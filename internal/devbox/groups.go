@@ -0,0 +1,95 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+)
+
+// packageGroupsEnv has the package groups of the devbox shell or script that
+// the environment is from. Nested devbox commands in the same project use the
+// same groups so that they don't uninstall packages that are in use.
+const packageGroupsEnv = "DEVBOX_PACKAGE_GROUPS"
+
+// selectGroups sets the package groups that are installed. It returns an
+// error if a group has no packages.
+func (d *Devbox) selectGroups(opts devopt.PackageGroups) error {
+	groups := []string{configfile.DefaultGroup}
+	switch {
+	case len(opts.Only) > 0:
+		groups = opts.Only
+	case len(opts.With) > 0:
+		groups = append(groups, opts.With...)
+	case os.Getenv(packageGroupsEnv) != "" && os.Getenv("DEVBOX_PROJECT_ROOT") == d.projectDir:
+		d.setGroups(strings.Split(os.Getenv(packageGroupsEnv), ","))
+		return nil
+	}
+
+	known := d.Groups()
+	for _, g := range groups {
+		if !slices.Contains(known, g) {
+			return usererr.New(
+				"No packages are in group %q. The groups in %s are: %s.",
+				g, configfile.DefaultName, strings.Join(known, ", "),
+			)
+		}
+	}
+	d.setGroups(groups)
+	return nil
+}
+
+// setGroups selects the groups, and the plugins of their packages.
+func (d *Devbox) setGroups(groups []string) {
+	d.groups = compactGroups(groups)
+	d.cfg.SelectGroups(d.groups)
+}
+
+// addScriptGroups adds the package groups that a script needs to the selected
+// groups.
+func (d *Devbox) addScriptGroups(name string) {
+	if script, ok := d.cfg.Scripts()[name]; ok && len(script.Groups) > 0 {
+		d.setGroups(append(d.groups, script.Groups...))
+	}
+}
+
+// Groups returns the names of the package groups in the project, including
+// the default group, in sorted order.
+func (d *Devbox) Groups() []string {
+	groups := []string{configfile.DefaultGroup}
+	for _, pkg := range d.cfg.Packages(true /*includeRemovedTriggerPackages*/) {
+		groups = append(groups, pkg.GroupsOrDefault()...)
+	}
+	slices.Sort(groups)
+	return slices.Compact(groups)
+}
+
+// SelectedGroups returns the package groups that are installed.
+func (d *Devbox) SelectedGroups() []string {
+	return d.groups
+}
+
+// selectedPackages returns the packages in the selected groups.
+func (d *Devbox) selectedPackages() []configfile.Package {
+	packages := []configfile.Package{}
+	for _, pkg := range d.cfg.Packages(false /*includeRemovedTriggerPackages*/) {
+		if pkg.InGroups(d.groups) {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages
+}
+
+// compactGroups sorts groups and removes duplicates so that the same
+// selection always has the same config hash.
+func compactGroups(groups []string) []string {
+	groups = slices.Clone(groups)
+	slices.Sort(groups)
+	return slices.Compact(groups)
+}
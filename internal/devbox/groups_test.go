@@ -0,0 +1,158 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/plugin"
+)
+
+const groupsConfig = `{
+  "packages": {
+    "hello": "latest",
+    "playwright": {"version": "latest", "groups": ["e2e"]},
+    "mkdocs": {"version": "latest", "groups": ["docs", "e2e"]}
+  },
+  "shell": {
+    "scripts": {
+      "docs": {"command": "mkdocs serve", "groups": ["docs"]},
+      "test": "go test ./..."
+    }
+  }
+}`
+
+func openGroupsProject(t *testing.T, groups devopt.PackageGroups) (*Devbox, error) {
+	t.Helper()
+	t.Setenv(packageGroupsEnv, "")
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "devbox.json"), []byte(groupsConfig), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Open(&devopt.Opts{Dir: dir, Groups: groups, Stderr: os.Stderr})
+}
+
+func selectedNames(d *Devbox) []string {
+	names := []string{}
+	for _, pkg := range d.selectedPackages() {
+		names = append(names, pkg.VersionedName())
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestPackageGroups(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups devopt.PackageGroups
+		want   []string
+	}{
+		{
+			name: "Default",
+			want: []string{"hello@latest"},
+		},
+		{
+			name:   "With",
+			groups: devopt.PackageGroups{With: []string{"docs"}},
+			want:   []string{"hello@latest", "mkdocs@latest"},
+		},
+		{
+			name:   "Only",
+			groups: devopt.PackageGroups{Only: []string{"e2e"}},
+			want:   []string{"mkdocs@latest", "playwright@latest"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := openGroupsProject(t, tt.groups)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := selectedNames(d); !slices.Equal(got, tt.want) {
+				t.Errorf("got selected packages %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackageGroupsUnknown(t *testing.T) {
+	_, err := openGroupsProject(t, devopt.PackageGroups{With: []string{"ml"}})
+	if err == nil {
+		t.Error("got nil error for a group without packages")
+	}
+}
+
+func TestPackageGroupsScript(t *testing.T) {
+	d, err := openGroupsProject(t, devopt.PackageGroups{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.addScriptGroups("test")
+	if got := d.SelectedGroups(); !slices.Equal(got, []string{"default"}) {
+		t.Errorf("got groups %q for a script without groups, want default", got)
+	}
+	d.addScriptGroups("docs")
+	if got := d.SelectedGroups(); !slices.Equal(got, []string{"default", "docs"}) {
+		t.Errorf("got groups %q, want default and docs", got)
+	}
+}
+
+func TestPackageGroupsConfigHash(t *testing.T) {
+	d, err := openGroupsProject(t, devopt.PackageGroups{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultHash, err := d.ConfigHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.addScriptGroups("docs")
+	docsHash, err := d.ConfigHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defaultHash == docsHash {
+		t.Error("got the same config hash for different groups")
+	}
+}
+
+func TestPackageGroupsPlugins(t *testing.T) {
+	t.Setenv(packageGroupsEnv, "")
+	dir := t.TempDir()
+	config := `{"packages": {"hello": "latest", "postgresql": {"version": "latest", "groups": ["db"]}}}`
+	err := os.WriteFile(filepath.Join(dir, "devbox.json"), []byte(config), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		groups devopt.PackageGroups
+		want   bool
+	}{
+		{name: "Unselected", want: false},
+		{name: "Selected", groups: devopt.PackageGroups{With: []string{"db"}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Open(&devopt.Opts{Dir: dir, Groups: tt.groups, Stderr: os.Stderr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			plugins := d.Config().IncludedPluginConfigs()
+			hasPlugin := slices.ContainsFunc(plugins, func(p *plugin.Config) bool { return p.Name == "postgresql" })
+			if hasPlugin != tt.want {
+				t.Errorf("got postgresql plugin = %t, want %t", hasPlugin, tt.want)
+			}
+			if _, hasEnv := d.Config().Env()["PGDATA"]; hasEnv != tt.want {
+				t.Errorf("got PGDATA in env = %t, want %t", hasEnv, tt.want)
+			}
+		})
+	}
+}
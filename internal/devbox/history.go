@@ -0,0 +1,291 @@
+package devbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+const (
+	historyFile = ".devbox/history.json"
+
+	// maxHistoryEntries is the number of entries that are kept. Older
+	// entries are removed.
+	maxHistoryEntries = 20
+)
+
+// HistoryEntry is a snapshot of the project's environment that devbox
+// rollback can restore.
+type HistoryEntry struct {
+	ID   int       `json:"id"`
+	Time time.Time `json:"time"`
+	// Command is the devbox command that produced the snapshot.
+	Command string `json:"command"`
+
+	ConfigHash string `json:"config_hash"`
+	Config     string `json:"config"`
+	Lockfile   string `json:"lockfile,omitempty"`
+	// Generation is the generation of the project's profile, or 0 if the
+	// project didn't have a profile.
+	Generation int `json:"generation,omitempty"`
+	// RestoredFrom is the ID of the entry that devbox rollback restored.
+	RestoredFrom int `json:"restored_from,omitempty"`
+}
+
+// effectiveID returns the ID of the entry whose state this entry has.
+func (e *HistoryEntry) effectiveID() int {
+	if e.RestoredFrom != 0 {
+		return e.RestoredFrom
+	}
+	return e.ID
+}
+
+func (e *HistoryEntry) sameState(other *HistoryEntry) bool {
+	return e.Config == other.Config && e.Lockfile == other.Lockfile
+}
+
+type history struct {
+	Entries []*HistoryEntry `json:"entries"`
+}
+
+func lockfilePath(projectDir string) string {
+	return filepath.Join(projectDir, "devbox.lock")
+}
+
+func (d *Devbox) historyPath() string {
+	return filepath.Join(d.projectDir, historyFile)
+}
+
+func (d *Devbox) loadHistory() (*history, error) {
+	h := &history{}
+	err := cuecfg.ParseFile(d.historyPath(), h)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	return h, err
+}
+
+func (d *Devbox) saveHistory(h *history) error {
+	if len(h.Entries) > maxHistoryEntries {
+		h.Entries = h.Entries[len(h.Entries)-maxHistoryEntries:]
+	}
+	if err := os.MkdirAll(filepath.Dir(d.historyPath()), 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+	return cuecfg.WriteFile(d.historyPath(), h)
+}
+
+// snapshot returns an entry with the state of the project on disk.
+func (d *Devbox) snapshot(command string) (*HistoryEntry, error) {
+	config, err := os.ReadFile(filepath.Join(d.projectDir, configfile.DefaultName))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	lockfile, err := os.ReadFile(lockfilePath(d.projectDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, errors.WithStack(err)
+	}
+	configHash, err := d.ConfigHash()
+	if err != nil {
+		return nil, err
+	}
+	generation, err := nix.ProfileGeneration(filepath.Join(d.projectDir, nix.ProfilePath))
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryEntry{
+		Time:       time.Now(),
+		Command:    command,
+		ConfigHash: configHash,
+		Config:     string(config),
+		Lockfile:   string(lockfile),
+		Generation: generation,
+	}, nil
+}
+
+// append adds an entry unless the project is in the same state as the
+// latest entry.
+func (h *history) append(entry *HistoryEntry) bool {
+	if len(h.Entries) > 0 {
+		last := h.Entries[len(h.Entries)-1]
+		if last.sameState(entry) {
+			return false
+		}
+		entry.ID = last.ID + 1
+	} else {
+		entry.ID = 1
+	}
+	h.Entries = append(h.Entries, entry)
+	return true
+}
+
+// trackHistory records the state of the project before and after a command
+// that changes it. It's used as:
+//
+//	defer d.trackHistory("add", pkgs)(&err)
+//
+// The state after the command is only recorded if it succeeds. Commands that
+// run other tracked commands, such as update, record a single entry.
+func (d *Devbox) trackHistory(command string, args []string) func(*error) {
+	d.historyDepth++
+	if d.historyDepth > 1 {
+		return func(*error) { d.historyDepth-- }
+	}
+	if len(args) > 0 {
+		command += " " + strings.Join(args, " ")
+	}
+
+	// The state before the command is recorded if it's not the latest
+	// entry, for example because devbox.json was edited by hand.
+	before, err := d.snapshot("")
+	if err != nil {
+		debug.Log("failed to snapshot history: %v", err)
+	}
+	return func(errp *error) {
+		d.historyDepth--
+		if before == nil || *errp != nil {
+			return
+		}
+		if err := d.recordHistory(before, command); err != nil {
+			// History is a convenience, so it never fails a command.
+			debug.Log("failed to record history: %v", err)
+		}
+	}
+}
+
+func (d *Devbox) recordHistory(before *HistoryEntry, command string) error {
+	h, err := d.loadHistory()
+	if err != nil {
+		return err
+	}
+	before.Command = "(before " + command + ")"
+	if len(h.Entries) == 0 {
+		before.Command = "(initial state)"
+	}
+	h.append(before)
+
+	after, err := d.snapshot(command)
+	if err != nil {
+		return err
+	}
+	if h.append(after) {
+		return d.saveHistory(h)
+	}
+	return nil
+}
+
+// History returns the recorded snapshots of the project, oldest first.
+func (d *Devbox) History() ([]*HistoryEntry, error) {
+	h, err := d.loadHistory()
+	return h.Entries, err
+}
+
+// Rollback restores devbox.json, devbox.lock and the project's profile from
+// a history entry. If id is 0, it restores the entry before the current one,
+// so running it repeatedly keeps going back in history.
+//
+// If the entry's profile generation was removed, for example by devbox gc,
+// the packages are installed again.
+func (d *Devbox) Rollback(ctx context.Context, id int) (*HistoryEntry, error) {
+	h, err := d.loadHistory()
+	if err != nil {
+		return nil, err
+	}
+	if len(h.Entries) == 0 {
+		return nil, usererr.New(
+			"This project has no history yet. It's recorded by devbox add, rm and update.")
+	}
+	target, err := h.rollbackTarget(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(d.projectDir, configfile.DefaultName), []byte(target.Config), 0o644); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if target.Lockfile == "" {
+		err = os.Remove(lockfilePath(d.projectDir))
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	} else {
+		err = os.WriteFile(lockfilePath(d.projectDir), []byte(target.Lockfile), 0o644)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	profilePath := filepath.Join(d.projectDir, nix.ProfilePath)
+	if target.Generation > 0 && nix.ProfileGenerationExists(profilePath, target.Generation) {
+		if err := nix.ProfileRollback(ctx, profilePath, target.Generation); err != nil {
+			return nil, err
+		}
+	} else if target.Generation > 0 {
+		ux.Fwarning(d.stderr,
+			"Profile generation %d was removed, so the packages are installed again.\n",
+			target.Generation)
+	}
+
+	// Reopen the project to read the restored files, and bring the rest of
+	// the environment in line with them. If the profile was rolled back,
+	// its packages already match and nothing is installed.
+	restored, err := Open(&devopt.Opts{
+		Dir:         d.projectDir,
+		Environment: d.environment,
+		Stderr:      d.stderr,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := restored.ensureStateIsUpToDate(ctx, ensure); err != nil {
+		return nil, err
+	}
+
+	entry, err := restored.snapshot(fmt.Sprintf("rollback to %d", target.effectiveID()))
+	if err != nil {
+		return nil, err
+	}
+	entry.RestoredFrom = target.effectiveID()
+	// Record the rollback even if the state matches the latest entry, so
+	// that the next rollback goes back further.
+	last := h.Entries[len(h.Entries)-1]
+	entry.ID = last.ID + 1
+	h.Entries = append(h.Entries, entry)
+	return target, d.saveHistory(h)
+}
+
+func (h *history) rollbackTarget(id int) (*HistoryEntry, error) {
+	if id != 0 {
+		for _, entry := range h.Entries {
+			if entry.ID == id {
+				return entry, nil
+			}
+		}
+		return nil, usererr.New("History entry %d doesn't exist. Run `devbox history` to list them.", id)
+	}
+
+	current := h.Entries[len(h.Entries)-1].effectiveID()
+	var target *HistoryEntry
+	for _, entry := range h.Entries {
+		if entry.ID < current && entry.RestoredFrom == 0 {
+			target = entry
+		}
+	}
+	if target == nil {
+		return nil, usererr.New("There's no earlier history entry to roll back to.")
+	}
+	return target, nil
+}
@@ -0,0 +1,53 @@
+package devbox
+
+import "testing"
+
+func TestHistoryAppend(t *testing.T) {
+	h := &history{}
+	if !h.append(&HistoryEntry{Config: "a"}) {
+		t.Fatal("the first entry wasn't appended")
+	}
+	if h.append(&HistoryEntry{Config: "a"}) {
+		t.Error("an entry with the same state was appended")
+	}
+	if !h.append(&HistoryEntry{Config: "a", Lockfile: "b"}) {
+		t.Error("an entry with a different lockfile wasn't appended")
+	}
+	if got := h.Entries[len(h.Entries)-1].ID; got != 2 {
+		t.Errorf("got ID %d for the second entry, want 2", got)
+	}
+}
+
+func TestHistoryRollbackTarget(t *testing.T) {
+	h := &history{Entries: []*HistoryEntry{
+		{ID: 1, Config: "a"},
+		{ID: 2, Config: "b"},
+		{ID: 3, Config: "c"},
+	}}
+
+	target, err := h.rollbackTarget(0)
+	if err != nil || target.ID != 2 {
+		t.Fatalf("rollbackTarget(0) = %+v, %v, want entry 2", target, err)
+	}
+
+	// After rolling back to 2, the next rollback goes to 1 instead of
+	// returning to 3.
+	h.Entries = append(h.Entries, &HistoryEntry{ID: 4, Config: "b", RestoredFrom: 2})
+	target, err = h.rollbackTarget(0)
+	if err != nil || target.ID != 1 {
+		t.Fatalf("rollbackTarget(0) after a rollback = %+v, %v, want entry 1", target, err)
+	}
+
+	h.Entries = append(h.Entries, &HistoryEntry{ID: 5, Config: "a", RestoredFrom: 1})
+	if _, err := h.rollbackTarget(0); err == nil {
+		t.Error("got nil error when there's no earlier entry")
+	}
+
+	target, err = h.rollbackTarget(3)
+	if err != nil || target.ID != 3 {
+		t.Errorf("rollbackTarget(3) = %+v, %v, want entry 3", target, err)
+	}
+	if _, err := h.rollbackTarget(9); err == nil {
+		t.Error("got nil error for a missing entry")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/ux/progress"
+)
+
+// hookEventsEnv names the file that the init hooks wrapper appends "start" and
+// "end" to when the hooks run. See shellgen/tmpl/init-hook-wrapper.tmpl.
+const hookEventsEnv = "__DEVBOX_HOOK_EVENTS"
+
+const hookEventsPollInterval = 50 * time.Millisecond
+
+// watchInitHooks reports the init hooks that a script or shell runs as hook
+// tasks, and times them. Hooks run inside the shell, so this is only done for
+// the JSON format or when timings are recorded, where the events are worth
+// polling for. It sets the variable that enables the events in env, and
+// returns a function to call with the shell's result once it exits.
+func watchInitHooks(env map[string]string) (stop func(error)) {
+	if !progress.IsJSON() && !debug.TimingsEnabled() {
+		return func(error) {}
+	}
+	f, err := os.CreateTemp("", "devbox-hook-events-")
+	if err != nil {
+		debug.Log("failed to create hook events file: %v", err)
+		return func(error) {}
+	}
+	path := f.Name()
+	f.Close()
+	env[hookEventsEnv] = path
+
+	w := &hookEventsWatcher{path: path}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(hookEventsPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				w.poll()
+			}
+		}
+	}()
+
+	return func(err error) {
+		close(done)
+		wg.Wait()
+		w.poll()
+		w.end(err)
+		if err := os.Remove(path); err != nil {
+			debug.Log("failed to remove hook events file: %v", err)
+		}
+	}
+}
+
+type hookEventsWatcher struct {
+	path string
+	// seen is the number of events that were already reported.
+	seen int
+	// task and endTimer are set while the hooks run. The times are only as
+	// accurate as the polling interval.
+	task     *progress.Task
+	endTimer func()
+}
+
+// poll reports the events that were appended since the last poll.
+func (w *hookEventsWatcher) poll() {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		debug.Log("failed to read hook events file: %v", err)
+		return
+	}
+	// Only complete lines are events, the last one may still be written.
+	lines := strings.Split(string(data), "\n")
+	lines = lines[:len(lines)-1]
+	for _, line := range lines[min(w.seen, len(lines)):] {
+		switch line {
+		case "start":
+			w.start()
+		case "end":
+			w.end(nil)
+		}
+	}
+	w.seen = len(lines)
+}
+
+func (w *hookEventsWatcher) start() {
+	if w.endTimer != nil {
+		return
+	}
+	w.endTimer = debug.Timer("init hooks").End
+	// In the text format, the spinner would garble the output of the
+	// script or shell.
+	if progress.IsJSON() {
+		w.task = progress.Start(progress.Hook, "Running init hooks")
+	}
+}
+
+func (w *hookEventsWatcher) end(err error) {
+	if w.endTimer == nil {
+		return
+	}
+	w.endTimer()
+	w.endTimer = nil
+	if w.task != nil {
+		w.task.End(err)
+		w.task = nil
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/devpkg"
+	"go.jetpack.io/devbox/internal/fileutil"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/nix/nixstore"
+	"go.jetpack.io/devbox/internal/ociimage"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+const (
+	ImageFormatOCI     = "oci"
+	ImageFormatArchive = "archive"
+)
+
+// BuildImage builds a container image that contains the closure of the
+// project's Nix profile. It doesn't need Docker or Nix inside the image.
+func (d *Devbox) BuildImage(ctx context.Context, opts devopt.BuildImageOpts) error {
+	defer debug.FunctionTimer().End()
+
+	arch, err := ociimage.Architecture(nix.System())
+	if err != nil {
+		return usererr.WithUserMessage(err, "Unable to build a container image on this system.")
+	}
+	if opts.Format != ImageFormatOCI && opts.Format != ImageFormatArchive {
+		return usererr.New("Unknown image format %q. Supported formats are %q and %q.",
+			opts.Format, ImageFormatOCI, ImageFormatArchive)
+	}
+	if !opts.Force && fileutil.Exists(opts.Output) {
+		return usererr.New(
+			"%s already exists. Remove it or use --force to overwrite it.", opts.Output)
+	}
+	if runx := lo.Filter(d.InstallablePackages(), devpkg.IsRunX); len(runx) > 0 {
+		ux.Fwarning(d.stderr, "runx packages are not included in the image: %v\n", runx)
+	}
+
+	if err := d.ensureStateIsUpToDate(ctx, ensure); err != nil {
+		return err
+	}
+	profilePath, err := d.profilePath()
+	if err != nil {
+		return err
+	}
+	profileStorePath, err := filepath.EvalSymlinks(profilePath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	store, err := nixstore.Local(filepath.Dir(profileStorePath))
+	if err != nil {
+		return err
+	}
+	profile, err := store.Package(filepath.Base(profileStorePath))
+	if err != nil {
+		return err
+	}
+
+	// Paths in the image must point into /nix/store even if the local store
+	// lives somewhere else.
+	imageProfile := path.Join("/nix/store", profile.StoreName)
+	workingDir := lo.Ternary(opts.WorkingDir != "", opts.WorkingDir, "/")
+
+	// The image has the environment of devbox shell without the variables
+	// of the host, and without secrets, which would be stored in a layer.
+	computedEnv, err := d.computeEnvFrom(
+		ctx, nil /*hostEnv*/, true /*usePrintDevEnvCache*/, false /*includeSecrets*/)
+	if err != nil {
+		return err
+	}
+	env := imageEnv(computedEnv, d.projectDir, imageProfile, workingDir)
+
+	img := &ociimage.Image{
+		Packages:     []*nixstore.Package{profile},
+		Env:          env,
+		Entrypoint:   opts.Entrypoint,
+		Cmd:          opts.Cmd,
+		WorkingDir:   workingDir,
+		Architecture: arch,
+		Tag:          opts.Tag,
+		MaxLayers:    opts.MaxLayers,
+	}
+	if len(img.Entrypoint) == 0 && len(img.Cmd) == 0 {
+		img.Cmd = defaultImageCmd(profile, imageProfile)
+	}
+
+	if opts.Force {
+		if err := os.RemoveAll(opts.Output); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if opts.Format == ImageFormatOCI {
+		err = img.WriteLayout(opts.Output)
+	} else {
+		err = writeImageArchive(img, opts.Output)
+	}
+	if err != nil {
+		return err
+	}
+	ux.Fsuccess(d.stderr, "Wrote image to %s\n", opts.Output)
+	return nil
+}
+
+// imageEnv moves the paths in an environment from the project's profile to
+// the image's profile, and from the project directory to the image's working
+// directory.
+func imageEnv(env map[string]string, projectDir, imageProfile, workingDir string) map[string]string {
+	replacer := strings.NewReplacer(
+		filepath.Join(projectDir, nix.ProfilePath), imageProfile,
+		projectDir+"/", strings.TrimSuffix(workingDir, "/")+"/",
+		projectDir, workingDir,
+	)
+	result := make(map[string]string, len(env))
+	for k, v := range env {
+		result[k] = replacer.Replace(v)
+	}
+	return result
+}
+
+// defaultImageCmd runs a shell from the profile if there is one.
+func defaultImageCmd(profile *nixstore.Package, imageProfile string) []string {
+	for _, shell := range []string{"bash", "sh"} {
+		bin := path.Join("bin", shell)
+		if _, err := fs.Stat(profile, bin); err == nil {
+			return []string{path.Join(imageProfile, bin)}
+		}
+	}
+	return nil
+}
+
+func writeImageArchive(img *ociimage.Image, outPath string) error {
+	f, err := os.Create(outPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	if err := img.WriteArchive(f); err != nil {
+		return err
+	}
+	return errors.WithStack(f.Close())
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImageEnv(t *testing.T) {
+	got := imageEnv(map[string]string{
+		"PATH":                "/project/.devbox/nix/profile/default/bin:/nix/store/abc-python/bin",
+		"DEVBOX_PACKAGES_DIR": "/project/.devbox/nix/profile/default",
+		"DEVBOX_PROJECT_ROOT": "/project",
+		"PGDATA":              "/project/.devbox/virtenv/postgresql/data",
+		"PYTHONPATH":          "/nix/store/def-python/lib/python3.12/site-packages",
+	}, "/project", "/nix/store/xyz-profile", "/")
+	want := map[string]string{
+		"PATH":                "/nix/store/xyz-profile/bin:/nix/store/abc-python/bin",
+		"DEVBOX_PACKAGES_DIR": "/nix/store/xyz-profile",
+		"DEVBOX_PROJECT_ROOT": "/",
+		"PGDATA":              "/.devbox/virtenv/postgresql/data",
+		"PYTHONPATH":          "/nix/store/def-python/lib/python3.12/site-packages",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("got wrong image env (-want +got):\n%s", diff)
+	}
+}
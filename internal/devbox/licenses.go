@@ -0,0 +1,95 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devpkg"
+)
+
+// LicenseReport lists the licenses of a project's packages and whether they
+// comply with the project's license_policy.
+type LicenseReport struct {
+	Packages []PackageLicenses `json:"packages"`
+}
+
+// PackageLicenses describes the licenses of a single package.
+type PackageLicenses struct {
+	Package  string   `json:"package"`
+	Licenses []string `json:"licenses"`
+	Unfree   bool     `json:"unfree"`
+
+	// Violations explains why the package doesn't comply with the license
+	// policy. It's empty if it complies or there's no policy.
+	Violations []string `json:"violations,omitempty"`
+}
+
+// Licenses reports the licenses of the project's Nix packages from their
+// nixpkgs metadata.
+func (d *Devbox) Licenses(ctx context.Context) (*LicenseReport, error) {
+	defer debug.FunctionTimer().End()
+
+	report := &LicenseReport{Packages: []PackageLicenses{}}
+	for _, pkg := range lo.Filter(d.InstallablePackages(), devpkg.IsNix) {
+		licenses, err := d.packageLicenses(ctx, pkg)
+		if err != nil {
+			return nil, err
+		}
+		report.Packages = append(report.Packages, licenses)
+	}
+	return report, nil
+}
+
+// checkLicensePolicy returns an error if any of pkgs don't comply with the
+// project's license_policy. It doesn't evaluate anything if there's no policy.
+func (d *Devbox) checkLicensePolicy(ctx context.Context, pkgs []*devpkg.Package) error {
+	if d.cfg.Root.LicensePolicy == nil {
+		return nil
+	}
+	defer debug.FunctionTimer().End()
+
+	violations := []string{}
+	for _, pkg := range lo.Filter(pkgs, devpkg.IsNix) {
+		licenses, err := d.packageLicenses(ctx, pkg)
+		if err != nil {
+			return err
+		}
+		for _, v := range licenses.Violations {
+			violations = append(violations, fmt.Sprintf("%s: %s", pkg.Raw, v))
+		}
+	}
+	if len(violations) > 0 {
+		return usererr.New(
+			"The following packages don't comply with the license_policy in devbox.json:\n  %s",
+			strings.Join(violations, "\n  "),
+		)
+	}
+	return nil
+}
+
+func (d *Devbox) packageLicenses(ctx context.Context, pkg *devpkg.Package) (PackageLicenses, error) {
+	meta, err := pkg.Meta(ctx)
+	if err != nil {
+		return PackageLicenses{}, usererr.WithUserMessage(
+			err, "Unable to get the license of %s.", pkg.Raw)
+	}
+
+	licenses := PackageLicenses{
+		Package:  pkg.Raw,
+		Licenses: []string{},
+		Unfree:   meta.Unfree,
+	}
+	for _, l := range meta.Licenses {
+		licenses.Licenses = append(licenses.Licenses, l.ID())
+		licenses.Unfree = licenses.Unfree || !l.Free
+	}
+	licenses.Violations = d.cfg.Root.LicensePolicy.Violations(licenses.Licenses, licenses.Unfree)
+	return licenses, nil
+}
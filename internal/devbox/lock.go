@@ -0,0 +1,116 @@
+package devbox
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+	"go.jetpack.io/devbox/internal/devpkg/pkgtype"
+	"go.jetpack.io/devbox/internal/lock"
+)
+
+// PlatformGap is a system in the platforms field of devbox.json that
+// devbox.lock has no outputs of a package for.
+type PlatformGap struct {
+	Package string
+	System  string
+	Problem string
+}
+
+// LockReport describes the result of devbox lock.
+type LockReport struct {
+	// Platforms are the systems that the project requires.
+	Platforms []string
+	// Gaps are only set when the platforms are checked.
+	Gaps []PlatformGap
+	// Unchecked are the packages whose outputs devbox.lock doesn't record
+	// per system, such as flakes, so they can't be checked.
+	Unchecked []string
+}
+
+// Lock resolves the project's packages and writes devbox.lock without
+// installing them. For each system in the platforms field of devbox.json, it
+// records the outputs of the packages that are enabled on it. With
+// opts.CheckPlatforms, the report lists the systems that a package has no
+// outputs for.
+func (d *Devbox) Lock(ctx context.Context, opts devopt.LockOpts) (*LockReport, error) {
+	defer debug.FunctionTimer().End()
+
+	report := &LockReport{Platforms: d.cfg.Platforms()}
+	if opts.CheckPlatforms && len(report.Platforms) == 0 {
+		return nil, usererr.New(
+			"devbox.json doesn't list any platforms to check. Add the systems that " +
+				"your team uses, for example \"platforms\": [\"x86_64-linux\", \"aarch64-darwin\"].")
+	}
+
+	packages := d.cfg.Packages(false /*includeRemovedTriggerPackages*/)
+	for _, pkg := range packages {
+		key := pkg.VersionedName()
+		if _, err := d.lockfile.Resolve(ctx, key); err != nil {
+			return nil, err
+		}
+		systems := slices.DeleteFunc(slices.Clone(report.Platforms), func(sys string) bool {
+			return !pkg.IsEnabledOnSystem(sys)
+		})
+		if err := d.lockfile.ResolveSystems(ctx, key, systems); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.lockfile.Save(); err != nil {
+		return nil, err
+	}
+
+	if opts.CheckPlatforms {
+		report.Gaps, report.Unchecked = platformGaps(packages, d.lockfile, report.Platforms)
+	}
+	return report, nil
+}
+
+// platformGaps returns the systems that the lock entries of packages have no
+// outputs for, skipping the systems that a package isn't enabled on. It also
+// returns the packages that can't be checked.
+func platformGaps(
+	packages []configfile.Package,
+	lockfile *lock.File,
+	platforms []string,
+) (gaps []PlatformGap, unchecked []string) {
+	for _, pkg := range packages {
+		key := pkg.VersionedName()
+		if pkgtype.IsFlake(key) || pkgtype.IsRunX(key) || lock.IsLegacyPackage(key) {
+			unchecked = append(unchecked, key)
+			continue
+		}
+		entry := lockfile.Get(key)
+		for _, sys := range platforms {
+			if !pkg.IsEnabledOnSystem(sys) {
+				continue
+			}
+			if problem := systemProblem(entry, sys, pkg.Outputs); problem != "" {
+				gaps = append(gaps, PlatformGap{Package: key, System: sys, Problem: problem})
+			}
+		}
+	}
+	return gaps, unchecked
+}
+
+// systemProblem returns why entry can't be installed on sys, or an empty
+// string if it can.
+func systemProblem(entry *lock.Package, sys string, outputs []string) string {
+	if entry == nil {
+		return "not in devbox.lock"
+	}
+	info := entry.Systems[sys]
+	if info == nil || len(info.Outputs) == 0 {
+		return "not available"
+	}
+	for _, name := range outputs {
+		if !slices.ContainsFunc(info.Outputs, func(o lock.Output) bool { return o.Name == name }) {
+			return fmt.Sprintf("no %q output", name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package devbox
+
+import (
+	"slices"
+	"testing"
+
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+	"go.jetpack.io/devbox/internal/lock"
+)
+
+func TestPlatformGaps(t *testing.T) {
+	systemInfo := func(outputs ...string) *lock.SystemInfo {
+		info := &lock.SystemInfo{}
+		for _, name := range outputs {
+			info.Outputs = append(info.Outputs, lock.Output{Name: name, Path: "/nix/store/abc-" + name})
+		}
+		return info
+	}
+	lockfile := &lock.File{Packages: map[string]*lock.Package{
+		"go@1.22": {
+			Resolved: "github:NixOS/nixpkgs/abc#go",
+			Systems: map[string]*lock.SystemInfo{
+				"x86_64-linux":   systemInfo("out"),
+				"aarch64-darwin": systemInfo("out"),
+			},
+		},
+		"glibc@2.38": {
+			Resolved: "github:NixOS/nixpkgs/abc#glibc",
+			Systems: map[string]*lock.SystemInfo{
+				"x86_64-linux": systemInfo("out"),
+			},
+		},
+		"curl@8": {
+			Resolved: "github:NixOS/nixpkgs/abc#curl",
+			Systems: map[string]*lock.SystemInfo{
+				"x86_64-linux":   systemInfo("bin", "dev"),
+				"aarch64-darwin": systemInfo("bin"),
+			},
+		},
+		"github:numtide/flake-utils": {Resolved: "github:numtide/flake-utils/abc"},
+	}}
+	packages := []configfile.Package{
+		{Name: "go", Version: "1.22"},
+		// Excluded on macOS, so it's only required on Linux.
+		{Name: "glibc", Version: "2.38", ExcludedPlatforms: []string{"aarch64-darwin"}},
+		{Name: "curl", Version: "8", Outputs: []string{"dev"}},
+		{Name: "ripgrep", Version: "14"},
+		{Name: "github:numtide/flake-utils"},
+	}
+
+	gaps, unchecked := platformGaps(packages, lockfile, []string{"x86_64-linux", "aarch64-darwin"})
+	want := []PlatformGap{
+		{Package: "curl@8", System: "aarch64-darwin", Problem: `no "dev" output`},
+		{Package: "ripgrep@14", System: "x86_64-linux", Problem: "not in devbox.lock"},
+		{Package: "ripgrep@14", System: "aarch64-darwin", Problem: "not in devbox.lock"},
+	}
+	if !slices.Equal(gaps, want) {
+		t.Errorf("got gaps %+v, want %+v", gaps, want)
+	}
+	if !slices.Equal(unchecked, []string{"github:numtide/flake-utils"}) {
+		t.Errorf("got unchecked packages %v, want the flake", unchecked)
+	}
+
+	gaps, _ = platformGaps(packages[:1], lockfile, []string{"aarch64-linux"})
+	want = []PlatformGap{{Package: "go@1.22", System: "aarch64-linux", Problem: "not available"}}
+	if !slices.Equal(gaps, want) {
+		t.Errorf("got gaps %+v, want %+v", gaps, want)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/devpkg"
+	"go.jetpack.io/devbox/internal/plugin"
+)
+
+// Migration is a legacy construct in devbox.json, devbox.lock or a local
+// plugin that devbox migrate rewrites to the current format.
+type Migration struct {
+	// Title names the legacy construct.
+	Title string
+	// Reason explains why it's deprecated and what replaces it.
+	Reason string
+	// Changes describe each edit. Migrate updates them with the results of
+	// resolving packages when it applies the migration.
+	Changes []string
+
+	apply func(ctx context.Context) ([]string, error)
+}
+
+// Migrate finds the legacy constructs in the project and rewrites them to the
+// current format, unless opts.Check is set. It returns the migrations that
+// were found, in the order that they're applied.
+func (d *Devbox) Migrate(ctx context.Context, opts devopt.MigrateOpts) ([]*Migration, error) {
+	migrations := []*Migration{}
+	for _, find := range []func() *Migration{
+		d.allowInsecureMigration,
+		d.storePathMigration,
+		d.legacyPackagesMigration,
+		d.nixpkgsCommitMigration,
+		d.pluginReadmeMigration,
+	} {
+		if m := find(); m != nil {
+			migrations = append(migrations, m)
+		}
+	}
+	if opts.Check || len(migrations) == 0 {
+		return migrations, nil
+	}
+
+	for _, m := range migrations {
+		changes, err := m.apply(ctx)
+		if err != nil {
+			return migrations, errors.WithMessagef(err, "migrate %s", m.Title)
+		}
+		if changes != nil {
+			m.Changes = changes
+		}
+	}
+	if err := d.saveCfg(); err != nil {
+		return migrations, err
+	}
+	return migrations, d.lockfile.Save()
+}
+
+func (d *Devbox) allowInsecureMigration() *Migration {
+	changes := []string{}
+	for _, name := range sortedLockfileKeys(d) {
+		if d.lockfile.Packages[name].AllowInsecure {
+			changes = append(changes, fmt.Sprintf("move allow_insecure of %s to devbox.json", name))
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return &Migration{
+		Title: "allow_insecure in devbox.lock",
+		Reason: "Insecure packages used to be allowed in devbox.lock. They're now " +
+			"allowed with the allow_insecure field of the package in devbox.json.",
+		Changes: changes,
+		apply: func(ctx context.Context) ([]string, error) {
+			return nil, d.moveAllowInsecureFromLockfile(io.Discard, d.lockfile, d.cfg)
+		},
+	}
+}
+
+func (d *Devbox) storePathMigration() *Migration {
+	pkgs := d.lockfile.LegacyStorePathPackages()
+	if len(pkgs) == 0 {
+		return nil
+	}
+	changes := make([]string, len(pkgs))
+	for i, name := range pkgs {
+		changes[i] = fmt.Sprintf("replace the store_path of %s with outputs", name)
+	}
+	return &Migration{
+		Title: "store_path in devbox.lock",
+		Reason: "Older lockfiles only have the store path of each package's default " +
+			"output. The current format lists every output of the package.",
+		Changes: changes,
+		apply: func(ctx context.Context) ([]string, error) {
+			d.lockfile.MigrateLegacyStorePaths()
+			return nil, nil
+		},
+	}
+}
+
+func (d *Devbox) legacyPackagesMigration() *Migration {
+	legacy := []*devpkg.Package{}
+	for _, pkg := range d.TopLevelPackages() {
+		if pkg.IsLegacy() {
+			legacy = append(legacy, pkg)
+		}
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+	changes := make([]string, len(legacy))
+	for i, pkg := range legacy {
+		changes[i] = fmt.Sprintf("resolve %s to an explicit version", pkg.Raw)
+	}
+	return &Migration{
+		Title: "unversioned packages in devbox.json",
+		Reason: "Packages without a version are installed from the nixpkgs commit in " +
+			"devbox.json, so they can't be updated individually. Versioned packages " +
+			"are resolved with the Devbox search index and locked in devbox.lock.",
+		Changes: changes,
+		apply: func(ctx context.Context) ([]string, error) {
+			changes := []string{}
+			for _, pkg := range legacy {
+				versioned, err := d.versionLegacyPackage(ctx, pkg.Raw)
+				if err != nil {
+					return changes, err
+				}
+				changes = append(changes, fmt.Sprintf("%s -> %s", pkg.Raw, versioned))
+			}
+			return changes, nil
+		},
+	}
+}
+
+// versionLegacyPackage resolves the latest version of an unversioned package
+// and pins it in devbox.json and devbox.lock. Other settings of the package,
+// such as its platforms, are kept.
+func (d *Devbox) versionLegacyPackage(ctx context.Context, name string) (string, error) {
+	resolved, err := d.lockfile.FetchResolvedPackage(ctx, name+"@latest")
+	if err != nil {
+		return "", err
+	}
+	if resolved == nil || resolved.Version == "" {
+		return "", usererr.New(
+			"Package %s wasn't found in the Devbox search index. "+
+				"Replace it with a versioned package or a flake reference.", name)
+	}
+
+	path := fmt.Sprintf("packages[%q]", name)
+	current, _, err := d.cfg.Root.GetPath(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.HasPrefix(current, []byte("{")) {
+		path += ".version"
+	}
+	version, err := json.Marshal(resolved.Version)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if err := d.cfg.Root.SetPath(path, version); err != nil {
+		return "", err
+	}
+
+	versioned := name + "@" + resolved.Version
+	delete(d.lockfile.Packages, name)
+	d.lockfile.Packages[versioned] = resolved
+	return versioned, nil
+}
+
+func (d *Devbox) nixpkgsCommitMigration() *Migration {
+	if d.cfg.Root.Nixpkgs == nil {
+		return nil
+	}
+	return &Migration{
+		Title: "nixpkgs in devbox.json",
+		Reason: "The nixpkgs commit was used to install unversioned packages. " +
+			"Versioned packages each lock their own nixpkgs commit in devbox.lock, " +
+			"so the field is no longer needed.",
+		Changes: []string{"remove the nixpkgs field"},
+		apply: func(ctx context.Context) ([]string, error) {
+			_, err := d.cfg.Root.UnsetPath("nixpkgs")
+			return nil, err
+		},
+	}
+}
+
+func (d *Devbox) pluginReadmeMigration() *Migration {
+	plugins := []*plugin.LocalPlugin{}
+	changes := []string{}
+	for _, cfg := range d.cfg.IncludedPluginConfigs() {
+		local, ok := cfg.Source.(*plugin.LocalPlugin)
+		if !ok || cfg.DeprecatedDescription == "" {
+			continue
+		}
+		plugins = append(plugins, local)
+		path := local.Path()
+		if rel, err := filepath.Rel(d.projectDir, path); err == nil {
+			path = rel
+		}
+		changes = append(changes, fmt.Sprintf("rename readme to description in %s", path))
+	}
+	if len(plugins) == 0 {
+		return nil
+	}
+	return &Migration{
+		Title:   "readme in local plugins",
+		Reason:  "The readme field of plugin.json was renamed to description.",
+		Changes: changes,
+		apply: func(ctx context.Context) ([]string, error) {
+			for _, local := range plugins {
+				if _, err := local.MigrateReadme(); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		},
+	}
+}
+
+func sortedLockfileKeys(d *Devbox) []string {
+	keys := lo.Keys(d.lockfile.Packages)
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/envir"
+	"go.jetpack.io/devbox/internal/nix"
+)
+
+// openMigrateProject opens a project with the files and a search index that
+// has version 1.2.3 of every package except "missing".
+func openMigrateProject(t *testing.T, config, lockfile string) *Devbox {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"name": %[1]q, "version": "1.2.3", "systems": {"x86_64-linux": {
+			"flake_installable": {"ref": {"type": "github", "owner": "NixOS", "repo": "nixpkgs", "rev": "abc"}, "attr_path": %[1]q},
+			"last_updated": "2024-01-01T00:00:00Z"}}}`, name)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv(envir.DevboxSearchHost, server.URL)
+	t.Setenv("__DEVBOX_NIX_SYSTEM", "x86_64-linux")
+	t.Cleanup(nix.SetSystem("x86_64-linux"))
+	t.Setenv(packageGroupsEnv, "")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "devbox.json"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if lockfile != "" {
+		if err := os.WriteFile(filepath.Join(dir, "devbox.lock"), []byte(lockfile), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, err := Open(&devopt.Opts{Dir: dir, Stderr: os.Stderr, SkipAutoMigrate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func readProjectFile(t *testing.T, d *Devbox, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(d.projectDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestMigrateOrder(t *testing.T) {
+	config := `{"packages": ["hello", "jq@1.7"], "nixpkgs": {"commit": "b22db301217578a8edfccccf5cedafe5fc54e78b"}}`
+	lockfile := `{"lockfile_version": "1", "packages": {"jq@1.7": {"resolved": "github:NixOS/nixpkgs/abc#jq", "allow_insecure": true}}}`
+	d := openMigrateProject(t, config, lockfile)
+
+	migrations, err := d.Migrate(context.Background(), devopt.MigrateOpts{Check: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, m := range migrations {
+		got = append(got, m.Title)
+	}
+	want := []string{
+		"allow_insecure in devbox.lock",
+		"unversioned packages in devbox.json",
+		"nixpkgs in devbox.json",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got migrations %q, want %q", got, want)
+	}
+	if got := readProjectFile(t, d, "devbox.json"); got != config {
+		t.Errorf("--check changed devbox.json to:\n%s", got)
+	}
+}
+
+func TestMigrateLegacyPackages(t *testing.T) {
+	d := openMigrateProject(t,
+		`{"packages": {"hello": "", "go": {"platforms": ["x86_64-linux"]}}, "nixpkgs": {"commit": "b22db301217578a8edfccccf5cedafe5fc54e78b"}}`, "")
+
+	migrations, err := d.Migrate(context.Background(), devopt.MigrateOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("got %d migrations, want 2", len(migrations))
+	}
+	changes := migrations[0].Changes
+	slices.Sort(changes)
+	if want := []string{"go -> go@1.2.3", "hello -> hello@1.2.3"}; !slices.Equal(changes, want) {
+		t.Errorf("got changes %q, want %q", changes, want)
+	}
+
+	var config map[string]any
+	if err := json.Unmarshal([]byte(readProjectFile(t, d, "devbox.json")), &config); err != nil {
+		t.Fatal(err)
+	}
+	wantConfig := map[string]any{"packages": map[string]any{
+		"hello": "1.2.3",
+		"go":    map[string]any{"version": "1.2.3", "platforms": []any{"x86_64-linux"}},
+	}}
+	if diff := cmp.Diff(wantConfig, config); diff != "" {
+		t.Errorf("got wrong devbox.json (-want +got):\n%s", diff)
+	}
+	lockfile := readProjectFile(t, d, "devbox.lock")
+	for _, want := range []string{`"go@1.2.3"`, `"hello@1.2.3"`} {
+		if !strings.Contains(lockfile, want) {
+			t.Errorf("got devbox.lock without %s:\n%s", want, lockfile)
+		}
+	}
+}
+
+func TestVersionLegacyPackageNotFound(t *testing.T) {
+	d := openMigrateProject(t, `{"packages": ["missing"]}`, "")
+	if _, err := d.versionLegacyPackage(context.Background(), "missing"); err == nil {
+		t.Error("got nil error for a package that isn't in the search index")
+	}
+	if got := readProjectFile(t, d, "devbox.json"); got != `{"packages": ["missing"]}` {
+		t.Errorf("got devbox.json changed to:\n%s", got)
+	}
+}
@@ -35,6 +35,7 @@ import (
 	"go.jetpack.io/devbox/internal/nix"
 	"go.jetpack.io/devbox/internal/plugin"
 	"go.jetpack.io/devbox/internal/ux"
+	"go.jetpack.io/devbox/internal/ux/progress"
 )
 
 // packages.go has functions for adding, removing and getting info about nix
@@ -42,9 +43,10 @@ import (
 
 // Add adds the `pkgs` to the config (i.e. devbox.json) and nix profile for this
 // devbox project
-func (d *Devbox) Add(ctx context.Context, pkgsNames []string, opts devopt.AddOpts) error {
+func (d *Devbox) Add(ctx context.Context, pkgsNames []string, opts devopt.AddOpts) (err error) {
 	ctx, task := trace.NewTask(ctx, "devboxAdd")
 	defer task.End()
+	defer d.trackHistory("add", pkgsNames)(&err)
 
 	// Track which packages had no changes so we can report that to the user.
 	unchangedPackageNames := []string{}
@@ -61,6 +63,24 @@ func (d *Devbox) Add(ctx context.Context, pkgsNames []string, opts devopt.AddOpt
 		d.cfg.Root.TopLevelPackages(), func(p configfile.Package, _ int) string {
 			return p.VersionedName()
 		})
+
+	// Resolve the packages concurrently so that the loop below, which checks
+	// them one at a time, doesn't wait on the search service for each one.
+	// Packages that fail to resolve are handled by the loop, without resolving
+	// them again.
+	toResolve := lo.FilterMap(pkgs, func(pkg *devpkg.Package, _ int) (string, bool) {
+		return pkg.Versioned(), pkg.IsDevboxPackage
+	})
+	resolveErrs := d.lockfile.TryResolveAll(ctx, toResolve)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// newPackageNames are the names that the packages that aren't in the
+	// config yet are added with. They're validated and checked against the
+	// license policy before the config is changed, so that a package that
+	// can't be added doesn't remove the one it would replace.
+	newPackageNames := []string{}
 	for _, pkg := range pkgs {
 		// If exact versioned package is already in the config, we can skip the
 		// next loop that only deals with newPackages.
@@ -72,24 +92,17 @@ func (d *Devbox) Add(ctx context.Context, pkgsNames []string, opts devopt.AddOpt
 			continue
 		}
 
-		// On the other hand, if there's a package with same canonical name, replace
-		// it. Ignore error (which is either missing or more than one). We search by
-		// CanonicalName so any legacy or versioned packages will be removed if they
-		// match.
-		found, _ := d.findPackageByName(pkg.CanonicalName())
-		if found != nil {
-			ux.Finfo(d.stderr, "Replacing package %q in devbox.json\n", found.Raw)
-			if err := d.Remove(ctx, found.Raw); err != nil {
-				return err
-			}
-		}
-
 		// validate that the versioned package exists in the search endpoint.
 		// if not, fallback to legacy vanilla nix.
 		versionedPkg := devpkg.PackageFromStringWithOptions(pkg.Versioned(), d.lockfile, opts)
 
 		packageNameForConfig := pkg.Raw
-		ok, err := versionedPkg.ValidateExists(ctx)
+		ok, err := false, resolveErrs[pkg.Versioned()]
+		if err != nil {
+			debug.Log("failed to resolve %s: %v", pkg.Versioned(), err)
+		} else {
+			ok, err = versionedPkg.ValidateExists(ctx)
+		}
 		if (err == nil && ok) || errors.Is(err, devpkg.ErrCannotBuildPackageOnSystem) {
 			// Only use versioned if it exists in search. We can disregard the error
 			// about not building on the current system, since user's can continue
@@ -104,10 +117,30 @@ func (d *Devbox) Add(ctx context.Context, pkgsNames []string, opts devopt.AddOpt
 			// could not find it in search or in the legacy nixpkgs path.
 			return usererr.New("Package %s not found", pkg.Raw)
 		}
+		newPackageNames = append(newPackageNames, packageNameForConfig)
+	}
+
+	newPackages := devpkg.PackagesFromStringsWithOptions(newPackageNames, d.lockfile, opts)
+	if err := d.checkLicensePolicy(ctx, newPackages); err != nil {
+		return err
+	}
+
+	for _, pkg := range newPackages {
+		// If there's a package with same canonical name, replace it. Ignore
+		// error (which is either missing or more than one). We search by
+		// CanonicalName so any legacy or versioned packages will be removed if
+		// they match.
+		found, _ := d.findPackageByName(pkg.CanonicalName())
+		if found != nil {
+			ux.Finfo(d.stderr, "Replacing package %q in devbox.json\n", found.Raw)
+			if err := d.Remove(ctx, found.Raw); err != nil {
+				return err
+			}
+		}
 
-		ux.Finfo(d.stderr, "Adding package %q to devbox.json\n", packageNameForConfig)
-		d.cfg.PackageMutator().Add(packageNameForConfig)
-		addedPackageNames = append(addedPackageNames, packageNameForConfig)
+		ux.Finfo(d.stderr, "Adding package %q to devbox.json\n", pkg.Raw)
+		d.cfg.PackageMutator().Add(pkg.Raw)
+		addedPackageNames = append(addedPackageNames, pkg.Raw)
 	}
 
 	// Options must be set before ensureStateIsUpToDate. See comment in function
@@ -152,6 +185,10 @@ func (d *Devbox) setPackageOptions(pkgs []string, opts devopt.AddOpts) error {
 			d.stderr, pkg, opts.AllowInsecure); err != nil {
 			return err
 		}
+		if err := d.cfg.PackageMutator().SetGroups(
+			d.stderr, pkg, opts.Groups); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -175,7 +212,13 @@ func (d *Devbox) printPostAddMessage(
 		}
 	}
 
-	if len(opts.Platforms) == 0 && len(opts.ExcludePlatforms) == 0 && len(opts.Outputs) == 0 && len(opts.AllowInsecure) == 0 {
+	if len(opts.Groups) > 0 && !lo.Some(d.groups, opts.Groups) {
+		ux.Finfo(d.stderr, "The packages aren't in the selected groups. Use --with %s to install them.\n",
+			strings.Join(opts.Groups, ","))
+	}
+
+	if len(opts.Platforms) == 0 && len(opts.ExcludePlatforms) == 0 && len(opts.Outputs) == 0 &&
+		len(opts.AllowInsecure) == 0 && len(opts.Groups) == 0 {
 		if len(unchangedPackageNames) == 1 {
 			ux.Finfo(d.stderr, "Package %q was already in devbox.json and was not modified\n", unchangedPackageNames[0])
 		} else if len(unchangedPackageNames) > 1 {
@@ -189,9 +232,10 @@ func (d *Devbox) printPostAddMessage(
 
 // Remove removes the `pkgs` from the config (i.e. devbox.json) and nix profile
 // for this devbox project
-func (d *Devbox) Remove(ctx context.Context, pkgs ...string) error {
+func (d *Devbox) Remove(ctx context.Context, pkgs ...string) (err error) {
 	ctx, task := trace.NewTask(ctx, "devboxRemove")
 	defer task.End()
+	defer d.trackHistory("rm", pkgs)(&err)
 
 	packagesToUninstall := []string{}
 	missingPkgs := []string{}
@@ -335,7 +379,10 @@ func (d *Devbox) updateLockfile(recomputeState bool) error {
 // - devbox.lock file
 // - the generated flake
 // - the nix-profile
-func (d *Devbox) recomputeState(ctx context.Context) error {
+func (d *Devbox) recomputeState(ctx context.Context) (err error) {
+	task := progress.Start(progress.Environment, "Updating the environment")
+	defer func() { task.End(err) }()
+
 	if err := shellgen.GenerateForPrintEnv(ctx, d); err != nil {
 		return err
 	}
@@ -395,11 +442,17 @@ func resetProfileDirForFlakes(profileDir string) (err error) {
 
 func (d *Devbox) installPackages(ctx context.Context, mode installMode) error {
 	defer debug.FunctionTimer().End()
-	// Create plugin directories first because packages might need them
-	for _, pluginConfig := range d.Config().IncludedPluginConfigs() {
-		if err := d.PluginManager().CreateFilesForConfig(pluginConfig); err != nil {
-			return err
-		}
+	if err := d.createPluginFiles(); err != nil {
+		return err
+	}
+
+	// Resolve the packages that aren't in the lockfile yet concurrently,
+	// instead of one at a time when they're installed.
+	raws := lo.FilterMap(d.InstallablePackages(), func(pkg *devpkg.Package, _ int) (string, bool) {
+		return pkg.Raw, pkg.IsDevboxPackage || pkg.IsRunX()
+	})
+	if err := d.lockfile.ResolveAll(ctx, raws); err != nil {
+		return err
 	}
 
 	if err := d.installNixPackagesToStore(ctx, mode); err != nil {
@@ -409,9 +462,30 @@ func (d *Devbox) installPackages(ctx context.Context, mode installMode) error {
 	return d.InstallRunXPackages(ctx)
 }
 
+// createPluginFiles creates the files of the project's plugins. They're
+// created before the packages are installed because packages might need them.
+func (d *Devbox) createPluginFiles() (err error) {
+	defer debug.FunctionTimer().End()
+	configs := d.Config().IncludedPluginConfigs()
+	if len(configs) == 0 {
+		return nil
+	}
+	task := progress.Start(progress.Plugin, "Creating plugin files")
+	defer func() { task.End(err) }()
+	for _, pluginConfig := range configs {
+		child := task.Child(progress.Plugin, pluginConfig.Name)
+		err := d.PluginManager().CreateFilesForConfig(pluginConfig)
+		child.End(err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (d *Devbox) InstallRunXPackages(ctx context.Context) error {
 	for _, pkg := range lo.Filter(d.InstallablePackages(), devpkg.IsRunX) {
-		lockedPkg, err := d.lockfile.Resolve(pkg.Raw)
+		lockedPkg, err := d.lockfile.Resolve(ctx, pkg.Raw)
 		if err != nil {
 			return err
 		}
@@ -430,7 +504,7 @@ func (d *Devbox) InstallRunXPackages(ctx context.Context) error {
 // This is done by running `nix build` on the flake. We do this so that the
 // packages will be available in the nix store when computing the devbox environment
 // and installing in the nix profile (even if offline).
-func (d *Devbox) installNixPackagesToStore(ctx context.Context, mode installMode) error {
+func (d *Devbox) installNixPackagesToStore(ctx context.Context, mode installMode) (err error) {
 	defer debug.FunctionTimer().End()
 	packages, err := d.packagesToInstallInStore(ctx, mode)
 	if err != nil || len(packages) == 0 {
@@ -444,8 +518,9 @@ func (d *Devbox) installNixPackagesToStore(ctx context.Context, mode installMode
 	}
 
 	args := &nix.BuildArgs{
-		Flags:  flags,
-		Writer: d.stderr,
+		BinaryCaches: d.cfg.BinaryCaches(),
+		Flags:        flags,
+		Writer:       d.stderr,
 	}
 	caches, err := d.providers.NixCache.ReadCaches(ctx)
 	if err != nil {
@@ -494,6 +569,9 @@ func (d *Devbox) installNixPackagesToStore(ctx context.Context, mode installMode
 		"Installing the following packages to the nix store: %s\n",
 		strings.Join(packageNames, ", "),
 	)
+	task := progress.Start(progress.Install, "Installing %d packages to the nix store", len(packages))
+	defer func() { task.End(err) }()
+	args.Progress = task
 
 	installables := map[bool][]string{false: {}, true: {}}
 	for _, pkg := range packages {
@@ -0,0 +1,175 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/build"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/fileutil"
+	"go.jetpack.io/devbox/internal/lock"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/sbom"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+// SBOM writes a software bill of materials for the packages in the project's
+// lockfile. By default it only uses the lockfile, so it doesn't need Nix. With
+// [devopt.SBOMOpts.Closure] it installs the packages and also lists their
+// runtime closures, store hashes and license metadata.
+func (d *Devbox) SBOM(ctx context.Context, opts devopt.SBOMOpts) error {
+	defer debug.FunctionTimer().End()
+
+	if opts.Format != sbom.FormatSPDX && opts.Format != sbom.FormatCycloneDX {
+		return usererr.New("Unknown SBOM format %q. Supported formats are %q and %q.",
+			opts.Format, sbom.FormatSPDX, sbom.FormatCycloneDX)
+	}
+
+	doc := &sbom.Document{
+		Name:        cmp.Or(d.cfg.Root.Name, filepath.Base(d.projectDir)),
+		Created:     sbomCreationTime(d.lockfile),
+		ToolVersion: build.Version,
+	}
+	keys := lo.Keys(d.lockfile.Packages)
+	slices.Sort(keys)
+	for _, key := range keys {
+		doc.Packages = append(doc.Packages, lockfileSBOMPackage(key, d.lockfile.Packages[key]))
+	}
+
+	if opts.Closure {
+		if err := d.ensureStateIsUpToDate(ctx, ensure); err != nil {
+			return err
+		}
+		if err := d.addSBOMClosure(ctx, doc); err != nil {
+			return err
+		}
+	}
+	return sbom.Write(opts.Writer, opts.Format, doc)
+}
+
+// addSBOMClosure adds the runtime closure and nixpkgs metadata of the
+// document's packages for the current system.
+func (d *Devbox) addSBOMClosure(ctx context.Context, doc *sbom.Document) error {
+	system := nix.System()
+	paths := []string{}
+	for i := range doc.Packages {
+		pkg := &doc.Packages[i]
+		for _, out := range pkg.Outputs {
+			// Only installed outputs are in the closure.
+			if out.System == system && fileutil.Exists(out.Path) {
+				pkg.DependsOn = append(pkg.DependsOn, out.Path)
+				paths = append(paths, out.Path)
+			}
+		}
+
+		// Packages that aren't from a flake (such as plugins) don't have
+		// any metadata.
+		if !strings.Contains(pkg.FlakeRef, "#") {
+			continue
+		}
+		meta, err := nix.EvalPackageMeta(ctx, pkg.FlakeRef)
+		if err != nil {
+			ux.Fwarning(d.stderr, "unable to get the metadata for %s: %v\n", pkg.ID, err)
+			continue
+		}
+		pkg.Description = meta.Description
+		if len(meta.Homepage) > 0 {
+			pkg.Homepage = meta.Homepage[0]
+		}
+		for _, l := range meta.Licenses {
+			pkg.Licenses = append(pkg.Licenses, sbom.License{SPDXID: l.SPDXID, Name: l.ID()})
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	infos, err := nix.PathInfos(ctx, paths...)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		narHash, err := info.NarHashHex()
+		if err != nil {
+			return err
+		}
+		name, version := splitStoreName(filepath.Base(info.Path))
+		doc.Closure = append(doc.Closure, sbom.Package{
+			ID:        info.Path,
+			Name:      name,
+			Version:   version,
+			StorePath: info.Path,
+			NarHash:   narHash,
+			DependsOn: info.References,
+		})
+	}
+	return nil
+}
+
+func lockfileSBOMPackage(key string, pkg *lock.Package) sbom.Package {
+	out := sbom.Package{
+		ID:       key,
+		Name:     lockfilePackageName(key),
+		Version:  pkg.Version,
+		FlakeRef: pkg.Resolved,
+	}
+	for system, info := range pkg.Systems {
+		for _, o := range info.Outputs {
+			out.Outputs = append(out.Outputs, sbom.Output{System: system, Name: o.Name, Path: o.Path})
+		}
+	}
+	return out
+}
+
+// lockfilePackageName returns the name of a package from its lockfile key,
+// which is usually of the form "name@version".
+func lockfilePackageName(key string) string {
+	if i := strings.LastIndex(key, "@"); i > 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// sbomCreationTime returns the time to record as the SBOM's creation time.
+// It honors SOURCE_DATE_EPOCH and otherwise uses the newest last_modified time
+// in the lockfile so that the output stays reproducible.
+//
+// See https://reproducible-builds.org/docs/source-date-epoch/
+func sbomCreationTime(lockfile *lock.File) time.Time {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		return time.Unix(epoch, 0)
+	}
+	created := time.Unix(0, 0)
+	for _, pkg := range lockfile.Packages {
+		if t, err := time.Parse(time.RFC3339, pkg.LastModified); err == nil && t.After(created) {
+			created = t
+		}
+	}
+	return created
+}
+
+// splitStoreName splits the name of a store path, such as
+// "xgzn0x2l3mz1v1gvbd7j5y5dvqbb6k0y-bash-5.2p26", into its package name and
+// version. Like Nix, it treats everything after the first dash that's
+// followed by a digit as the version.
+func splitStoreName(storeName string) (name, version string) {
+	_, name, _ = strings.Cut(storeName, "-")
+	for i := 0; i < len(name)-1; i++ {
+		if name[i] == '-' && name[i+1] >= '0' && name[i+1] <= '9' {
+			return name[:i], name[i+1:]
+		}
+	}
+	return name, ""
+}
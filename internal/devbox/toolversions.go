@@ -0,0 +1,200 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devbox
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.jetpack.io/devbox/internal/autodetect"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devbox/devopt"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+	"go.jetpack.io/devbox/internal/searcher"
+	"go.jetpack.io/devbox/internal/ux"
+)
+
+const toolVersionsFile = ".tool-versions"
+
+func (d *Devbox) toolVersionsPath(opts devopt.ToolVersionsOpts) string {
+	if opts.Path != "" {
+		return opts.Path
+	}
+	return filepath.Join(d.projectDir, toolVersionsFile)
+}
+
+// ImportToolVersions adds the tools in an asdf or mise .tool-versions file to
+// devbox.json. Each tool is mapped to its nixpkgs packages, and its version is
+// resolved with the search service. If the exact version isn't available, it
+// uses the latest version that matches the tool's version prefix.
+//
+// Packages that are already in devbox.json with a different version are
+// replaced. The packages aren't installed until the next devbox command that
+// needs them.
+func (d *Devbox) ImportToolVersions(ctx context.Context, opts devopt.ToolVersionsOpts) error {
+	mapping, err := autodetect.LoadToolMapping(opts.Mapping)
+	if err != nil {
+		return err
+	}
+	path := d.toolVersionsPath(opts)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return usererr.WithUserMessage(err, "Unable to read %s.", path)
+	}
+	tools, err := autodetect.ParseToolVersions(b)
+	if err != nil {
+		return usererr.New("Unable to parse %s: %v", path, err)
+	}
+
+	changed := false
+	for _, tool := range tools {
+		pkgs, ok := mapping.Lookup(tool.Tool)
+		if !ok {
+			ux.Fwarning(d.stderr, "Skipping %s: no nixpkgs package is known for it. "+
+				"Add it to a mapping file and pass --mapping.\n", tool.Tool)
+			continue
+		}
+		candidates := pkgs.VersionCandidates(tool.Versions[0])
+		if len(candidates) == 0 {
+			ux.Fwarning(d.stderr, "Skipping %s %s: not a version number.\n", tool.Tool, tool.Versions[0])
+			continue
+		}
+
+		for _, name := range pkgs.Packages {
+			version, err := resolveToolVersion(ctx, name, candidates)
+			if errors.Is(err, searcher.ErrNotFound) {
+				ux.Fwarning(d.stderr, "Skipping %s: no version of %s matches %s.\n",
+					tool.Tool, name, tool.Versions[0])
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if d.importPackage(name, version) {
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return d.saveCfg()
+}
+
+// resolveToolVersion returns the first version candidate of a package that
+// exists in the search service. Versions other than "latest" are pinned to
+// the version they resolve to.
+func resolveToolVersion(ctx context.Context, name string, candidates []string) (string, error) {
+	for _, candidate := range candidates {
+		resolved, err := searcher.Client().Resolve(ctx, name, candidate)
+		if errors.Is(err, searcher.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if candidate == "latest" {
+			return candidate, nil
+		}
+		return resolved.Version, nil
+	}
+	return "", searcher.ErrNotFound
+}
+
+// importPackage adds name@version to devbox.json, replacing any other version
+// of the package. It returns false if the package was already in devbox.json.
+func (d *Devbox) importPackage(name, version string) bool {
+	versioned := name + "@" + version
+	for _, existing := range d.cfg.Root.TopLevelPackages() {
+		if existing.Name != name {
+			continue
+		}
+		if existing.Version == version {
+			ux.Finfo(d.stderr, "Package %q already in devbox.json\n", versioned)
+			return false
+		}
+		ux.Finfo(d.stderr, "Replacing package %q in devbox.json\n", existing.VersionedName())
+		d.cfg.PackageMutator().Remove(existing.VersionedName())
+		break
+	}
+	ux.Finfo(d.stderr, "Adding package %q to devbox.json\n", versioned)
+	d.cfg.PackageMutator().Add(versioned)
+	return true
+}
+
+// ExportToolVersions returns the contents of a .tool-versions file with the
+// devbox.json packages that have an asdf tool. Versions come from devbox.lock
+// because asdf needs exact versions. If the .tool-versions file already
+// exists, tools that aren't in devbox.json are kept and the versions of the
+// rest are updated.
+func (d *Devbox) ExportToolVersions(opts devopt.ToolVersionsOpts) ([]byte, error) {
+	mapping, err := autodetect.LoadToolMapping(opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := map[string]string{}
+	order := []string{}
+	for _, pkg := range d.cfg.Root.TopLevelPackages() {
+		tool, ok := mapping.ToolFor(pkg.Name)
+		if !ok {
+			continue
+		}
+		version := d.exactVersion(pkg)
+		if version == "" {
+			ux.Fwarning(d.stderr, "Skipping %s: its exact version isn't in devbox.lock. "+
+				"Run devbox install and try again.\n", pkg.VersionedName())
+			continue
+		}
+		if _, ok := exported[tool]; !ok {
+			order = append(order, tool)
+		}
+		exported[tool] = version
+	}
+
+	path := d.toolVersionsPath(opts)
+	tools := []autodetect.ToolVersion{}
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, usererr.WithUserMessage(err, "Unable to read %s.", path)
+	}
+	if err == nil {
+		tools, err = autodetect.ParseToolVersions(b)
+		if err != nil {
+			return nil, usererr.New("Unable to parse %s: %v", path, err)
+		}
+	}
+
+	// Update the tools that are already in the file, keeping the name
+	// that the file uses in case it's a mise alias.
+	for i, tool := range tools {
+		pkgs, ok := mapping.Lookup(tool.Tool)
+		if !ok {
+			continue
+		}
+		canonical, _ := mapping.ToolFor(pkgs.Packages[0])
+		if version, ok := exported[canonical]; ok {
+			tools[i].Versions = []string{version}
+			delete(exported, canonical)
+		}
+	}
+	for _, tool := range order {
+		if version, ok := exported[tool]; ok {
+			tools = append(tools, autodetect.ToolVersion{Tool: tool, Versions: []string{version}})
+		}
+	}
+	return autodetect.FormatToolVersions(tools), nil
+}
+
+// exactVersion returns the exact version of a package from devbox.lock. It
+// returns an empty string if the package isn't locked.
+func (d *Devbox) exactVersion(pkg configfile.Package) string {
+	if locked := d.lockfile.Get(pkg.VersionedName()); locked != nil && locked.Version != "" {
+		return locked.Version
+	}
+	return ""
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"go.jetpack.io/devbox/internal/boxcli/featureflag"
 	"go.jetpack.io/devbox/internal/devbox/devopt"
 	"go.jetpack.io/devbox/internal/devpkg"
@@ -20,7 +21,14 @@ import (
 	"go.jetpack.io/devbox/internal/ux"
 )
 
-func (d *Devbox) Update(ctx context.Context, opts devopt.UpdateOpts) error {
+func (d *Devbox) Update(ctx context.Context, opts devopt.UpdateOpts) (err error) {
+	defer d.trackHistory("update", opts.Pkgs)(&err)
+	if len(opts.Pkgs) == 0 {
+		if err := d.updateRemoteIncludes(); err != nil {
+			return err
+		}
+	}
+
 	inputs, err := d.inputsToUpdate(opts)
 	if err != nil {
 		return err
@@ -54,15 +62,20 @@ func (d *Devbox) Update(ctx context.Context, opts devopt.UpdateOpts) error {
 		}
 	}
 
+	versioned, flakes := []*devpkg.Package{}, []*devpkg.Package{}
 	for _, pkg := range pendingPackagesToUpdate {
-		if _, _, isVersioned := searcher.ParseVersionedPackage(pkg.Raw); !isVersioned {
-			if err = d.attemptToUpgradeFlake(pkg); err != nil {
-				return err
-			}
+		if _, _, isVersioned := searcher.ParseVersionedPackage(pkg.Raw); isVersioned {
+			versioned = append(versioned, pkg)
 		} else {
-			if err = d.updateDevboxPackage(pkg); err != nil {
-				return err
-			}
+			flakes = append(flakes, pkg)
+		}
+	}
+	if err = d.updateDevboxPackages(ctx, versioned); err != nil {
+		return err
+	}
+	for _, pkg := range flakes {
+		if err = d.attemptToUpgradeFlake(pkg); err != nil {
+			return err
 		}
 	}
 
@@ -78,6 +91,25 @@ func (d *Devbox) Update(ctx context.Context, opts devopt.UpdateOpts) error {
 	return plugin.Update()
 }
 
+// updateRemoteIncludes fetches the latest contents of the remote includes and
+// locks them.
+func (d *Devbox) updateRemoteIncludes() error {
+	old := d.lockfile.Includes
+	if len(old) == 0 {
+		return nil
+	}
+	d.lockfile.Includes = nil
+	if err := d.cfg.LoadRecursive(d.lockfile); err != nil {
+		return err
+	}
+	for ref, include := range d.lockfile.Includes {
+		if prev := old[ref]; prev == nil || prev.Hash != include.Hash {
+			ux.Finfo(d.stderr, "Updated include %s\n", ref)
+		}
+	}
+	return nil
+}
+
 func (d *Devbox) inputsToUpdate(
 	opts devopt.UpdateOpts,
 ) ([]*devpkg.Package, error) {
@@ -98,16 +130,24 @@ func (d *Devbox) inputsToUpdate(
 	return pkgsToUpdate, nil
 }
 
-func (d *Devbox) updateDevboxPackage(pkg *devpkg.Package) error {
-	resolved, err := d.lockfile.FetchResolvedPackage(pkg.Raw)
+// updateDevboxPackages resolves the latest versions of pkgs concurrently, and
+// then merges them into the lockfile in order.
+func (d *Devbox) updateDevboxPackages(ctx context.Context, pkgs []*devpkg.Package) error {
+	resolved, err := d.lockfile.FetchResolvedPackages(ctx, lo.Map(pkgs, func(pkg *devpkg.Package, _ int) string {
+		return pkg.Raw
+	}))
 	if err != nil {
 		return err
 	}
-	if resolved == nil {
-		return nil
+	for i, pkg := range pkgs {
+		if resolved[i] == nil {
+			continue
+		}
+		if err := d.mergeResolvedPackageToLockfile(pkg, resolved[i], d.lockfile); err != nil {
+			return err
+		}
 	}
-
-	return d.mergeResolvedPackageToLockfile(pkg, resolved, d.lockfile)
+	return nil
 }
 
 func (d *Devbox) mergeResolvedPackageToLockfile(
@@ -8,15 +8,20 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
 	"go.jetpack.io/devbox/internal/build"
 	"go.jetpack.io/devbox/internal/cachehash"
+	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/devbox/shellcmd"
 	"go.jetpack.io/devbox/internal/devconfig/configfile"
 	"go.jetpack.io/devbox/internal/lock"
+	"go.jetpack.io/devbox/internal/nix"
 	"go.jetpack.io/devbox/internal/plugin"
+	"go.jetpack.io/devbox/nix/flake"
 )
 
 // Config represents a base devbox.json as well as any included plugins it may have.
@@ -25,6 +30,18 @@ type Config struct {
 
 	pluginData *plugin.PluginOnlyData // pointer by design, to allow for nil
 
+	// remoteInclude is the include reference that the config was loaded
+	// from if it's a remote devbox.json fragment.
+	remoteInclude string
+
+	// pluginGroups are the groups of the package that a built-in plugin is
+	// for. It's nil if the config isn't a built-in plugin.
+	pluginGroups []string
+
+	// selectedGroups are the package groups whose plugins are included. If
+	// it's nil, all plugins are included.
+	selectedGroups []string
+
 	included []*Config
 }
 
@@ -109,79 +126,140 @@ func loadBytes(b []byte) (*Config, error) {
 	}, nil
 }
 
+// LoadRecursive loads the config's includes and the plugins for its packages.
+// Remote includes that aren't in the lockfile are fetched and added to it,
+// and locked includes that are no longer used are removed from it.
 func (c *Config) LoadRecursive(lockfile *lock.File) error {
-	return c.loadRecursive(lockfile, map[string]bool{}, "" /*cyclePath*/)
+	defer debug.FunctionTimer().End()
+	remoteIncludes := map[string]bool{}
+	if err := c.loadRecursive(
+		lockfile, map[string]bool{}, remoteIncludes, "" /*cyclePath*/); err != nil {
+		return err
+	}
+	lockfile.TidyIncludes(lo.Keys(remoteIncludes))
+	c.SelectGroups(c.selectedGroups)
+	return nil
+}
+
+// SelectGroups leaves out the built-in plugins for packages that aren't in
+// any of the groups, so that their env, files, services and packages aren't
+// used. A nil groups selects all of them.
+func (c *Config) SelectGroups(groups []string) {
+	c.selectedGroups = groups
+	for _, i := range c.included {
+		i.SelectGroups(groups)
+	}
+}
+
+// selectedIncludes returns the included configs without the built-in plugins
+// for packages in groups that aren't selected.
+func (c *Config) selectedIncludes() []*Config {
+	if c.selectedGroups == nil {
+		return c.included
+	}
+	return lo.Filter(c.included, func(i *Config, _ int) bool {
+		return i.pluginGroups == nil || lo.Some(i.pluginGroups, c.selectedGroups)
+	})
 }
 
 // loadRecursive loads all the included plugins and their included plugins, etc.
 // seen should be a cloned map because loading plugins twice is allowed if they
-// are in different paths.
+// are in different paths. remoteIncludes collects the remote includes that
+// were loaded.
 func (c *Config) loadRecursive(
 	lockfile *lock.File,
 	seen map[string]bool,
+	remoteIncludes map[string]bool,
 	cyclePath string,
 ) error {
 	included := make([]*Config, 0, len(c.Root.Include))
 
 	for _, includeRef := range c.Root.Include {
-		pluginConfig, err := plugin.LoadConfigFromInclude(
-			includeRef, lockfile, filepath.Dir(c.Root.AbsRootPath))
+		includable, hash, err := c.loadInclude(includeRef, lockfile)
 		if err != nil {
 			return errors.WithStack(err)
 		}
+		if includable.remoteInclude != "" {
+			remoteIncludes[includeRef] = true
+		}
 
 		newCyclePath := fmt.Sprintf("%s -> %s", cyclePath, includeRef)
-		if seen[pluginConfig.Source.Hash()] {
+		if seen[hash] {
 			// Note that duplicate includes are allowed if they are in different paths
 			// e.g. 2 different plugins can include the same plugin.
 			// We do not allow a single plugin to include duplicates.
 			return errors.Errorf(
 				"circular or duplicate include detected:\n%s", newCyclePath)
 		}
-		seen[pluginConfig.Source.Hash()] = true
-
-		includable := createIncludableFromPluginConfig(pluginConfig)
+		seen[hash] = true
 
 		if err := includable.loadRecursive(
-			lockfile, maps.Clone(seen), newCyclePath); err != nil {
+			lockfile, maps.Clone(seen), remoteIncludes, newCyclePath); err != nil {
 			return errors.WithStack(err)
 		}
 
 		included = append(included, includable)
 	}
 
-	builtIns, err := plugin.GetBuiltinsForPackages(
-		c.Root.TopLevelPackages(),
-		lockfile,
-	)
-	if err != nil {
-		return errors.WithStack(err)
-	}
-
-	for _, builtIn := range builtIns {
-		includable := &Config{
-			Root:       builtIn.ConfigFile,
-			pluginData: &builtIn.PluginOnlyData,
-		}
-		newCyclePath := fmt.Sprintf("%s -> %s", cyclePath, builtIn.Source.LockfileKey())
-		if err := includable.loadRecursive(
-			lockfile, maps.Clone(seen), newCyclePath); err != nil {
+	for _, pkg := range c.Root.TopLevelPackages() {
+		builtIns, err := plugin.GetBuiltinsForPackages([]configfile.Package{pkg}, lockfile)
+		if err != nil {
 			return errors.WithStack(err)
 		}
-		included = append(included, includable)
+
+		for _, builtIn := range builtIns {
+			includable := &Config{
+				Root:         builtIn.ConfigFile,
+				pluginData:   &builtIn.PluginOnlyData,
+				pluginGroups: pkg.GroupsOrDefault(),
+			}
+			newCyclePath := fmt.Sprintf("%s -> %s", cyclePath, builtIn.Source.LockfileKey())
+			if err := includable.loadRecursive(
+				lockfile, maps.Clone(seen), remoteIncludes, newCyclePath); err != nil {
+				return errors.WithStack(err)
+			}
+			included = append(included, includable)
+		}
 	}
 
 	c.included = included
 	return nil
 }
 
+// loadInclude loads a remote devbox.json fragment or a plugin. It also
+// returns a hash that identifies the include for detecting cycles.
+func (c *Config) loadInclude(includeRef string, lockfile *lock.File) (*Config, string, error) {
+	if IsRemoteInclude(includeRef) {
+		includable, err := loadRemoteInclude(includeRef, lockfile)
+		if err != nil {
+			return nil, "", err
+		}
+		return includable, cachehash.Bytes([]byte(includeRef)), nil
+	}
+
+	// Local paths in a remote fragment would be relative to the current
+	// directory, which is unlikely to be what the fragment's author meant.
+	if c.remoteInclude != "" {
+		if ref, err := flake.ParseRef(includeRef); err == nil && ref.Type == flake.TypePath {
+			return nil, "", usererr.New(
+				"Include %s can't include the local path %s.", c.remoteInclude, includeRef)
+		}
+	}
+	pluginConfig, err := plugin.LoadConfigFromInclude(
+		includeRef, lockfile, filepath.Dir(c.Root.AbsRootPath))
+	if err != nil {
+		return nil, "", err
+	}
+	return createIncludableFromPluginConfig(pluginConfig), pluginConfig.Source.Hash(), nil
+}
+
 func (c *Config) PackageMutator() *configfile.PackagesMutator {
 	return &c.Root.PackagesMutator
 }
 
 func (c *Config) IncludedPluginConfigs() []*plugin.Config {
 	configs := []*plugin.Config{}
-	for _, i := range c.included {
+	for _, i := range c.selectedIncludes() {
 		configs = append(configs, i.IncludedPluginConfigs()...)
 	}
 	if c.pluginData != nil {
@@ -204,9 +282,9 @@ func (c *Config) Packages(
 	packages := []configfile.Package{}
 	packagesToRemove := map[string]bool{}
 
-	for _, i := range c.included {
+	for _, i := range c.selectedIncludes() {
 		packages = append(packages, i.Packages(includeRemovedTriggerPackages)...)
-		if i.pluginData.RemoveTriggerPackage && !includeRemovedTriggerPackages {
+		if i.pluginData != nil && i.pluginData.RemoveTriggerPackage && !includeRemovedTriggerPackages {
 			packagesToRemove[i.pluginData.Source.LockfileKey()] = true
 		}
 	}
@@ -232,7 +310,7 @@ func (c *Config) NixPkgsCommitHash() string {
 
 func (c *Config) Env() map[string]string {
 	env := map[string]string{}
-	for _, i := range c.included {
+	for _, i := range c.selectedIncludes() {
 		maps.Copy(env, i.Env())
 	}
 	maps.Copy(env, c.Root.Env)
@@ -241,7 +319,7 @@ func (c *Config) Env() map[string]string {
 
 func (c *Config) InitHook() *shellcmd.Commands {
 	commands := shellcmd.Commands{}
-	for _, i := range c.included {
+	for _, i := range c.selectedIncludes() {
 		commands.Cmds = append(commands.Cmds, i.InitHook().Cmds...)
 	}
 	commands.Cmds = append(commands.Cmds, c.Root.InitHook().Cmds...)
@@ -250,16 +328,61 @@ func (c *Config) InitHook() *shellcmd.Commands {
 
 func (c *Config) Scripts() configfile.Scripts {
 	scripts := configfile.Scripts{}
-	for _, i := range c.included {
+	for _, i := range c.selectedIncludes() {
 		maps.Copy(scripts, i.Scripts())
 	}
 	maps.Copy(scripts, c.Root.Scripts())
 	return scripts
 }
 
+// Derivations returns the derivations of the project and its includes. The
+// project's derivations take precedence over included ones with the same name.
+func (c *Config) Derivations() map[string]configfile.Derivation {
+	derivations := map[string]configfile.Derivation{}
+	for _, i := range c.selectedIncludes() {
+		maps.Copy(derivations, i.Derivations())
+	}
+	maps.Copy(derivations, c.Root.Derivations)
+	return derivations
+}
+
+// BinaryCaches returns the binary caches of the config and its includes. If
+// more than one config has a cache with the same URL, the root config's
+// settings take precedence.
+func (c *Config) BinaryCaches() []nix.BinaryCache {
+	caches := []nix.BinaryCache{}
+	for _, cache := range c.binaryCaches() {
+		i := slices.IndexFunc(caches, func(c nix.BinaryCache) bool { return c.URL == cache.URL })
+		if i == -1 {
+			caches = append(caches, cache)
+		} else {
+			caches[i] = cache
+		}
+	}
+	return caches
+}
+
+func (c *Config) binaryCaches() []nix.BinaryCache {
+	caches := []nix.BinaryCache{}
+	for _, i := range c.selectedIncludes() {
+		caches = append(caches, i.binaryCaches()...)
+	}
+	return append(caches, c.Root.BinaryCaches...)
+}
+
+// Platforms returns the systems that the config and its includes require,
+// without duplicates.
+func (c *Config) Platforms() []string {
+	platforms := []string{}
+	for _, i := range c.selectedIncludes() {
+		platforms = append(platforms, i.Platforms()...)
+	}
+	return lo.Uniq(append(platforms, c.Root.Platforms...))
+}
+
 func (c *Config) Hash() (string, error) {
 	data := []byte{}
-	for _, i := range c.included {
+	for _, i := range c.selectedIncludes() {
 		hash, err := i.Hash()
 		if err != nil {
 			return "", err
@@ -275,7 +398,7 @@ func (c *Config) Hash() (string, error) {
 }
 
 func (c *Config) IsEnvsecEnabled() bool {
-	for _, i := range c.included {
+	for _, i := range c.selectedIncludes() {
 		if i.IsEnvsecEnabled() {
 			return true
 		}
@@ -28,7 +28,7 @@ func TestDefault(t *testing.T) {
 	if diff := cmp.Diff(
 		cfg,
 		out,
-		cmpopts.IgnoreUnexported(configfile.ConfigFile{}, configfile.PackagesMutator{}, Config{}),
+		cmpopts.IgnoreUnexported(configfile.ConfigFile{}, configfile.PackagesMutator{}, configfile.ScriptConfig{}, Config{}),
 		cmpopts.IgnoreFields(configfile.ConfigFile{}, "AbsRootPath"),
 	); diff != "" {
 		t.Errorf("configs not equal (-in +out):\n%s", diff)
@@ -0,0 +1,32 @@
+package configfile
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func validateBinaryCaches(cfg *ConfigFile) error {
+	for _, cache := range cfg.BinaryCaches {
+		u, err := url.Parse(cache.URL)
+		if err != nil || u.Scheme == "" {
+			return errors.Errorf("invalid url in binary_caches of devbox.json: %q", cache.URL)
+		}
+		if cache.PublicKey != "" {
+			name, key, ok := strings.Cut(cache.PublicKey, ":")
+			if !ok || name == "" || key == "" {
+				return errors.Errorf(
+					"invalid public_key for %s in binary_caches of devbox.json: "+
+						"expected the format <name>:<key>, such as cache.example.com-1:abc=",
+					cache.URL)
+			}
+		}
+		if cache.Priority < 0 {
+			return errors.Errorf(
+				"invalid priority for %s in binary_caches of devbox.json: must not be negative",
+				cache.URL)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package configfile
+
+import "testing"
+
+func TestLoadBinaryCaches(t *testing.T) {
+	cfg, err := LoadBytes([]byte(`{
+		"binary_caches": [
+			{"url": "https://cache.example.com", "public_key": "cache.example.com-1:abc=", "priority": 10}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.BinaryCaches) != 1 || cfg.BinaryCaches[0].Priority != 10 {
+		t.Errorf("got binary caches %+v, want one cache with priority 10", cfg.BinaryCaches)
+	}
+}
+
+func TestLoadInvalidBinaryCaches(t *testing.T) {
+	tests := map[string]string{
+		"missing url":       `{"binary_caches": [{"public_key": "cache.example.com-1:abc="}]}`,
+		"no scheme":         `{"binary_caches": [{"url": "cache.example.com"}]}`,
+		"bad public key":    `{"binary_caches": [{"url": "https://cache.example.com", "public_key": "abc="}]}`,
+		"negative priority": `{"binary_caches": [{"url": "https://cache.example.com", "priority": -1}]}`,
+	}
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadBytes([]byte(config)); err == nil {
+				t.Errorf("got nil error for %s", config)
+			}
+		})
+	}
+}
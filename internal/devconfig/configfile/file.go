@@ -17,6 +17,7 @@ import (
 	"go.jetpack.io/devbox/internal/boxcli/usererr"
 	"go.jetpack.io/devbox/internal/cachehash"
 	"go.jetpack.io/devbox/internal/devbox/shellcmd"
+	"go.jetpack.io/devbox/internal/nix"
 )
 
 const (
@@ -48,20 +49,37 @@ type ConfigFile struct {
 	// Deprecated: Versioned packages don't need this
 	Nixpkgs *NixpkgsConfig `json:"nixpkgs,omitempty"`
 
-	// Reserved to allow including other config files. Proposed format is:
-	// path: for local files
-	// https:// for remote files
+	// Include merges other config files into this one. The formats are:
+	// path: and github: for plugins
 	// plugin: for built-in plugins
+	// https:// and git+<scheme>:// for devbox.json fragments, which are
+	// locked in devbox.lock
 	// This is a similar format to nix inputs
 	Include []string `json:"include,omitempty"`
 
+	// LicensePolicy restricts the licenses of the project's packages.
+	LicensePolicy *LicensePolicy `json:"license_policy,omitempty"`
+
+	// Derivations are packages that are built from source and installed
+	// in the environment. The keys are the package names.
+	Derivations map[string]Derivation `json:"derivations,omitempty"`
+
+	// BinaryCaches are substituters that nix uses in addition to
+	// cache.nixos.org. Packages are fetched from the first cache, in order
+	// of priority, that has them.
+	BinaryCaches []nix.BinaryCache `json:"binary_caches,omitempty"`
+
+	// Platforms are the systems that the project must work on. devbox lock
+	// records the packages' outputs for each of them.
+	Platforms []string `json:"platforms,omitempty"`
+
 	ast *configAST
 }
 
 type shellConfig struct {
 	// InitHook contains commands that will run at shell startup.
-	InitHook *shellcmd.Commands            `json:"init_hook,omitempty"`
-	Scripts  map[string]*shellcmd.Commands `json:"scripts,omitempty"`
+	InitHook *shellcmd.Commands       `json:"init_hook,omitempty"`
+	Scripts  map[string]*ScriptConfig `json:"scripts,omitempty"`
 }
 
 type NixpkgsConfig struct {
@@ -146,6 +164,14 @@ func LoadBytes(b []byte) (*ConfigFile, error) {
 		ast:             ast,
 	}
 	if err := json.Unmarshal(jsonb, cfg); err != nil {
+		// Report where the problem is, which json.Unmarshal can't do
+		// because it only sees the standardized JSON.
+		problems, _ := Validate(b)
+		for _, p := range problems {
+			if p.wrongType {
+				return nil, errors.Errorf("invalid config at %s", p)
+			}
+		}
 		return nil, err
 	}
 	return cfg, validateConfig(cfg)
@@ -155,6 +181,10 @@ func validateConfig(cfg *ConfigFile) error {
 	fns := []func(cfg *ConfigFile) error{
 		ValidateNixpkg,
 		validateScripts,
+		validateLicensePolicy,
+		validateOverrides,
+		validateBinaryCaches,
+		validatePlatforms,
 	}
 
 	for _, fn := range fns {
@@ -185,6 +215,10 @@ func validateScripts(cfg *ConfigFile) error {
 	return nil
 }
 
+func validatePlatforms(cfg *ConfigFile) error {
+	return nix.EnsureValidPlatform(cfg.Platforms...)
+}
+
 func ValidateNixpkg(cfg *ConfigFile) error {
 	hash := cfg.NixPkgsCommitHash()
 	if hash == "" {
@@ -710,3 +710,21 @@ func TestNixpkgsValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestPlatformsValidation(t *testing.T) {
+	_, err := LoadBytes([]byte(`{"platforms": ["x86_64-linux", "aarch64-darwin"]}`))
+	assert.NoError(t, err)
+
+	_, err = LoadBytes([]byte(`{"platforms": ["x86_64-windows"]}`))
+	assert.Error(t, err)
+}
+
+func TestIsEnabledOnSystem(t *testing.T) {
+	pkg := Package{Platforms: []string{"x86_64-linux"}}
+	assert.True(t, pkg.IsEnabledOnSystem("x86_64-linux"))
+	assert.False(t, pkg.IsEnabledOnSystem("aarch64-darwin"))
+
+	pkg = Package{ExcludedPlatforms: []string{"aarch64-darwin"}}
+	assert.True(t, pkg.IsEnabledOnSystem("x86_64-linux"))
+	assert.False(t, pkg.IsEnabledOnSystem("aarch64-darwin"))
+}
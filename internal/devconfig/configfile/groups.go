@@ -0,0 +1,90 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package configfile
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/cuecfg"
+	"go.jetpack.io/devbox/internal/devbox/shellcmd"
+)
+
+// DefaultGroup is the group of packages that don't list any groups. It's
+// installed unless a command selects groups with --only.
+const DefaultGroup = "default"
+
+var groupNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// GroupsOrDefault returns the package's groups, or the default group if it
+// doesn't list any.
+func (p *Package) GroupsOrDefault() []string {
+	if len(p.Groups) == 0 {
+		return []string{DefaultGroup}
+	}
+	return p.Groups
+}
+
+// InGroups returns whether the package is in any of the groups.
+func (p *Package) InGroups(groups []string) bool {
+	for _, g := range p.GroupsOrDefault() {
+		if slices.Contains(groups, g) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkGroupName(name string) error {
+	if !groupNameRegexp.MatchString(name) {
+		return errors.Errorf(
+			"invalid group name %q: must only contain letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// ScriptConfig is a script in devbox.json. It's either the script's shell
+// commands as a string or an array of strings, or an object with the commands
+// and the package groups that the script needs:
+//
+//	"e2e": {"command": "playwright test", "groups": ["e2e"]}
+type ScriptConfig struct {
+	shellcmd.Commands
+
+	// Groups are the package groups that are installed in addition to the
+	// selected groups when the script runs.
+	Groups []string
+
+	// isObject is true if the script was unmarshalled from an object so
+	// that it marshals back to one.
+	isObject bool
+}
+
+type scriptObject struct {
+	Command shellcmd.Commands `json:"command"`
+	Groups  []string          `json:"groups,omitempty"`
+}
+
+func (s ScriptConfig) MarshalJSON() ([]byte, error) {
+	if !s.isObject && len(s.Groups) == 0 {
+		return s.Commands.MarshalJSON()
+	}
+	return cuecfg.MarshalJSON(scriptObject{Command: s.Commands, Groups: s.Groups})
+}
+
+func (s *ScriptConfig) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '{' {
+		*s = ScriptConfig{}
+		return s.Commands.UnmarshalJSON(data)
+	}
+	obj := scriptObject{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return errors.WithStack(err)
+	}
+	*s = ScriptConfig{Commands: obj.Command, Groups: obj.Groups, isObject: true}
+	return nil
+}
@@ -0,0 +1,70 @@
+package configfile
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestScriptConfigJSON(t *testing.T) {
+	for _, in := range []string{
+		`"go test ./..."`,
+		`["go build","go test"]`,
+		`{"command":"playwright test","groups":["e2e"]}`,
+		`{"command":["a","b"]}`,
+	} {
+		script := ScriptConfig{}
+		if err := json.Unmarshal([]byte(in), &script); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", in, err)
+		}
+		out, err := json.Marshal(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != in {
+			t.Errorf("got %s after a round trip, want %s", out, in)
+		}
+	}
+}
+
+func TestScriptGroups(t *testing.T) {
+	cfg, err := LoadBytes([]byte(`{
+  "packages": {"playwright": {"version": "latest", "groups": ["e2e"]}},
+  "shell": {"scripts": {"e2e": {"command": "playwright test", "groups": ["e2e"]}}}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := cfg.Scripts()["e2e"]
+	if script.String() != "playwright test" || !slices.Equal(script.Groups, []string{"e2e"}) {
+		t.Errorf("got script %q with groups %q, want playwright test with e2e", script.String(), script.Groups)
+	}
+
+	pkg := cfg.TopLevelPackages()[0]
+	if pkg.InGroups([]string{DefaultGroup}) || !pkg.InGroups([]string{DefaultGroup, "e2e"}) {
+		t.Errorf("got wrong groups for a package in the e2e group: %q", pkg.GroupsOrDefault())
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	got, err := Validate([]byte(`{
+  "packages": {"hello": {"groups": ["e2e tests"]}},
+  "shell": {"scripts": {"e2e": {"command": "hello", "groups": [""], "env": {}}}}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`2:37: packages.hello.groups[0]: invalid group name "e2e tests"`,
+		`3:64: shell.scripts.e2e.groups[0]: invalid group name ""`,
+		`3:69: shell.scripts.e2e.env: unknown field`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(got), len(want), got)
+	}
+	for i := range want {
+		if gotStr := got[i].String(); len(gotStr) < len(want[i]) || gotStr[:len(want[i])] != want[i] {
+			t.Errorf("got problem %d = %q, want prefix %q", i, gotStr, want[i])
+		}
+	}
+}
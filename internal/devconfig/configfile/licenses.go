@@ -0,0 +1,80 @@
+package configfile
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LicensePolicy restricts the licenses of the packages in a project.
+type LicensePolicy struct {
+	// Allow lists the SPDX IDs of the allowed licenses. If it's not empty,
+	// every license of every package must be in the list. Entries may use
+	// shell-style wildcards, such as "BSD-*".
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny lists the SPDX IDs of licenses that aren't allowed. It takes
+	// precedence over Allow and supports the same wildcards.
+	Deny []string `json:"deny,omitempty"`
+
+	// AllowUnfree allows packages that nixpkgs considers unfree. It defaults
+	// to true.
+	AllowUnfree *bool `json:"allow_unfree,omitempty"`
+}
+
+// Violations returns the reasons a package with the given licenses doesn't
+// comply with the policy. It returns nil if the package complies.
+//
+// A package with more than one license must comply with every license. This
+// is stricter than necessary for dual-licensed packages, but nixpkgs doesn't
+// distinguish between "and" and "or" when it lists several licenses.
+func (p *LicensePolicy) Violations(licenses []string, unfree bool) []string {
+	if p == nil {
+		return nil
+	}
+
+	violations := []string{}
+	if unfree && p.AllowUnfree != nil && !*p.AllowUnfree {
+		violations = append(violations, "unfree packages are not allowed")
+	}
+	if len(p.Allow) > 0 && len(licenses) == 0 {
+		violations = append(violations, "it has no license information")
+	}
+	for _, license := range licenses {
+		if matchLicense(p.Deny, license) {
+			violations = append(violations, fmt.Sprintf("license %s is denied", license))
+		} else if len(p.Allow) > 0 && !matchLicense(p.Allow, license) {
+			violations = append(violations, fmt.Sprintf("license %s is not allowed", license))
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return violations
+}
+
+// matchLicense reports if license matches any of the patterns. SPDX license
+// IDs are case-insensitive.
+func matchLicense(patterns []string, license string) bool {
+	license = strings.ToLower(license)
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		ok, err := path.Match(strings.ToLower(pattern), license)
+		return err == nil && ok
+	})
+}
+
+func validateLicensePolicy(cfg *ConfigFile) error {
+	if cfg.LicensePolicy == nil {
+		return nil
+	}
+	for _, pattern := range slices.Concat(cfg.LicensePolicy.Allow, cfg.LicensePolicy.Deny) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Errorf(
+				"invalid license pattern in license_policy of devbox.json: %s", pattern)
+		}
+	}
+	return nil
+}
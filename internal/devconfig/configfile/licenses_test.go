@@ -0,0 +1,51 @@
+package configfile
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLicensePolicyViolations(t *testing.T) {
+	noUnfree := false
+	policy := &LicensePolicy{
+		Allow:       []string{"MIT", "Apache-2.0", "bsd-*", "AGPL-3.0-only"},
+		Deny:        []string{"AGPL-*"},
+		AllowUnfree: &noUnfree,
+	}
+
+	tests := []struct {
+		licenses []string
+		unfree   bool
+		want     []string
+	}{
+		{[]string{"MIT"}, false, nil},
+		{[]string{"mit", "BSD-3-Clause"}, false, nil},
+		{[]string{"GPL-3.0-only"}, false, []string{"license GPL-3.0-only is not allowed"}},
+		{[]string{"AGPL-3.0-only"}, false, []string{"license AGPL-3.0-only is denied"}},
+		{nil, false, []string{"it has no license information"}},
+		{[]string{"MIT"}, true, []string{"unfree packages are not allowed"}},
+	}
+	for _, tt := range tests {
+		got := policy.Violations(tt.licenses, tt.unfree)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Violations(%v, %v) = %q, want %q", tt.licenses, tt.unfree, got, tt.want)
+		}
+	}
+
+	// Without an allow list or an explicit allow_unfree, only denied
+	// licenses are violations.
+	denyOnly := &LicensePolicy{Deny: []string{"AGPL-*"}}
+	if got := denyOnly.Violations(nil, true); got != nil {
+		t.Errorf("Violations(nil, true) = %q, want nil", got)
+	}
+	if got := (*LicensePolicy)(nil).Violations([]string{"AGPL-3.0-only"}, true); got != nil {
+		t.Errorf("nil policy Violations() = %q, want nil", got)
+	}
+}
+
+func TestLoadInvalidLicensePolicy(t *testing.T) {
+	_, err := LoadBytes([]byte(`{"license_policy": {"deny": ["AGPL-["]}}`))
+	if err == nil {
+		t.Error("got nil error for an invalid license pattern")
+	}
+}
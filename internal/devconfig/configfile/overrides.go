@@ -0,0 +1,176 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package configfile
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/pkg/errors"
+
+	"go.jetpack.io/devbox/internal/devbox/shellcmd"
+)
+
+// PackageOverride customizes the Nix derivation of a package without having
+// to write a flake. For example:
+//
+//	"python@3.12": {
+//	  "override": {"with_packages": ["numpy", "pandas"]}
+//	}
+type PackageOverride struct {
+	// WithPackages are packages from the language's package set, such as
+	// python3Packages, that are added with the package's withPackages
+	// function. It works for python, ruby, perl and other packages that
+	// have a withPackages function.
+	WithPackages []string `json:"with_packages,omitempty"`
+
+	// Src replaces the package's source with overrideAttrs.
+	Src *Source `json:"src,omitempty"`
+
+	// Patches are paths to patch files, relative to the project
+	// directory, that are applied after the package's own patches.
+	Patches []string `json:"patches,omitempty"`
+
+	// Attrs are other derivation attributes to set with overrideAttrs.
+	// The values are Nix expressions, so strings must be quoted.
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+// Source is the source code of a package override or derivation. It's either
+// a URL with its hash or a local path.
+type Source struct {
+	URL  string `json:"url,omitempty"`
+	Hash string `json:"hash,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// Derivation is a package that's built from source with the standard
+// environment's mkDerivation. Derivations are always installed in the
+// project's environment.
+type Derivation struct {
+	Version string  `json:"version,omitempty"`
+	Src     *Source `json:"src,omitempty"`
+
+	// BuildInputs and NativeBuildInputs are attribute names of nixpkgs
+	// packages, such as "openssl" or "pkg-config".
+	BuildInputs       []string `json:"build_inputs,omitempty"`
+	NativeBuildInputs []string `json:"native_build_inputs,omitempty"`
+
+	// Build and Install are the commands of the build and install phases.
+	// Install must put the package's files in $out.
+	Build   *shellcmd.Commands `json:"build,omitempty"`
+	Install *shellcmd.Commands `json:"install,omitempty"`
+}
+
+// AbsPaths returns a copy of the override with its local paths made absolute
+// relative to dir.
+func (o *PackageOverride) AbsPaths(dir string) *PackageOverride {
+	if o == nil {
+		return nil
+	}
+	abs := *o
+	abs.Src = o.Src.AbsPaths(dir)
+	abs.Patches = make([]string, len(o.Patches))
+	for i, patch := range o.Patches {
+		abs.Patches[i] = absPath(dir, patch)
+	}
+	return &abs
+}
+
+// AbsPaths returns a copy of the source with its path made absolute relative
+// to dir.
+func (s *Source) AbsPaths(dir string) *Source {
+	if s == nil {
+		return nil
+	}
+	abs := *s
+	if abs.Path != "" {
+		abs.Path = absPath(dir, abs.Path)
+	}
+	return &abs
+}
+
+func absPath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// nixAttrRegexp matches a Nix attribute name or a path of attribute names.
+var nixAttrRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_'-]*(\.[a-zA-Z_][a-zA-Z0-9_'-]*)*$`)
+
+func checkNixAttr(name string) error {
+	if !nixAttrRegexp.MatchString(name) {
+		return errors.Errorf("invalid Nix attribute name %q", name)
+	}
+	return nil
+}
+
+// derivationNameRegexp matches derivation names, which are also used as Nix
+// identifiers in the generated flake.
+var derivationNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+func checkDerivationName(name string) error {
+	if !derivationNameRegexp.MatchString(name) {
+		return errors.Errorf(
+			"invalid derivation name %q: must start with a letter and only contain letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+func validateSource(src *Source, where string) error {
+	if src == nil {
+		return nil
+	}
+	switch {
+	case src.Path != "" && src.URL != "":
+		return errors.Errorf("%s in devbox.json can't have both a url and a path", where)
+	case src.URL != "" && src.Hash == "":
+		return errors.Errorf("%s in devbox.json must have the hash of its url", where)
+	case src.URL == "" && src.Path == "":
+		return errors.Errorf("%s in devbox.json must have a url or a path", where)
+	}
+	return nil
+}
+
+func validateOverrides(cfg *ConfigFile) error {
+	for _, pkg := range cfg.TopLevelPackages() {
+		if pkg.Override == nil {
+			continue
+		}
+		if pkg.PatchGlibc {
+			return errors.Errorf(
+				"package %s in devbox.json can't have both an override and patch_glibc", pkg.VersionedName())
+		}
+		if err := validateSource(pkg.Override.Src, "the override src of package "+pkg.VersionedName()); err != nil {
+			return err
+		}
+		for _, name := range pkg.Override.WithPackages {
+			if err := checkNixAttr(name); err != nil {
+				return errors.Wrapf(err, "with_packages of package %s in devbox.json", pkg.VersionedName())
+			}
+		}
+		for name := range pkg.Override.Attrs {
+			if err := checkNixAttr(name); err != nil {
+				return errors.Wrapf(err, "override attrs of package %s in devbox.json", pkg.VersionedName())
+			}
+		}
+	}
+	for name, drv := range cfg.Derivations {
+		if err := checkDerivationName(name); err != nil {
+			return errors.Wrap(err, "devbox.json")
+		}
+		if err := validateSource(drv.Src, "the src of derivation "+name); err != nil {
+			return err
+		}
+		for _, input := range slices.Concat(drv.BuildInputs, drv.NativeBuildInputs) {
+			if err := checkNixAttr(input); err != nil {
+				return errors.Wrapf(err, "build inputs of derivation %s in devbox.json", name)
+			}
+		}
+	}
+	return nil
+}
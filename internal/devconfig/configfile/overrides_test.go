@@ -0,0 +1,70 @@
+package configfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOverrides(t *testing.T) {
+	cfg, err := LoadBytes([]byte(`{
+  "packages": {
+    "python": {"version": "3.12", "override": {"with_packages": ["numpy", "pandas"]}},
+    "hello": {"override": {"patches": ["patches/hello.patch"], "attrs": {"doCheck": "false"}}}
+  },
+  "derivations": {
+    "greet": {"src": {"path": "./greet"}, "install": "install -D greet.sh $out/bin/greet"}
+  }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	python, _ := cfg.GetPackage("python@3.12")
+	if got := python.Override.WithPackages; strings.Join(got, " ") != "numpy pandas" {
+		t.Errorf("got with_packages %q, want numpy and pandas", got)
+	}
+	hello, _ := cfg.GetPackage("hello")
+	if got := hello.Override.AbsPaths("/project").Patches[0]; got != "/project/patches/hello.patch" {
+		t.Errorf("got patch path %q, want it relative to the project", got)
+	}
+	if got := cfg.Derivations["greet"].Src.AbsPaths("/project").Path; got != "/project/greet" {
+		t.Errorf("got derivation src %q, want it relative to the project", got)
+	}
+}
+
+func TestOverridesInvalid(t *testing.T) {
+	tests := map[string]string{
+		"URLAndPath":  `{"packages": {"hello": {"override": {"src": {"url": "https://example.com", "hash": "sha256-", "path": "."}}}}}`,
+		"MissingHash": `{"packages": {"hello": {"override": {"src": {"url": "https://example.com"}}}}}`,
+		"PatchGlibc":  `{"packages": {"hello": {"patch_glibc": true, "override": {"attrs": {"doCheck": "false"}}}}}`,
+		"EmptySrc":    `{"derivations": {"greet": {"src": {}}}}`,
+	}
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadBytes([]byte(config)); err == nil {
+				t.Error("got nil error for an invalid override")
+			}
+		})
+	}
+}
+
+func TestValidateOverrides(t *testing.T) {
+	got, err := Validate([]byte(`{
+  "packages": {"python": {"override": {"with_packages": ["numpy", "not valid"]}}},
+  "derivations": {"1tool": {"build_inputs": ["openssl"]}}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`packages.python.override.with_packages[1]: invalid Nix attribute name "not valid"`,
+		`derivations.1tool: invalid derivation name "1tool"`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !strings.Contains(got[i].String(), want[i]) {
+			t.Errorf("got problem %d = %q, want it to contain %q", i, got[i], want[i])
+		}
+	}
+}
@@ -225,6 +225,32 @@ func (pkgs *PackagesMutator) SetAllowInsecure(writer io.Writer, versionedName st
 	return nil
 }
 
+func (pkgs *PackagesMutator) SetGroups(writer io.Writer, versionedName string, groups []string) error {
+	name, version := parseVersionedName(versionedName)
+	i := pkgs.index(name, version)
+	if i == -1 {
+		return errors.Errorf("package %s not found", versionedName)
+	}
+
+	toAdd := []string{}
+	for _, g := range groups {
+		if err := checkGroupName(g); err != nil {
+			return usererr.WithUserMessage(err, "Invalid group name %q.", g)
+		}
+		if !slices.Contains(pkgs.collection[i].Groups, g) {
+			toAdd = append(toAdd, g)
+		}
+	}
+
+	if len(toAdd) > 0 {
+		pkg := &pkgs.collection[i]
+		pkgs.ast.appendStringSliceField(pkg.Name, "groups", toAdd)
+		pkg.Groups = append(pkg.Groups, toAdd...)
+		ux.Finfo(writer, "Added package %s to groups %s\n", versionedName, strings.Join(toAdd, ", "))
+	}
+	return nil
+}
+
 func (pkgs *PackagesMutator) index(name, version string) int {
 	return slices.IndexFunc(pkgs.collection, func(p Package) bool {
 		return p.Name == name && p.Version == version
@@ -250,6 +276,13 @@ type Package struct {
 	// AllowInsecure is a whitelist of packages that may be marked insecure
 	// in nixpkgs, but are allowed by the user to be installed.
 	AllowInsecure []string `json:"allow_insecure,omitempty"`
+
+	// Groups are the package groups that the package belongs to. Packages
+	// without groups are in the default group.
+	Groups []string `json:"groups,omitempty"`
+
+	// Override customizes the package's Nix derivation.
+	Override *PackageOverride `json:"override,omitempty"`
 }
 
 func NewVersionOnlyPackage(name, version string) Package {
@@ -259,12 +292,17 @@ func NewVersionOnlyPackage(name, version string) Package {
 	}
 }
 
-// enabledOnPlatform returns whether the package is enabled on the given platform.
+// IsEnabledOnPlatform returns whether the package is enabled on the current
+// system.
+func (p *Package) IsEnabledOnPlatform() bool {
+	return p.IsEnabledOnSystem(nix.System())
+}
+
+// IsEnabledOnSystem returns whether the package is enabled on the given system.
 // If the package has a list of platforms, it is enabled only on those platforms.
 // If the package has a list of excluded platforms, it is enabled on all platforms
 // except those.
-func (p *Package) IsEnabledOnPlatform() bool {
-	platform := nix.System()
+func (p *Package) IsEnabledOnSystem(platform string) bool {
 	if len(p.Platforms) > 0 {
 		for _, plt := range p.Platforms {
 			if plt == platform {
@@ -0,0 +1,374 @@
+package configfile
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tailscale/hujson"
+)
+
+// GetPath returns the JSON value at a path in the config, such as
+// "shell.scripts.test". See parsePath for the path syntax. The value is
+// standard JSON without comments. It returns false if the path isn't set.
+func (c *ConfigFile) GetPath(path string) ([]byte, bool, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Work on a copy so that looking up a package field doesn't migrate
+	// the packages array to an object.
+	ast := &configAST{root: c.ast.root.Clone()}
+	if err := ast.preparePackagesPath(segments); errors.Is(err, errPackageNotFound) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	val, err := ast.valueAt(segments)
+	if err != nil || val == nil {
+		return nil, false, err
+	}
+	standard := val.Clone()
+	standard.Standardize()
+	standard.Minimize()
+	return standard.Pack(), true, nil
+}
+
+// SetPath sets the value at a path in the config to a JSON value, creating
+// any missing objects along the way. Comments and formatting elsewhere in the
+// file are preserved.
+func (c *ConfigFile) SetPath(path string, value []byte) error {
+	segments, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	val, err := hujson.Parse(value)
+	if err != nil {
+		return errors.Wrapf(err, "invalid JSON value for %s", path)
+	}
+	return c.edit(path, func(ast *configAST) error {
+		if err := ast.preparePackagesPath(segments); err != nil {
+			return err
+		}
+		return ast.setValue(segments, val)
+	})
+}
+
+// UnsetPath removes the value at a path in the config. It returns false if
+// the path wasn't set.
+func (c *ConfigFile) UnsetPath(path string) (bool, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return false, err
+	}
+	removed := false
+	err = c.edit(path, func(ast *configAST) error {
+		if err := ast.preparePackagesPath(segments); err != nil {
+			return err
+		}
+		removed, err = ast.removeValue(segments)
+		return err
+	})
+	if errors.Is(err, errPackageNotFound) {
+		return false, nil
+	}
+	return removed, err
+}
+
+// edit applies fn to a copy of the config's AST and reloads the config from
+// the result. The config is left unchanged if fn fails or the edited config
+// is invalid.
+func (c *ConfigFile) edit(path string, fn func(ast *configAST) error) error {
+	ast := &configAST{root: c.ast.root.Clone()}
+	if err := fn(ast); err != nil {
+		return err
+	}
+	ast.root.Format()
+	updated, err := LoadBytes(ast.root.Pack())
+	if err != nil {
+		return errors.Wrapf(err, "invalid devbox.json after changing %s", path)
+	}
+	updated.AbsRootPath = c.AbsRootPath
+	*c = *updated
+	return nil
+}
+
+// parsePath parses a path to a value in devbox.json. Object keys are
+// separated by dots and array indexes are in brackets. Keys that contain dots
+// or brackets, such as package names, can be quoted in brackets. For example:
+//
+//	shell.scripts.test
+//	shell.init_hook[0]
+//	packages["python@3.12"].platforms
+//
+// Object keys are returned as strings and array indexes as ints.
+func parsePath(path string) ([]any, error) {
+	segments := []any{}
+	rest := path
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, `["`):
+			key, err := strconv.QuotedPrefix(rest[1:])
+			if err != nil || !strings.HasPrefix(rest[1+len(key):], "]") {
+				return nil, errors.Errorf("invalid path %q: unterminated quoted key", path)
+			}
+			unquoted, _ := strconv.Unquote(key)
+			segments = append(segments, unquoted)
+			rest = rest[len(key)+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, errors.Errorf("invalid path %q: missing ]", path)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 {
+				return nil, errors.Errorf("invalid path %q: bad array index %q", path, rest[1:end])
+			}
+			segments = append(segments, i)
+			rest = rest[end+1:]
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, errors.Errorf("invalid path %q: empty key", path)
+			}
+			segments = append(segments, rest[:end])
+			rest = rest[end:]
+		}
+
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" {
+				return nil, errors.Errorf("invalid path %q: trailing dot", path)
+			}
+		} else if rest != "" && rest[0] != '[' {
+			return nil, errors.Errorf("invalid path %q", path)
+		}
+	}
+	if len(segments) == 0 {
+		return nil, errors.New("path must not be empty")
+	}
+	return segments, nil
+}
+
+var errPackageNotFound = errors.New("package is not in devbox.json")
+
+// preparePackagesPath converts the packages field to the object format when
+// the path refers to a single package, and converts that package's version
+// string to an object when the path refers to one of its fields. This lets
+// paths like packages.go.platforms work with every package format.
+//
+// The package can be referred to by name or by versioned name, such as
+// python@3.12, and path is updated to refer to its member of the packages
+// object. Packages are added with devbox add, so it returns
+// errPackageNotFound if the package isn't in the config.
+func (c *configAST) preparePackagesPath(path []any) error {
+	if len(path) < 2 || path[0] != "packages" {
+		return nil
+	}
+	versionedName, ok := path[1].(string)
+	if !ok {
+		return errors.New("packages must be referred to by name, not index")
+	}
+	rootObject, ok := c.root.Value.(*hujson.Object)
+	if !ok || c.memberIndex(rootObject, "packages") == -1 {
+		return errors.Wrap(errPackageNotFound, versionedName)
+	}
+
+	pkgs := c.packagesField(true)
+	obj, ok := pkgs.Value.Value.(*hujson.Object)
+	if !ok {
+		return nil
+	}
+	i := c.packageMemberIndex(obj, versionedName)
+	if i == -1 {
+		return errors.Wrap(errPackageNotFound, versionedName)
+	}
+	path[1] = obj.Members[i].Name.Value.(hujson.Literal).String()
+	if len(path) > 2 {
+		c.convertVersionToObject(&obj.Members[i].Value)
+	}
+	return nil
+}
+
+// packageMemberIndex returns the index of the member of the packages object
+// that versionedName refers to. A versioned name, such as python@3.12, matches
+// the member with that name and version, like it does in the packages mutator.
+func (c *configAST) packageMemberIndex(pkgs *hujson.Object, versionedName string) int {
+	if i := c.memberIndex(pkgs, versionedName); i != -1 {
+		return i
+	}
+	name, version := parseVersionedName(versionedName)
+	if version == "" {
+		return -1
+	}
+	return slices.IndexFunc(pkgs.Members, func(m hujson.ObjectMember) bool {
+		return m.Name.Value.(hujson.Literal).String() == name && packageVersion(m.Value) == version
+	})
+}
+
+// packageVersion returns the version of a member of the packages object,
+// which is either a version string or an object with a version field.
+func packageVersion(pkg hujson.Value) string {
+	switch val := pkg.Value.(type) {
+	case hujson.Literal:
+		return val.String()
+	case *hujson.Object:
+		for _, m := range val.Members {
+			if m.Name.Value.(hujson.Literal).String() != "version" {
+				continue
+			}
+			if version, ok := m.Value.Value.(hujson.Literal); ok {
+				return version.String()
+			}
+		}
+	}
+	return ""
+}
+
+// valueAt returns the value at path or nil if it isn't set.
+func (c *configAST) valueAt(path []any) (*hujson.Value, error) {
+	val := &c.root
+	for n, segment := range path {
+		next, err := c.child(val, path[:n+1], segment, false)
+		if err != nil || next == nil {
+			return nil, err
+		}
+		val = next
+	}
+	return val, nil
+}
+
+// setValue sets the value at path, creating any missing objects along the
+// way. Array indexes must refer to an existing element or be one past the end
+// to append an element.
+func (c *configAST) setValue(path []any, val hujson.Value) error {
+	parent := &c.root
+	for n, segment := range path[:len(path)-1] {
+		next, err := c.child(parent, path[:n+1], segment, true)
+		if err != nil {
+			return err
+		}
+		parent = next
+	}
+	target, err := c.child(parent, path, path[len(path)-1], true)
+	if err != nil {
+		return err
+	}
+	// Replace the value but keep the comments around it.
+	target.Value = val.Value
+	return nil
+}
+
+// removeValue removes the value at path. It returns false if it isn't set.
+func (c *configAST) removeValue(path []any) (bool, error) {
+	parent, err := c.valueAt(path[:len(path)-1])
+	if err != nil || parent == nil {
+		return false, err
+	}
+	switch segment := path[len(path)-1].(type) {
+	case string:
+		obj, ok := parent.Value.(*hujson.Object)
+		if !ok {
+			return false, notAnObjectError(path[:len(path)-1])
+		}
+		i := c.memberIndex(obj, segment)
+		if i == -1 {
+			return false, nil
+		}
+		obj.Members = slices.Delete(obj.Members, i, i+1)
+	case int:
+		arr, ok := parent.Value.(*hujson.Array)
+		if !ok {
+			return false, errors.Errorf("%s is not an array", formatPath(path[:len(path)-1]))
+		}
+		if segment >= len(arr.Elements) {
+			return false, nil
+		}
+		arr.Elements = slices.Delete(arr.Elements, segment, segment+1)
+	}
+	return true, nil
+}
+
+// child returns the member or element of val that segment refers to. If
+// create is true, it adds missing object members (initialized to an empty
+// object) and allows appending to arrays. Otherwise it returns nil for
+// missing children.
+func (c *configAST) child(val *hujson.Value, path []any, segment any, create bool) (*hujson.Value, error) {
+	switch segment := segment.(type) {
+	case string:
+		if create {
+			if lit, ok := val.Value.(hujson.Literal); ok && lit.Kind() == 'n' {
+				val.Value = &hujson.Object{}
+			}
+		}
+		obj, ok := val.Value.(*hujson.Object)
+		if !ok {
+			return nil, notAnObjectError(path[:len(path)-1])
+		}
+		i := c.memberIndex(obj, segment)
+		if i == -1 {
+			if !create {
+				return nil, nil
+			}
+			obj.Members = append(obj.Members, hujson.ObjectMember{
+				Name:  hujson.Value{Value: hujson.String(segment), BeforeExtra: []byte{'\n'}},
+				Value: hujson.Value{Value: &hujson.Object{}},
+			})
+			i = len(obj.Members) - 1
+		}
+		return &obj.Members[i].Value, nil
+	case int:
+		arr, ok := val.Value.(*hujson.Array)
+		if !ok {
+			return nil, errors.Errorf("%s is not an array", formatPath(path[:len(path)-1]))
+		}
+		if segment == len(arr.Elements) && create {
+			var extra []byte
+			if len(arr.Elements) > 0 {
+				extra = []byte{'\n'}
+			}
+			arr.Elements = append(arr.Elements, hujson.Value{BeforeExtra: extra})
+		}
+		if segment >= len(arr.Elements) {
+			if !create {
+				return nil, nil
+			}
+			return nil, errors.Errorf("index %d of %s is out of range", segment, formatPath(path[:len(path)-1]))
+		}
+		return &arr.Elements[segment], nil
+	}
+	panic("path segments must be strings or ints")
+}
+
+func notAnObjectError(path []any) error {
+	return errors.Errorf("%s is not an object", formatPath(path))
+}
+
+// formatPath formats parsed path segments for error messages.
+func formatPath(path []any) string {
+	if len(path) == 0 {
+		return "devbox.json"
+	}
+	sb := strings.Builder{}
+	for _, segment := range path {
+		switch segment := segment.(type) {
+		case string:
+			if strings.ContainsAny(segment, ".[]\"") {
+				sb.WriteString("[" + strconv.Quote(segment) + "]")
+				continue
+			}
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(segment)
+		case int:
+			sb.WriteString("[" + strconv.Itoa(segment) + "]")
+		}
+	}
+	return sb.String()
+}
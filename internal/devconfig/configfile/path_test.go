@@ -0,0 +1,234 @@
+package configfile
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []any
+	}{
+		{"name", []any{"name"}},
+		{"shell.scripts.test", []any{"shell", "scripts", "test"}},
+		{"shell.init_hook[1]", []any{"shell", "init_hook", 1}},
+		{`packages["python@3.12"].platforms[0]`, []any{"packages", "python@3.12", "platforms", 0}},
+		{`env["A.B"]`, []any{"env", "A.B"}},
+	}
+	for _, tt := range tests {
+		got, err := parsePath(tt.path)
+		if err != nil {
+			t.Errorf("parsePath(%q) error: %v", tt.path, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parsePath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	for _, path := range []string{"", "a.", ".a", "a..b", "a[x]", "a[-1]", `a["b`, "a[0]b"} {
+		if got, err := parsePath(path); err == nil {
+			t.Errorf("parsePath(%q) = %v, want error", path, got)
+		}
+	}
+}
+
+func TestSetPathPreservesComments(t *testing.T) {
+	in, want := parseConfigTxtarTest(t, `
+-- in --
+{
+  // Project packages
+  "packages": ["go"],
+  "env": {
+    // Keep this comment
+    "FOO": "bar"
+  }
+}
+-- want --
+{
+  // Project packages
+  "packages": ["go"],
+  "env": {
+    // Keep this comment
+    "FOO": "baz",
+    "NEW": "value",
+  },
+  "shell": {
+    "init_hook": ["echo hi"],
+  },
+}
+`)
+
+	for path, value := range map[string]string{
+		"env.FOO":         `"baz"`,
+		"env.NEW":         `"value"`,
+		"shell.init_hook": `["echo hi"]`,
+	} {
+		if err := in.SetPath(path, []byte(value)); err != nil {
+			t.Fatalf("SetPath(%q, %s) error: %v", path, value, err)
+		}
+	}
+	if diff := cmp.Diff(want, in.Bytes(), optBytesToStrings()); diff != "" {
+		t.Errorf("wrong raw config hujson (-want +got):\n%s", diff)
+	}
+	if got := in.Env["FOO"]; got != "baz" {
+		t.Errorf("got env FOO = %q after SetPath, want baz", got)
+	}
+}
+
+func TestSetPathPackageField(t *testing.T) {
+	in, want := parseConfigTxtarTest(t, `
+-- in --
+{
+  "packages": ["go@1.20", "hello"]
+}
+-- want --
+{
+  "packages": {
+    "go": {
+      "version":   "1.20",
+      "platforms": ["x86_64-linux"]
+    },
+    "hello": ""
+  }
+}
+`)
+
+	if err := in.SetPath(`packages.go.platforms`, []byte(`["x86_64-linux"]`)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, in.Bytes(), optBytesToStrings()); diff != "" {
+		t.Errorf("wrong raw config hujson (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetPathVersionedPackage(t *testing.T) {
+	in, want := parseConfigTxtarTest(t, `
+-- in --
+{
+  "packages": ["go@1.22", "python@3.12"]
+}
+-- want --
+{
+  "packages": {
+    "go": "1.22",
+    "python": {
+      "version":   "3.12",
+      "platforms": ["x86_64-linux"]
+    }
+  }
+}
+`)
+
+	path := `packages["python@3.12"].platforms`
+	if err := in.SetPath(path, []byte(`["x86_64-linux"]`)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, in.Bytes(), optBytesToStrings()); diff != "" {
+		t.Errorf("wrong raw config hujson (-want +got):\n%s", diff)
+	}
+	if got, ok, err := in.GetPath(path); err != nil || string(got) != `["x86_64-linux"]` {
+		t.Errorf("GetPath(%q) = %s, %v, %v", path, got, ok, err)
+	}
+
+	for _, path := range []string{`packages["python@3.11"].platforms`, "packages.hello.platforms", "packages.hello"} {
+		if err := in.SetPath(path, []byte(`["x86_64-linux"]`)); err == nil {
+			t.Errorf("got nil error for %s, which isn't in the config", path)
+		}
+		if _, ok, err := in.GetPath(path); ok || err != nil {
+			t.Errorf("GetPath(%q) = %v, %v, want false, nil", path, ok, err)
+		}
+		if ok, err := in.UnsetPath(path); ok || err != nil {
+			t.Errorf("UnsetPath(%q) = %v, %v, want false, nil", path, ok, err)
+		}
+	}
+	if diff := cmp.Diff(want, in.Bytes(), optBytesToStrings()); diff != "" {
+		t.Errorf("config changed after editing missing packages (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetPathInvalid(t *testing.T) {
+	in, _ := parseConfigTxtarTest(t, `
+-- in --
+{
+  "packages": [],
+  "env": {"FOO": "bar"}
+}
+`)
+	before := in.Bytes()
+
+	if err := in.SetPath("env.FOO", []byte(`8080`)); err == nil {
+		t.Error("got nil error for a number env value")
+	}
+	if err := in.SetPath("env.FOO.BAR", []byte(`"x"`)); err == nil {
+		t.Error("got nil error for a key in a string")
+	}
+	if err := in.SetPath("env", []byte(`{`)); err == nil {
+		t.Error("got nil error for invalid JSON")
+	}
+	if diff := cmp.Diff(before, in.Bytes(), optBytesToStrings()); diff != "" {
+		t.Errorf("config changed after failed edits (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetAndUnsetPath(t *testing.T) {
+	in, want := parseConfigTxtarTest(t, `
+-- in --
+{
+  "packages": {"go": {"version": "1.20", "platforms": ["x86_64-linux"]}},
+  "shell": {
+    "init_hook": [
+      "echo one",
+      // Comment
+      "echo two"
+    ]
+  }
+}
+-- want --
+{
+  "packages": {"go": {"version": "1.20"}},
+  "shell": {
+    "init_hook": [
+      // Comment
+      "echo two",
+    ],
+  },
+}
+`)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"shell.init_hook", `["echo one","echo two"]`},
+		{"shell.init_hook[1]", `"echo two"`},
+		{"packages.go.version", `"1.20"`},
+		{"packages.go.platforms", `["x86_64-linux"]`},
+	}
+	for _, tt := range tests {
+		got, ok, err := in.GetPath(tt.path)
+		if err != nil || !ok {
+			t.Errorf("GetPath(%q) = %s, %v, %v", tt.path, got, ok, err)
+		} else if string(got) != tt.want {
+			t.Errorf("GetPath(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+	if _, ok, err := in.GetPath("env.MISSING"); ok || err != nil {
+		t.Errorf("GetPath(env.MISSING) = %v, %v, want false, nil", ok, err)
+	}
+
+	for _, path := range []string{"shell.init_hook[0]", "packages.go.platforms"} {
+		if ok, err := in.UnsetPath(path); !ok || err != nil {
+			t.Errorf("UnsetPath(%q) = %v, %v, want true, nil", path, ok, err)
+		}
+	}
+	if ok, err := in.UnsetPath("env.MISSING"); ok || err != nil {
+		t.Errorf("UnsetPath(env.MISSING) = %v, %v, want false, nil", ok, err)
+	}
+	if diff := cmp.Diff(want, in.Bytes(), optBytesToStrings()); diff != "" {
+		t.Errorf("wrong raw config hujson (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,286 @@
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.jetpack.io/devbox/internal/devbox/shellcmd"
+	"go.jetpack.io/devbox/internal/nix"
+)
+
+// JSONSchema is the subset of a JSON Schema (draft-04) needed to describe
+// devbox.json. The schema is generated from the ConfigFile type, and Validate
+// uses the same schema, so the two can't disagree about which fields exist.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 schemaType             `json:"type,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	PatternProperties    map[string]*JSONSchema `json:"patternProperties,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+
+	// check and checkKey validate the meaning of a string value or object
+	// key beyond what JSON Schema can express, such as whether a string is
+	// a valid package reference. They aren't part of the generated schema.
+	check    func(string) error
+	checkKey func(string) error
+}
+
+// schemaType is a JSON Schema type keyword. It marshals to a string if it has
+// a single type and an array otherwise.
+type schemaType []string
+
+func (t schemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// schemaDescriptions describes each field in devbox.json. Keys are paths
+// where "*" stands for any object key and "[]" for any array element.
+var schemaDescriptions = map[string]string{
+	"$schema":                           "The schema version of this devbox.json file.",
+	"name":                              "The name of the project or plugin.",
+	"description":                       "A description of the project or plugin.",
+	"packages":                          "Collection of packages to install.",
+	"packages[]":                        "Name and version of each package in name@version format, or a flake reference.",
+	"packages.*":                        "Name of each package in {\"name\": {\"version\": \"1.2.3\"}} format.",
+	"packages.*.version":                "Version of the package.",
+	"packages.*.disable_plugin":         "Whether to skip the built-in plugin for this package.",
+	"packages.*.platforms":              "Names of platforms to install the package on. This package will be skipped for any platforms not on this list.",
+	"packages.*.excluded_platforms":     "Names of platforms to exclude the package on.",
+	"packages.*.patch_glibc":            "Whether to patch the package's binaries to use the latest available version of glibc.",
+	"packages.*.outputs":                "Outputs of the package to install. Defaults to the package's default outputs.",
+	"packages.*.allow_insecure":         "Packages that nixpkgs marks as insecure but are allowed to be installed.",
+	"packages.*.groups":                 "Package groups that the package belongs to. Packages without groups are in the default group, which is installed unless a command selects groups with --only.",
+	"packages.*.override":               "Customizes the package's Nix derivation.",
+	"packages.*.override.with_packages": "Packages from the language's package set to add with the package's withPackages function, such as numpy for python.",
+	"packages.*.override.src":           "Source code that replaces the package's source.",
+	"packages.*.override.src.url":       "URL of a source archive or file.",
+	"packages.*.override.src.hash":      "SRI hash of the file at url, such as sha256-....",
+	"packages.*.override.src.path":      "Local path to the source code, relative to the project directory.",
+	"packages.*.override.patches":       "Paths to patch files to apply after the package's own patches, relative to the project directory.",
+	"packages.*.override.attrs":         "Derivation attributes to set with overrideAttrs.",
+	"packages.*.override.attrs.*":       "Nix expression of the attribute's value. Strings must be quoted.",
+	"env":                               "List of additional environment variables to be set in the Devbox environment. Values containing $PATH or $PWD will be expanded. No other variable expansion or command substitution will occur.",
+	"env.*":                             "Value of the environment variable.",
+	"env_from":                          "Where to load additional environment variables from. Only \"envsec\" is supported.",
+	"shell":                             "Definitions of scripts and actions to take when in devbox shell.",
+	"shell.init_hook":                   "List of shell commands/scripts to run right after devbox shell starts.",
+	"shell.scripts":                     "List of command/script definitions to run with `devbox run <script_name>`.",
+	"shell.scripts.*":                   "The script's shell commands, or an object with the commands and the package groups that the script needs.",
+	"shell.scripts.*.command":           "The script's shell commands.",
+	"shell.scripts.*.groups":            "Package groups to install in addition to the selected groups when the script runs.",
+	"nixpkgs":                           "Deprecated: versioned packages don't need a nixpkgs commit.",
+	"nixpkgs.commit":                    "The nixpkgs commit to install unversioned packages from.",
+	"include":                           "List of plugins and devbox.json fragments to merge into the project's config.",
+	"include[]":                         "Reference to a plugin, or the URL or git repository of a devbox.json fragment.",
+	"license_policy":                    "Restricts the licenses of the packages in your project. Checked by devbox add, devbox install and devbox licenses.",
+	"license_policy.allow":              "SPDX IDs of allowed licenses. If set, every license of every package must match an entry. Entries may use wildcards such as BSD-*.",
+	"license_policy.deny":               "SPDX IDs of licenses that aren't allowed. Takes precedence over allow. Entries may use wildcards such as AGPL-*.",
+	"license_policy.allow_unfree":       "Whether packages that nixpkgs considers unfree are allowed. Defaults to true.",
+	"derivations":                       "Packages to build from source and install. The keys are the package names.",
+	"derivations.*":                     "A package that's built with the standard environment's mkDerivation.",
+	"derivations.*.version":             "Version of the package.",
+	"derivations.*.src":                 "Source code of the package. If it isn't set, the package has no source to unpack.",
+	"derivations.*.src.url":             "URL of a source archive or file.",
+	"derivations.*.src.hash":            "SRI hash of the file at url, such as sha256-....",
+	"derivations.*.src.path":            "Local path to the source code, relative to the project directory.",
+	"derivations.*.build_inputs":        "Attribute names of nixpkgs packages that the package depends on.",
+	"derivations.*.native_build_inputs": "Attribute names of nixpkgs packages that are needed to build the package, such as pkg-config.",
+	"derivations.*.build":               "Shell commands of the build phase.",
+	"derivations.*.install":             "Shell commands of the install phase. They must put the package's files in $out.",
+	"binary_caches":                     "Binary caches (substituters) that nix uses in addition to cache.nixos.org. Nix must trust them, for example with trusted-substituters or trusted-users.",
+	"binary_caches[]":                   "A binary cache.",
+	"binary_caches[].url":               "URL of the binary cache, such as https://cache.example.com or s3://bucket.",
+	"binary_caches[].public_key":        "Public key that signs the cache's paths, in <name>:<key> format.",
+	"binary_caches[].upload":            "Whether devbox cache upload uploads to this cache by default.",
+	"binary_caches[].secret_key_file":   "Path to the secret key that devbox cache upload signs paths with. A leading ~ is expanded to the home directory.",
+	"binary_caches[].priority":          "Priority of the cache. Caches with a lower value are used first. Defaults to 50. cache.nixos.org has priority 40.",
+	"platforms":                         "Systems that the project must work on, such as x86_64-linux and aarch64-darwin. devbox lock records the packages' outputs for each of them, and devbox lock --check-platforms fails if one is missing.",
+	"platforms[]":                       "A Nix system.",
+}
+
+// Schema returns the JSON schema for devbox.json.
+func Schema() *JSONSchema {
+	s := schemaForType(reflect.TypeOf(ConfigFile{}), "")
+	s.Schema = "http://json-schema.org/draft-04/schema#"
+	s.ID = "https://github.com/jetify-com/devbox"
+	s.Title = "Devbox json definition"
+	s.Description = "Defines fields and acceptable values of devbox.json"
+	s.Properties["$schema"] = &JSONSchema{
+		Description: schemaDescriptions["$schema"],
+		Type:        schemaType{"string"},
+	}
+	s.Properties["include"].Items.check = checkInclude
+	return s
+}
+
+// SchemaJSON returns the JSON schema for devbox.json formatted the same way
+// as .schema/devbox.schema.json in the devbox repository.
+func SchemaJSON() []byte {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(Schema()); err != nil {
+		panic("devbox.json schema can't be marshalled: " + err.Error())
+	}
+	return buf.Bytes()
+}
+
+var (
+	commandsType   = reflect.TypeOf(&shellcmd.Commands{})
+	packagesType   = reflect.TypeOf(PackagesMutator{})
+	scriptType     = reflect.TypeOf(&ScriptConfig{})
+	platformFields = map[string]bool{
+		"packages.*.platforms":          true,
+		"packages.*.excluded_platforms": true,
+		"platforms":                     true,
+	}
+	groupFields = map[string]bool{
+		"packages.*.groups":      true,
+		"shell.scripts.*.groups": true,
+	}
+	nixAttrFields = map[string]bool{
+		"packages.*.override.with_packages": true,
+		"derivations.*.build_inputs":        true,
+		"derivations.*.native_build_inputs": true,
+	}
+	keyChecks = map[string]func(string) error{
+		"packages.*.override.attrs": checkNixAttr,
+		"derivations":               checkDerivationName,
+	}
+)
+
+// schemaForType generates the schema for a Go type at a path in devbox.json.
+// Struct fields without a json tag, or with the tag "-", are skipped.
+func schemaForType(t reflect.Type, path string) *JSONSchema {
+	s := &JSONSchema{Description: schemaDescriptions[path]}
+	switch {
+	case t == commandsType:
+		// shellcmd.Commands is either a single string or an array of
+		// strings.
+		s.Type = schemaType{"array", "string"}
+		s.Items = &JSONSchema{Type: schemaType{"string"}}
+		return s
+	case t == packagesType:
+		return packagesSchema(s)
+	case t == scriptType:
+		return scriptSchema(s, path)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaForType(t.Elem(), path)
+	case reflect.String:
+		s.Type = schemaType{"string"}
+	case reflect.Bool:
+		s.Type = schemaType{"boolean"}
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		s.Type = schemaType{"number"}
+	case reflect.Slice:
+		s.Type = schemaType{"array"}
+		s.Items = schemaForType(t.Elem(), path+"[]")
+		if platformFields[path] {
+			s.Items.Enum = nix.Platforms()
+		}
+		if groupFields[path] {
+			s.Items.check = checkGroupName
+		}
+		if nixAttrFields[path] {
+			s.Items.check = checkNixAttr
+		}
+	case reflect.Map:
+		s.Type = schemaType{"object"}
+		s.PatternProperties = map[string]*JSONSchema{
+			".*": schemaForType(t.Elem(), joinSchemaPath(path, "*")),
+		}
+		s.checkKey = keyChecks[path]
+	case reflect.Struct:
+		s.Type = schemaType{"object"}
+		s.Properties = map[string]*JSONSchema{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			s.Properties[name] = schemaForType(field.Type, joinSchemaPath(path, name))
+		}
+		s.AdditionalProperties = new(bool)
+	default:
+		panic(fmt.Sprintf("no JSON schema for type %s at %q", t, path))
+	}
+	return s
+}
+
+// packagesSchema returns the schema for the packages field, which is either
+// an array of package strings or an object that maps package strings to a
+// version or a package object.
+func packagesSchema(s *JSONSchema) *JSONSchema {
+	pkg := schemaForType(reflect.TypeOf(Package{}), "packages.*")
+	pkg.Description = ""
+	s.OneOf = []*JSONSchema{
+		{
+			Type: schemaType{"array"},
+			Items: &JSONSchema{
+				Description: schemaDescriptions["packages[]"],
+				Type:        schemaType{"string"},
+				check:       checkPackageString,
+			},
+		},
+		{
+			Description: schemaDescriptions["packages.*"],
+			Type:        schemaType{"object"},
+			PatternProperties: map[string]*JSONSchema{
+				".*": {
+					OneOf: []*JSONSchema{
+						pkg,
+						{
+							Description: "Version of the package to install.",
+							Type:        schemaType{"string"},
+						},
+					},
+				},
+			},
+			checkKey: checkPackageString,
+		},
+	}
+	return s
+}
+
+// scriptSchema returns the schema for a script, which is either its commands
+// or an object with its commands and package groups.
+func scriptSchema(s *JSONSchema, path string) *JSONSchema {
+	commands := schemaForType(commandsType, path)
+	commands.Description = ""
+	s.OneOf = []*JSONSchema{
+		commands,
+		{
+			Type: schemaType{"object"},
+			Properties: map[string]*JSONSchema{
+				"command": schemaForType(commandsType, joinSchemaPath(path, "command")),
+				"groups":  schemaForType(reflect.TypeOf([]string{}), joinSchemaPath(path, "groups")),
+			},
+			AdditionalProperties: new(bool),
+		},
+	}
+	return s
+}
+
+func joinSchemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
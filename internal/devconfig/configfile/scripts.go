@@ -9,6 +9,9 @@ import (
 type script struct {
 	shellcmd.Commands
 	Comments string
+
+	// Groups are the package groups that the script needs.
+	Groups []string
 }
 
 type Scripts map[string]*script
@@ -24,8 +27,9 @@ func (c *ConfigFile) Scripts() Scripts {
 			comments = string(c.ast.beforeComment("shell", "scripts", name))
 		}
 		result[name] = &script{
-			Commands: *commands,
+			Commands: commands.Commands,
 			Comments: comments,
+			Groups:   commands.Groups,
 		}
 	}
 
@@ -45,6 +49,7 @@ func (s Scripts) WithRelativePaths(projectDir string) Scripts {
 		result[name] = &script{
 			Commands: commandsWithRelativePaths,
 			Comments: s.Comments,
+			Groups:   s.Groups,
 		}
 	}
 	return result
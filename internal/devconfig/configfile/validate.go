@@ -0,0 +1,280 @@
+package configfile
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/tailscale/hujson"
+	"go.jetpack.io/devbox/internal/devpkg/pkgtype"
+	"go.jetpack.io/devbox/nix/flake"
+)
+
+// Problem is an issue with a devbox.json, such as an unknown field or a value
+// of the wrong type.
+type Problem struct {
+	// Line and Column are the 1-based position of the problem.
+	Line   int `json:"line"`
+	Column int `json:"column"`
+
+	// Path is the path to the value with the problem, in the same syntax as
+	// GetPath. It's empty when the problem is with the top-level object.
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+
+	// wrongType is true when the value has a JSON type that the config
+	// can't be unmarshalled from.
+	wrongType bool
+}
+
+func (p Problem) String() string {
+	if p.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", p.Line, p.Column, p.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", p.Line, p.Column, p.Path, p.Message)
+}
+
+// Validate checks the contents of a devbox.json against its schema. Unlike
+// LoadBytes, it reports every unknown field, value of the wrong type, invalid
+// platform, package and include, along with its position in the file. It
+// only returns an error if b isn't valid JSON with comments.
+func Validate(b []byte) ([]Problem, error) {
+	root, err := hujson.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	v := validator{src: b}
+	v.validate(&root, Schema(), []any{})
+	return v.problems, nil
+}
+
+// Validate checks the config against its schema. See the package-level
+// Validate function.
+func (c *ConfigFile) Validate() []Problem {
+	// Pack returns the original bytes when the config hasn't been edited,
+	// so the problem positions match the file on disk.
+	problems, _ := Validate(c.ast.root.Pack())
+	return problems
+}
+
+type validator struct {
+	src      []byte
+	problems []Problem
+}
+
+func (v *validator) report(offset int, path []any, wrongType bool, format string, a ...any) {
+	line := 1 + bytes.Count(v.src[:offset], []byte("\n"))
+	column := 1 + offset - (bytes.LastIndexByte(v.src[:offset], '\n') + 1)
+	problem := Problem{
+		Line:      line,
+		Column:    column,
+		Message:   fmt.Sprintf(format, a...),
+		wrongType: wrongType,
+	}
+	if len(path) > 0 {
+		problem.Path = formatPath(path)
+	}
+	v.problems = append(v.problems, problem)
+}
+
+func (v *validator) validate(val *hujson.Value, s *JSONSchema, path []any) {
+	// A null is the same as leaving the field out.
+	if jsonType(val) == "null" {
+		return
+	}
+	if len(s.OneOf) > 0 {
+		for _, alt := range s.OneOf {
+			if slices.Contains(alt.Type, jsonType(val)) {
+				v.validate(val, alt, path)
+				return
+			}
+		}
+		want := []string{}
+		for _, alt := range s.OneOf {
+			want = append(want, alt.Type...)
+		}
+		v.report(val.StartOffset, path, true, "expected %s, got %s", orList(want), jsonType(val))
+		return
+	}
+	if !slices.Contains(s.Type, jsonType(val)) {
+		v.report(val.StartOffset, path, true, "expected %s, got %s", orList(s.Type), jsonType(val))
+		return
+	}
+
+	switch value := val.Value.(type) {
+	case *hujson.Object:
+		v.validateObject(value, s, path)
+	case *hujson.Array:
+		for i := range value.Elements {
+			v.validate(&value.Elements[i], s.Items, append(slices.Clip(path), i))
+		}
+	case hujson.Literal:
+		if value.Kind() != '"' {
+			return
+		}
+		str := value.String()
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			v.report(val.StartOffset, path, false, "invalid value %q, must be one of: %s",
+				str, strings.Join(s.Enum, ", "))
+		}
+		if s.check != nil {
+			if err := s.check(str); err != nil {
+				v.report(val.StartOffset, path, false, "%v", err)
+			}
+		}
+	}
+}
+
+func (v *validator) validateObject(obj *hujson.Object, s *JSONSchema, path []any) {
+	for i := range obj.Members {
+		member := &obj.Members[i]
+		key := member.Name.Value.(hujson.Literal).String()
+		memberPath := append(slices.Clip(path), key)
+
+		if s.checkKey != nil {
+			if err := s.checkKey(key); err != nil {
+				v.report(member.Name.StartOffset, memberPath, false, "%v", err)
+			}
+		}
+		if prop, ok := s.Properties[key]; ok {
+			v.validate(&member.Value, prop, memberPath)
+			continue
+		}
+		if prop, ok := s.PatternProperties[".*"]; ok {
+			v.validate(&member.Value, prop, memberPath)
+			continue
+		}
+		if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+			msg := "unknown field"
+			if suggestion := closestKey(key, s.Properties); suggestion != "" {
+				msg += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			v.report(member.Name.StartOffset, memberPath, false, msg)
+		}
+	}
+}
+
+// jsonType returns the JSON Schema type name of a value.
+func jsonType(val *hujson.Value) string {
+	switch value := val.Value.(type) {
+	case *hujson.Object:
+		return "object"
+	case *hujson.Array:
+		return "array"
+	case hujson.Literal:
+		switch value.Kind() {
+		case '"':
+			return "string"
+		case 't', 'f':
+			return "boolean"
+		case 'n':
+			return "null"
+		}
+	}
+	return "number"
+}
+
+func orList(items []string) string {
+	items = slices.Clone(items)
+	slices.Sort(items)
+	items = slices.Compact(items)
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " or " + items[len(items)-1]
+}
+
+// closestKey returns the known key that's most likely to be a typo of key,
+// or an empty string if none of them are close.
+func closestKey(key string, known map[string]*JSONSchema) string {
+	normalized := strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	best, bestDist := "", 3
+	for candidate := range known {
+		dist := editDistance(normalized, candidate)
+		if dist < bestDist || (dist == bestDist && candidate < best) {
+			best, bestDist = candidate, dist
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// checkPackageString returns an error if a package in devbox.json isn't a
+// devbox package ("name" or "name@version"), a runx package or a flake
+// installable.
+func checkPackageString(pkg string) error {
+	if pkg == "" {
+		return errors.New("package name is empty")
+	}
+	if strings.IndexFunc(pkg, unicode.IsSpace) != -1 {
+		return errors.Errorf("invalid package %q: package names can't contain whitespace", pkg)
+	}
+	if pkgtype.IsRunX(pkg) {
+		if strings.TrimPrefix(pkg, pkgtype.RunXPrefix) == "" {
+			return errors.Errorf("invalid package %q: missing a runx package name", pkg)
+		}
+		return nil
+	}
+	if looksLikeFlakeRef(pkg) {
+		if _, err := flake.ParseInstallable(pkg); err != nil {
+			return errors.Errorf("invalid package %q: %v", pkg, err)
+		}
+		return nil
+	}
+	if strings.HasPrefix(pkg, "@") {
+		return errors.Errorf("invalid package %q: missing a name before the version", pkg)
+	}
+	return nil
+}
+
+// checkInclude returns an error if an include can't be parsed.
+func checkInclude(include string) error {
+	if name, ok := strings.CutPrefix(include, "plugin:"); ok {
+		if name == "" {
+			return errors.Errorf("invalid include %q: missing a plugin name", include)
+		}
+		return nil
+	}
+	ref, err := flake.ParseRef(include)
+	if err != nil {
+		return errors.Errorf("invalid include %q: %v", include, err)
+	}
+	// Remote devbox.json fragments are fetched from a URL or git repository.
+	if ref.Type == flake.TypeFile && strings.HasPrefix(include, "http") {
+		return nil
+	}
+	if ref.Type != flake.TypePath && ref.Type != flake.TypeGitHub && ref.Type != flake.TypeGit {
+		return errors.Errorf("invalid include %q: unsupported ref type %q", include, ref.Type)
+	}
+	return nil
+}
+
+// looksLikeFlakeRef reports whether a package string is meant to be a flake
+// reference rather than a devbox package name.
+func looksLikeFlakeRef(pkg string) bool {
+	return strings.Contains(pkg, ":") || strings.Contains(pkg, "#") ||
+		strings.HasPrefix(pkg, ".") || strings.HasPrefix(pkg, "/")
+}
@@ -0,0 +1,93 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidate(t *testing.T) {
+	in := []byte(`{
+  "packages": {
+    "go": {"version": "1.22", "platforms": ["x86_64-linux", "x86-linux"]},
+    " bad": "",
+    "github:": ""
+  },
+  "scrips": {},
+  "env": {"PORT": 8080},
+  "shell": {
+    // Typo
+    "init-hook": ["echo hi"],
+    "scripts": {"test": true}
+  },
+  "include": ["plugin:", "path:./plugin.json"]
+}`)
+	got, err := Validate(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`3:61: packages.go.platforms[1]: invalid value "x86-linux", must be one of: aarch64-darwin, aarch64-linux, i686-linux, x86_64-darwin, x86_64-linux, armv7l-linux`,
+		`4:5: packages. bad: invalid package " bad": package names can't contain whitespace`,
+		`5:5: packages.github:: invalid package "github:": github flake reference is missing an owner or repo`,
+		`7:3: scrips: unknown field`,
+		`8:19: env.PORT: expected string, got number`,
+		`11:5: shell.init-hook: unknown field, did you mean "init_hook"?`,
+		`12:25: shell.scripts.test: expected array, object or string, got boolean`,
+		`14:15: include[0]: invalid include "plugin:": missing a plugin name`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(got), len(want), got)
+	}
+	for i := range want {
+		if gotStr := got[i].String(); len(gotStr) < len(want[i]) || gotStr[:len(want[i])] != want[i] {
+			t.Errorf("got problem %d = %q, want prefix %q", i, gotStr, want[i])
+		}
+	}
+}
+
+func TestValidateDefaultFormats(t *testing.T) {
+	for _, in := range []string{
+		`{"packages": ["go@1.22", "github:numtide/flake-utils", "path:../flake#pkg", "runx:mvdan/gofumpt@latest"]}`,
+		`{"packages": {"go": "1.22", "hello": {"version": "latest", "outputs": ["out"]}}, "shell": {"init_hook": "echo"}}`,
+		`{"$schema": "https://example.com", "packages": null, "nixpkgs": {"commit": "abc"}}`,
+		`{"include": ["https://example.com/devbox.json", "git+https://github.com/org/repo?ref=main&dir=base"]}`,
+	} {
+		got, err := Validate([]byte(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("Validate(%s) = %v, want no problems", in, got)
+		}
+	}
+}
+
+func TestLoadBytesWrongTypePosition(t *testing.T) {
+	_, err := LoadBytes([]byte("{\n  \"env\": {\"PORT\": 8080}\n}"))
+	if err == nil {
+		t.Fatal("got nil error for a number env value")
+	}
+	if want := "invalid config at 2:19: env.PORT: expected string, got number"; err.Error() != want {
+		t.Errorf("got error %q, want %q", err, want)
+	}
+}
+
+// TestSchemaUpToDate checks that the JSON schema in the repository matches
+// the one generated from the config types. Run
+//
+//	devbox config schema > .schema/devbox.schema.json
+//
+// to update it.
+func TestSchemaUpToDate(t *testing.T) {
+	path := filepath.Join("..", "..", "..", ".schema", "devbox.schema.json")
+	committed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(committed), string(SchemaJSON())); diff != "" {
+		t.Errorf("%s is out of date (-committed +generated):\n%s", path, diff)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package devconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+	"go.jetpack.io/devbox/internal/lock"
+	"go.jetpack.io/devbox/internal/xdg"
+	"go.jetpack.io/devbox/nix/flake"
+)
+
+// includeFetchTimeout limits how long fetching a remote include can take.
+const includeFetchTimeout = 2 * time.Minute
+
+// IsRemoteInclude reports whether an include is a devbox.json fragment that's
+// fetched from a URL (https://...) or a git repository (git+https://...,
+// git+ssh://..., git+file://...), as opposed to a plugin.
+func IsRemoteInclude(include string) bool {
+	return strings.HasPrefix(include, "https://") ||
+		strings.HasPrefix(include, "http://") ||
+		strings.HasPrefix(include, "git+")
+}
+
+// loadRemoteInclude loads a remote devbox.json fragment. If the lockfile has
+// the include, its contents are read from the cache by hash, or fetched from
+// the locked URL or commit if they aren't cached. Otherwise, the include is
+// fetched and added to the lockfile.
+func loadRemoteInclude(include string, lockfile *lock.File) (*Config, error) {
+	locked := lockfile.GetInclude(include)
+
+	var content []byte
+	if locked != nil {
+		content = readCachedInclude(locked.Hash)
+	}
+	if content == nil {
+		source := include
+		if locked != nil {
+			source = locked.Resolved
+		}
+		fetched, resolved, err := fetchInclude(source)
+		if err != nil {
+			return nil, usererr.WithUserMessage(err, "Unable to fetch include %s.", include)
+		}
+
+		hash := includeHash(fetched)
+		if locked != nil && hash != locked.Hash {
+			return nil, usererr.New(
+				"The contents of include %s changed since they were locked in devbox.lock "+
+					"(%s, now %s). Run `devbox update` to use the new contents.",
+				include, locked.Hash, hash,
+			)
+		}
+		// Caching is best effort. The include is fetched again if it
+		// isn't cached.
+		_ = writeCachedInclude(hash, fetched)
+		if locked == nil {
+			lockfile.SetInclude(include, &lock.Include{Resolved: resolved, Hash: hash})
+		}
+		content = fetched
+	}
+
+	cfg, err := loadBytes(content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "include %s", include)
+	}
+	cfg.remoteInclude = include
+	return cfg, nil
+}
+
+// fetchInclude fetches a remote include. It returns the contents and the
+// source to lock, which pins git includes to a commit.
+func fetchInclude(include string) (content []byte, resolved string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), includeFetchTimeout)
+	defer cancel()
+
+	if !strings.HasPrefix(include, "git+") {
+		content, err := fetchURL(ctx, include)
+		return content, include, err
+	}
+
+	ref, err := flake.ParseRef(include)
+	if err != nil {
+		return nil, "", err
+	}
+	return fetchGit(ctx, ref)
+}
+
+func fetchURL(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("GET %s: %s", url, res.Status)
+	}
+	data, err := io.ReadAll(res.Body)
+	return data, errors.WithStack(err)
+}
+
+// fetchGit reads devbox.json from the directory given by the ref's dir
+// parameter in a git repository. It fetches only the commit that it needs:
+// the locked rev if there is one, or the head of the ref or default branch.
+func fetchGit(ctx context.Context, ref flake.Ref) (content []byte, resolved string, err error) {
+	// The URL keeps query parameters other than ref and rev, such as
+	// dir, which aren't part of the repository's URL.
+	repoURL, err := url.Parse(ref.URL)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	repoURL.RawQuery = ""
+
+	tmp, err := os.MkdirTemp("", "devbox-include")
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	git := func(args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", tmp}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, errors.Errorf("git %s: %v: %s",
+				strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+		}
+		return out, nil
+	}
+
+	want := ref.Rev
+	if want == "" {
+		want = ref.Ref
+	}
+	if want == "" {
+		want = "HEAD"
+	}
+	if _, err := git("init", "--quiet"); err != nil {
+		return nil, "", err
+	}
+	if _, err := git("fetch", "--quiet", "--depth", "1", repoURL.String(), want); err != nil {
+		return nil, "", err
+	}
+	rev, err := git("rev-parse", "FETCH_HEAD")
+	if err != nil {
+		return nil, "", err
+	}
+	content, err = git("show", "FETCH_HEAD:"+path.Join(ref.Dir, configfile.DefaultName))
+	if err != nil {
+		return nil, "", err
+	}
+
+	ref.Rev = strings.TrimSpace(string(rev))
+	return content, ref.String(), nil
+}
+
+// includeHash returns the SRI hash of an include's contents.
+func includeHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// includeCachePath returns the path of a cached include. Includes are cached
+// by hash so that a locked include can be loaded without fetching it.
+func includeCachePath(hash string) (string, bool) {
+	b64, ok := strings.CutPrefix(hash, "sha256-")
+	if !ok {
+		return "", false
+	}
+	sum, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(xdg.CacheSubpath("devbox/includes"), hex.EncodeToString(sum)+".json"), true
+}
+
+// readCachedInclude returns the cached contents of an include, or nil if it
+// isn't cached or the cached contents don't match the hash.
+func readCachedInclude(hash string) []byte {
+	path, ok := includeCachePath(hash)
+	if !ok {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || includeHash(content) != hash {
+		return nil
+	}
+	return content
+}
+
+func writeCachedInclude(hash string, content []byte) error {
+	path, ok := includeCachePath(hash)
+	if !ok {
+		return errors.Errorf("invalid include hash %q", hash)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(path, content, 0o644))
+}
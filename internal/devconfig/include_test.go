@@ -0,0 +1,188 @@
+package devconfig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.jetpack.io/devbox/internal/lock"
+	"go.jetpack.io/devbox/internal/nix"
+)
+
+type testProject struct{ dir string }
+
+func (p testProject) BinaryCaches() []nix.BinaryCache                          { return nil }
+func (p testProject) ConfigHash() (string, error)                              { return "", nil }
+func (p testProject) NixPkgsCommitHash() string                                { return "" }
+func (p testProject) AllPackageNamesIncludingRemovedTriggerPackages() []string { return nil }
+func (p testProject) ProjectDir() string                                       { return p.dir }
+
+func loadTestConfig(t *testing.T, dir, config string, lockfile *lock.File) (*Config, error) {
+	t.Helper()
+	cfg, err := loadBytes([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Root.AbsRootPath = filepath.Join(dir, "devbox.json")
+	return cfg, cfg.LoadRecursive(lockfile)
+}
+
+func newTestLockfile(t *testing.T, dir string) *lock.File {
+	t.Helper()
+	lockfile, err := lock.GetFile(testProject{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lockfile
+}
+
+func TestRemoteInclude(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	fragment := `{
+		"packages": ["ripgrep@latest", "go@1.21"],
+		"env": {"A": "include", "B": "include"},
+		"shell": {
+			"init_hook": ["echo include"],
+			"scripts": {"test": "echo include", "build": "echo include"}
+		}
+	}`
+	requests := atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/base.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(fragment))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	include := server.URL + "/base.json"
+	project := `{
+		"packages": ["go@1.22"],
+		"env": {"A": "project"},
+		"shell": {
+			"init_hook": ["echo project"],
+			"scripts": {"test": "echo project"}
+		},
+		"include": ["` + include + `"]
+	}`
+	lockfile := newTestLockfile(t, dir)
+	cfg, err := loadTestConfig(t, dir, project, lockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The project config overrides the include.
+	got := []string{}
+	for _, pkg := range cfg.Packages(false) {
+		got = append(got, pkg.VersionedName())
+	}
+	if want := "ripgrep@latest go@1.22"; strings.Join(got, " ") != want {
+		t.Errorf("got packages %q, want %q", got, want)
+	}
+	if env := cfg.Env(); env["A"] != "project" || env["B"] != "include" {
+		t.Errorf("got env %v, want A=project and B=include", env)
+	}
+	scripts := cfg.Scripts()
+	if got := scripts["test"].Cmds; len(got) != 1 || got[0] != "echo project" {
+		t.Errorf("got test script %q, want the project's", got)
+	}
+	if got := scripts["build"].Cmds; len(got) != 1 || got[0] != "echo include" {
+		t.Errorf("got build script %q, want the include's", got)
+	}
+	if got := cfg.InitHook().Cmds; strings.Join(got, "; ") != "echo include; echo project" {
+		t.Errorf("got init hook %q, want the include's first", got)
+	}
+
+	locked := lockfile.GetInclude(include)
+	if locked == nil || locked.Resolved != include || locked.Hash != includeHash([]byte(fragment)) {
+		t.Fatalf("got locked include %+v, want it locked to the fragment's hash", locked)
+	}
+
+	// A locked include is read from the cache.
+	fragment = `{"env": {"B": "changed"}}`
+	if _, err := loadTestConfig(t, dir, project, lockfile); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("got %d requests, want 1 because the include is cached", got)
+	}
+
+	// Without the cache, the fetched include must match the lockfile.
+	if err := os.RemoveAll(os.Getenv("XDG_CACHE_HOME")); err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadTestConfig(t, dir, project, lockfile)
+	if err == nil || !strings.Contains(err.Error(), "changed since they were locked") {
+		t.Errorf("got error %v, want an error about the changed include", err)
+	}
+
+	// Removing the include removes it from the lockfile.
+	if _, err := loadTestConfig(t, dir, `{"packages": ["go@1.22"]}`, lockfile); err != nil {
+		t.Fatal(err)
+	}
+	if lockfile.GetInclude(include) != nil {
+		t.Error("got a locked include after it was removed from the config")
+	}
+}
+
+func TestRemoteIncludeNotFound(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	dir := t.TempDir()
+	_, err := loadTestConfig(t, dir, `{"include": ["`+server.URL+`/missing.json"]}`, newTestLockfile(t, dir))
+	if err == nil {
+		t.Error("got nil error for an include that doesn't exist")
+	}
+}
+
+func TestGitInclude(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, "base"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(filepath.Join(repo, "base", "devbox.json"), []byte(`{"env": {"FROM": "git"}}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "base"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	dir := t.TempDir()
+	include := "git+file://" + repo + "?dir=base"
+	lockfile := newTestLockfile(t, dir)
+	cfg, err := loadTestConfig(t, dir, `{"include": ["`+include+`"]}`, lockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Env()["FROM"]; got != "git" {
+		t.Errorf("got FROM=%q, want git", got)
+	}
+	locked := lockfile.GetInclude(include)
+	if locked == nil || !strings.Contains(locked.Resolved, "rev=") {
+		t.Errorf("got locked include %+v, want it pinned to a rev", locked)
+	}
+}
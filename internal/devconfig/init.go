@@ -8,10 +8,19 @@ import (
 	"os"
 	"path/filepath"
 
+	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/devconfig/configfile"
 )
 
+// Init creates a devbox.json with the default config in dir. It returns
+// false if dir already has a devbox.json.
 func Init(dir string) (created bool, err error) {
+	return InitWithConfig(dir, DefaultConfig())
+}
+
+// InitWithConfig creates a devbox.json with the given config in dir. It
+// returns false if dir already has a devbox.json.
+func InitWithConfig(dir string, cfg *Config) (created bool, err error) {
 	file, err := os.OpenFile(
 		filepath.Join(dir, configfile.DefaultName),
 		os.O_RDWR|os.O_CREATE|os.O_EXCL,
@@ -29,7 +38,7 @@ func Init(dir string) (created bool, err error) {
 		}
 	}()
 
-	_, err = file.Write(DefaultConfig().Root.Bytes())
+	_, err = file.Write(cfg.Root.Bytes())
 	if err != nil {
 		file.Close()
 		return false, err
@@ -41,6 +50,7 @@ func Init(dir string) (created bool, err error) {
 }
 
 func Open(projectDir string) (*Config, error) {
+	defer debug.FunctionTimer().End()
 	cfgPath := filepath.Join(projectDir, configfile.DefaultName)
 	return readFromFile(cfgPath)
 }
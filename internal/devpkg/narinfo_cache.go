@@ -11,17 +11,17 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"go.jetpack.io/devbox/internal/boxcli/featureflag"
+	"go.jetpack.io/devbox/internal/cachehash"
 	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/lock"
 	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/xdg"
+	"go.jetpack.io/pkg/filecache"
 	"golang.org/x/sync/errgroup"
 )
 
-// BinaryCache is the store from which to fetch this package's binaries.
-// It is used as FromStore in builtins.fetchClosure.
-const BinaryCache = "https://cache.nixos.org"
-
 // useDefaultOutput is a special value for the outputName parameter of
 // fetchNarInfoStatusOnce, which indicates that the default outputs should be
 // used.
@@ -101,22 +101,70 @@ func FillNarInfoCache(ctx context.Context, packages ...*Package) error {
 }
 
 // narInfoStatusFnCache contains cached OnceValues functions that return cache
-// status for a package. In the future we can remove this cache by caching
-// package objects and ensuring packages are shared globally.
+// status for a package and the caches that it's looked up in. In the future we
+// can remove this cache by caching package objects and ensuring packages are
+// shared globally.
 var narInfoStatusFnCache = sync.Map{}
 
 // fetchNarInfoStatusOnce is like fetchNarInfoStatus, but will only ever run
 // once and cache the result.
 func (p *Package) fetchNarInfoStatusOnce(output string) (bool, error) {
-	type inCacheFunc func() (bool, error)
-	f, ok := narInfoStatusFnCache.Load(p.Raw)
+	caches, err := p.fetchNarInfoCachesOnce(output)
+	return len(caches) > 0, err
+}
+
+// fetchNarInfoCachesOnce returns the binary cache of each output, and caches
+// the result. It returns nil if any output isn't in a binary cache.
+func (p *Package) fetchNarInfoCachesOnce(output string) (map[string]string, error) {
+	type inCacheFunc func() (map[string]string, error)
+	caches := p.lockfile.BinaryCaches()
+	key := p.keyForOutput(output) + "^" + strings.Join(lo.Map(caches, func(c nix.BinaryCache, _ int) string {
+		return c.Substituter()
+	}), " ")
+	f, ok := narInfoStatusFnCache.Load(key)
 	if !ok {
-		f = inCacheFunc(sync.OnceValues(func() (bool, error) { return p.fetchNarInfoStatus(output) }))
-		f, _ = narInfoStatusFnCache.LoadOrStore(p.keyForOutput(output), f)
+		f = inCacheFunc(sync.OnceValues(func() (map[string]string, error) {
+			return p.fetchNarInfoStatus(output, caches)
+		}))
+		f, _ = narInfoStatusFnCache.LoadOrStore(key, f)
 	}
 	return f.(inCacheFunc)()
 }
 
+// BinaryCache returns the URL of the binary cache that has the first default
+// output of the package. It's used as fromStore in builtins.fetchClosure.
+func (p *Package) BinaryCache() (string, error) {
+	sysInfo, err := p.sysInfoIfExists()
+	if err != nil {
+		return "", err
+	} else if sysInfo == nil || len(sysInfo.DefaultOutputs()) == 0 {
+		return "", errors.Errorf("package %q has no outputs for this system", p.Raw)
+	}
+	caches, err := p.fetchNarInfoCachesOnce(useDefaultOutput)
+	if err != nil {
+		return "", err
+	}
+	cache, ok := caches[sysInfo.DefaultOutputs()[0].Name]
+	if !ok {
+		return "", errors.Errorf("package %q isn't in a binary cache", p.Raw)
+	}
+	return cache, nil
+}
+
+// BinaryCacheForOutput returns the URL of the binary cache that has the
+// output. It's used as fromStore in builtins.fetchClosure.
+func (p *Package) BinaryCacheForOutput(output string) (string, error) {
+	caches, err := p.fetchNarInfoCachesOnce(output)
+	if err != nil {
+		return "", err
+	}
+	cache, ok := caches[output]
+	if !ok {
+		return "", errors.Errorf("output %q of package %q isn't in a binary cache", output, p.Raw)
+	}
+	return cache, nil
+}
+
 func (p *Package) keyForOutput(output string) string {
 	if output == useDefaultOutput {
 		sysInfo, err := p.sysInfoIfExists()
@@ -135,18 +183,19 @@ func (p *Package) keyForOutput(output string) string {
 }
 
 // fetchNarInfoStatus fetches the cache status for the package. It returns
-// true if cache exists, false otherwise.
-// NOTE: This function always performs an HTTP request and should not be called
+// the URL of the binary cache that has each output, or nil if any output isn't
+// in a binary cache. The caches are checked in the order they're given.
+// NOTE: This function always performs HTTP requests and should not be called
 // more than once per package.
 //
 // The outputName parameter is the name of the output to check for in the cache.
 // If outputName is UseDefaultOutput, the default outputs will be checked.
-func (p *Package) fetchNarInfoStatus(outputName string) (bool, error) {
+func (p *Package) fetchNarInfoStatus(outputName string, caches []nix.BinaryCache) (map[string]string, error) {
 	sysInfo, err := p.sysInfoIfExists()
 	if err != nil {
-		return false, err
+		return nil, err
 	} else if sysInfo == nil {
-		return false, errors.New(
+		return nil, errors.New(
 			"sysInfo is nil, but should not be because" +
 				" the package is eligible for binary cache",
 		)
@@ -158,38 +207,131 @@ func (p *Package) fetchNarInfoStatus(outputName string) (bool, error) {
 	} else {
 		out, err := sysInfo.Output(outputName)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
 		outputs = []lock.Output{out}
 	}
 
-	outputInCache := map[string]bool{} // key = output name, value = in cache
+	outputCaches := map[string]string{} // key = output name, value = cache URL
 	for _, output := range outputs {
-		pathParts := nix.NewStorePathParts(output.Path)
-		reqURL := BinaryCache + "/" + pathParts.Hash + ".narinfo"
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		req, err := http.NewRequestWithContext(ctx, http.MethodHead, reqURL, nil)
-		if err != nil {
-			return false, err
+		cacheURL := findNarInfo(caches, nix.NewStorePathParts(output.Path).Hash)
+		// If any output is not in the cache, then the package is deemed to be not in the cache.
+		if cacheURL == "" {
+			return nil, nil
 		}
-		res, err := http.DefaultClient.Do(req)
+		outputCaches[output.Name] = cacheURL
+	}
+	return outputCaches, nil
+}
+
+// findNarInfo returns the URL of the first cache that has the narinfo of a
+// store path hash, or "" if none of them has it. A cache that can't be
+// reached counts as a miss, so that one that's down doesn't stop the lookup.
+func findNarInfo(caches []nix.BinaryCache, hash string) string {
+	for _, cache := range caches {
+		// Only HTTP caches can be probed directly. Other caches,
+		// such as s3://, are still used as substituters.
+		if !cache.IsHTTP() {
+			continue
+		}
+		inCache, err := fetchNarInfo(cache.URL, hash)
 		if err != nil {
-			return false, err
+			debug.Log("failed to fetch narinfo of %s from %s: %v", hash, cache.URL, err)
+			continue
+		}
+		if inCache {
+			return cache.URL
 		}
-		// read the body fully, and close it to ensure the connection is reused.
-		_, _ = io.Copy(io.Discard, res.Body)
-		outputInCache[output.Name] = res.StatusCode == 200
-		res.Body.Close()
 	}
+	return ""
+}
 
-	// If any output is not in the cache, then the package is deemed to be not in the cache.
-	for _, inCache := range outputInCache {
-		if !inCache {
-			return false, nil
-		}
+// narInfoDiskCache persists the results of fetchNarInfo across devbox
+// invocations, so that packages aren't probed every time the environment is
+// recomputed.
+var narInfoDiskCache = filecache.New[bool](
+	"devbox/narinfo",
+	filecache.WithCacheDir[bool](xdg.CacheSubpath("")),
+)
+
+// A store path that's in a cache stays there, so hits are kept for as long as
+// possible. Misses expire quickly because the path may be pushed at any time.
+const (
+	narInfoHitTTL  = 365 * 24 * time.Hour
+	narInfoMissTTL = 15 * time.Minute
+)
+
+// unreachableCaches has the URLs of the binary caches that a narinfo request
+// failed to reach. They aren't requested again for the rest of the process,
+// so that a cache that's down only delays the first lookup.
+var unreachableCaches = sync.Map{}
+
+var errCacheUnreachable = errors.New("binary cache was unreachable earlier")
+
+// ClearNarInfoCache removes the binary cache lookups that are persisted on
+// disk.
+func ClearNarInfoCache() error {
+	narInfoStatusFnCache.Range(func(key, _ any) bool {
+		narInfoStatusFnCache.Delete(key)
+		return true
+	})
+	return errors.WithStack(narInfoDiskCache.Clear())
+}
+
+// narInfoCacheKey returns the key of a store path hash in the disk cache. The
+// cache URL is hashed so that the key is a valid file name.
+func narInfoCacheKey(cacheURL, hash string) string {
+	return cachehash.Bytes6([]byte(strings.TrimSuffix(cacheURL, "/"))) + "-" + hash
+}
+
+// fetchNarInfo returns true if a binary cache has the narinfo of a store path.
+// Results are read from and written to the disk cache. Once a request to the
+// cache fails, the cache is only looked up on disk.
+func fetchNarInfo(cacheURL, hash string) (bool, error) {
+	key := narInfoCacheKey(cacheURL, hash)
+	if inCache, err := narInfoDiskCache.Get(key); err == nil {
+		return inCache, nil
+	} else if !filecache.IsCacheMiss(err) {
+		debug.Log("failed to read narinfo cache for %s: %v", key, err)
+	}
+	if _, ok := unreachableCaches.Load(cacheURL); ok {
+		return false, errCacheUnreachable
 	}
-	return true, nil
+
+	inCache, err := fetchNarInfoFromCache(cacheURL, hash)
+	if err != nil {
+		unreachableCaches.Store(cacheURL, struct{}{})
+		return false, err
+	}
+	ttl := narInfoMissTTL
+	if inCache {
+		ttl = narInfoHitTTL
+	}
+	// A failure to persist the result only means that it's fetched again.
+	if err := narInfoDiskCache.Set(key, inCache, ttl); err != nil {
+		debug.Log("failed to write narinfo cache for %s: %v", key, err)
+	}
+	return inCache, nil
+}
+
+// fetchNarInfoFromCache requests the narinfo of a store path from a binary
+// cache.
+func fetchNarInfoFromCache(cacheURL, hash string) (bool, error) {
+	reqURL := strings.TrimSuffix(cacheURL, "/") + "/" + hash + ".narinfo"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, reqURL, nil)
+	if err != nil {
+		return false, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	// read the body fully, and close it to ensure the connection is reused.
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	return res.StatusCode == 200, nil
 }
 
 // isEligibleForBinaryCache returns true if we have additional metadata about
@@ -199,6 +341,10 @@ func (p *Package) isEligibleForBinaryCache() (bool, error) {
 	if p.PatchGlibc() {
 		return false, nil
 	}
+	// Neither are overridden packages.
+	if p.Override != nil {
+		return false, nil
+	}
 	sysInfo, err := p.sysInfoIfExists()
 	if err != nil {
 		return false, err
@@ -230,7 +376,7 @@ func (p *Package) sysInfoIfExists() (*lock.SystemInfo, error) {
 		return nil, err
 	}
 
-	entry, err := p.lockfile.Resolve(p.Raw)
+	entry, err := p.lockfile.Resolve(context.TODO(), p.Raw)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,66 @@
+package devpkg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"go.jetpack.io/devbox/internal/nix"
+)
+
+func TestNarInfoCacheKey(t *testing.T) {
+	const hash = "cvrn84c1hshv2wcds7n1rhydi6lacqns"
+	key := narInfoCacheKey("https://cache.nixos.org", hash)
+	if got := narInfoCacheKey("https://cache.nixos.org/", hash); got != key {
+		t.Errorf("got key %q with a trailing slash, want %q", got, key)
+	}
+	if got := narInfoCacheKey("https://cache.example.com", hash); got == key {
+		t.Errorf("got the same key %q for different caches", got)
+	}
+}
+
+func TestFindNarInfoSkipsUnreachableCache(t *testing.T) {
+	const hash = "cvrn84c1hshv2wcds7n1rhydi6lacqns"
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+hash+".narinfo" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer up.Close()
+
+	caches := []nix.BinaryCache{{URL: down.URL}, {URL: "s3://bucket"}, {URL: up.URL}}
+	if got := findNarInfo(caches, hash); got != up.URL {
+		t.Errorf("got cache %q, want %q", got, up.URL)
+	}
+	if got := findNarInfo(caches[:2], hash); got != "" {
+		t.Errorf("got cache %q without a reachable cache, want none", got)
+	}
+}
+
+func TestFindNarInfoRemembersUnreachableCache(t *testing.T) {
+	hits := atomic.Int32{}
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		// Close the connection without a response.
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+	defer down.Close()
+
+	caches := []nix.BinaryCache{{URL: down.URL}}
+	for _, hash := range []string{"cvrn84c1hshv2wcds7n1rhydi6lacqns", "0c5fkdqibkm1ibzpi4pl5jk6rc9dfbc7"} {
+		if got := findNarInfo(caches, hash); got != "" {
+			t.Errorf("got cache %q from an unreachable cache, want none", got)
+		}
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("got %d requests to the unreachable cache, want 1", got)
+	}
+}
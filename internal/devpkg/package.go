@@ -87,6 +87,10 @@ type Package struct {
 	// installed even if they are marked as insecure.
 	AllowInsecure []string
 
+	// Override customizes the package's Nix derivation. Its local paths
+	// are absolute.
+	Override *configfile.PackageOverride
+
 	// isInstallable is true if the package may be enabled on the current platform.
 	// It's a function to allow deferring nix System call until it's needed.
 	isInstallable func() bool
@@ -112,6 +116,7 @@ func PackagesFromConfig(packages []configfile.Package, l lock.Locker) []*Package
 		})
 		pkg.outputs.selectedNames = lo.Uniq(append(pkg.outputs.selectedNames, cfgPkg.Outputs...))
 		pkg.AllowInsecure = cfgPkg.AllowInsecure
+		pkg.Override = cfgPkg.Override.AbsPaths(l.ProjectDir())
 		result = append(result, pkg)
 	}
 	return result
@@ -159,7 +164,7 @@ func newPackage(raw string, isInstallable func() bool, locker lock.Locker) *Pack
 // resolve is the implementation of Package.resolve, where it is wrapped in a
 // sync.OnceValue function. It should not be called directly.
 func resolve(pkg *Package) error {
-	resolved, err := pkg.lockfile.Resolve(pkg.LockfileKey())
+	resolved, err := pkg.lockfile.Resolve(context.TODO(), pkg.LockfileKey())
 	if err != nil {
 		return err
 	}
@@ -558,7 +563,7 @@ func (p *Package) InputAddressedPaths() ([]string, error) {
 			errors.Errorf("Package %q cannot be fetched from binary cache store", p.Raw)
 	}
 
-	entry, err := p.lockfile.Resolve(p.LockfileKey())
+	entry, err := p.lockfile.Resolve(context.TODO(), p.LockfileKey())
 	if err != nil {
 		return nil, err
 	}
@@ -585,7 +590,7 @@ func (p *Package) InputAddressedPathForOutput(output string) (string, error) {
 			errors.Errorf("Package %q cannot be fetched from binary cache store", p.Raw)
 	}
 
-	entry, err := p.lockfile.Resolve(p.LockfileKey())
+	entry, err := p.lockfile.Resolve(context.TODO(), p.LockfileKey())
 	if err != nil {
 		return "", err
 	}
@@ -619,13 +624,24 @@ func (p *Package) StoreName() (string, error) {
 	return name, nil
 }
 
+// Meta evaluates the package's nixpkgs meta attributes, such as its licenses
+// and known vulnerabilities, without building it.
+func (p *Package) Meta(ctx context.Context) (*nix.PackageMeta, error) {
+	if err := p.resolve(); err != nil {
+		return nil, err
+	}
+	installable := p.installable
+	installable.Outputs = ""
+	return nix.EvalPackageMeta(ctx, installable.String())
+}
+
 func (p *Package) EnsureUninstallableIsInLockfile() error {
 	// TODO savil: Should !p.isInstallable() be the opposite i.e. p.IsInstallable()?
 	// TODO savil: Do we need the IsDevboxPackage check here?
 	if !p.IsInstallable() || !p.IsDevboxPackage {
 		return nil
 	}
-	_, err := p.lockfile.Resolve(p.LockfileKey())
+	_, err := p.lockfile.Resolve(context.TODO(), p.LockfileKey())
 	return err
 }
 
@@ -4,6 +4,7 @@
 package devpkg
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -108,6 +109,10 @@ func (l *lockfile) ProjectDir() string {
 	return l.projectDir
 }
 
+func (l *lockfile) BinaryCaches() []nix.BinaryCache {
+	return nix.BinaryCaches(nil)
+}
+
 func (l *lockfile) LegacyNixpkgsPath(pkg string) string {
 	return fmt.Sprintf(
 		"github:NixOS/nixpkgs/%s#%s",
@@ -120,7 +125,7 @@ func (l *lockfile) Get(pkg string) *lock.Package {
 	return nil
 }
 
-func (l *lockfile) Resolve(pkg string) (*lock.Package, error) {
+func (l *lockfile) Resolve(ctx context.Context, pkg string) (*lock.Package, error) {
 	switch {
 	case strings.Contains(pkg, "path:"):
 		return &lock.Package{Resolved: pkg}, nil
@@ -10,7 +10,7 @@ import (
 
 func (p *Package) ValidateExists(ctx context.Context) (bool, error) {
 	if p.IsRunX() {
-		_, err := p.lockfile.Resolve(p.Raw)
+		_, err := p.lockfile.Resolve(ctx, p.Raw)
 		return err == nil, err
 	}
 	if p.isVersioned() && p.version() == "" {
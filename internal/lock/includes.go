@@ -0,0 +1,37 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package lock
+
+import "github.com/samber/lo"
+
+// Include is a remote devbox.json fragment from a URL or git repository that
+// is pinned in the lockfile.
+type Include struct {
+	// Resolved is where the fragment was fetched from. For git includes, it
+	// has the commit in its rev parameter.
+	Resolved string `json:"resolved"`
+
+	// Hash is the SRI hash (sha256-<base64>) of the fragment's contents.
+	Hash string `json:"hash"`
+}
+
+// GetInclude returns the locked include for a reference in devbox.json, or
+// nil if it isn't locked.
+func (f *File) GetInclude(ref string) *Include {
+	return f.Includes[ref]
+}
+
+// SetInclude locks an include reference. It updates the in memory copy but
+// does not write to disk.
+func (f *File) SetInclude(ref string, include *Include) {
+	if f.Includes == nil {
+		f.Includes = map[string]*Include{}
+	}
+	f.Includes[ref] = include
+}
+
+// TidyIncludes removes the locked includes that aren't in refs.
+func (f *File) TidyIncludes(refs []string) {
+	f.Includes = lo.PickByKeys(f.Includes, refs)
+}
@@ -3,7 +3,14 @@
 
 package lock
 
+import (
+	"context"
+
+	"go.jetpack.io/devbox/internal/nix"
+)
+
 type devboxProject interface {
+	BinaryCaches() []nix.BinaryCache
 	ConfigHash() (string, error)
 	NixPkgsCommitHash() string
 	AllPackageNamesIncludingRemovedTriggerPackages() []string
@@ -11,8 +18,11 @@ type devboxProject interface {
 }
 
 type Locker interface {
+	// BinaryCaches returns the caches that the project's packages are
+	// looked up in, ordered by priority.
+	BinaryCaches() []nix.BinaryCache
 	Get(string) *Package
 	LegacyNixpkgsPath(string) string
 	ProjectDir() string
-	Resolve(string) (*Package, error)
+	Resolve(context.Context, string) (*Package, error)
 }
@@ -9,12 +9,16 @@ import (
 	"io/fs"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
 	"go.jetpack.io/devbox/internal/cachehash"
+	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/devpkg/pkgtype"
 	"go.jetpack.io/devbox/internal/searcher"
+	"go.jetpack.io/devbox/internal/ux/progress"
 	"go.jetpack.io/pkg/runx/impl/types"
 
 	"go.jetpack.io/devbox/internal/cuecfg"
@@ -30,6 +34,9 @@ type File struct {
 
 	// Packages is keyed by "canonicalName@version"
 	Packages map[string]*Package `json:"packages"`
+
+	// Includes is keyed by the include reference in devbox.json.
+	Includes map[string]*Include `json:"includes,omitempty"`
 }
 
 func GetFile(project devboxProject) (*File, error) {
@@ -53,11 +60,9 @@ func GetFile(project devboxProject) (*File, error) {
 	return lockFile, nil
 }
 
-func (f *File) Add(pkgs ...string) error {
-	for _, p := range pkgs {
-		if _, err := f.Resolve(p); err != nil {
-			return err
-		}
+func (f *File) Add(ctx context.Context, pkgs ...string) error {
+	if err := f.ResolveAll(ctx, pkgs); err != nil {
+		return err
 	}
 	return f.Save()
 }
@@ -71,24 +76,11 @@ func (f *File) Remove(pkgs ...string) error {
 
 // Resolve updates the in memory copy for performance but does not write to disk
 // This avoids writing values that may need to be removed in case of error.
-func (f *File) Resolve(pkg string) (*Package, error) {
-	entry, hasEntry := f.Packages[pkg]
-
-	if !hasEntry || entry.Resolved == "" {
-		locked := &Package{}
-		var err error
-		if _, _, versioned := searcher.ParseVersionedPackage(pkg); pkgtype.IsRunX(pkg) || versioned {
-			locked, err = f.FetchResolvedPackage(pkg)
-			if err != nil {
-				return nil, err
-			}
-		} else if IsLegacyPackage(pkg) {
-			// These are legacy packages without a version. Resolve to nixpkgs with
-			// whatever hash is in the devbox.json
-			locked = &Package{
-				Resolved: f.LegacyNixpkgsPath(pkg),
-				Source:   nixpkgSource,
-			}
+func (f *File) Resolve(ctx context.Context, pkg string) (*Package, error) {
+	if !f.isResolved(pkg) {
+		locked, err := f.resolveEntry(ctx, pkg)
+		if err != nil {
+			return nil, err
 		}
 		f.Packages[pkg] = locked
 	}
@@ -96,6 +88,125 @@ func (f *File) Resolve(pkg string) (*Package, error) {
 	return f.Packages[pkg], nil
 }
 
+// ResolveAll resolves the packages that aren't in the lockfile yet, like
+// Resolve. The search service is queried for up to
+// searcher.MaxConcurrentRequests packages at a time. Entries are added in the
+// order of pkgs, so the result doesn't depend on which request finishes
+// first. Packages that fail to resolve are left out, and the error of the
+// first one is returned.
+func (f *File) ResolveAll(ctx context.Context, pkgs []string) error {
+	_, err := f.resolveAll(ctx, pkgs)
+	return err
+}
+
+// TryResolveAll is like ResolveAll, but returns the error of each package that
+// failed to resolve, keyed by package, so that the caller can recover from
+// them without resolving the packages again.
+func (f *File) TryResolveAll(ctx context.Context, pkgs []string) map[string]error {
+	failed, _ := f.resolveAll(ctx, pkgs)
+	return failed
+}
+
+func (f *File) resolveAll(ctx context.Context, pkgs []string) (map[string]error, error) {
+	defer debug.FunctionTimer().End()
+	unresolved := lo.Filter(lo.Uniq(pkgs), func(pkg string, _ int) bool {
+		return !f.isResolved(pkg)
+	})
+	entries, errs := forEachConcurrently(ctx, unresolved, f.resolveEntry)
+	failed := map[string]error{}
+	for i, pkg := range unresolved {
+		if errs[i] != nil {
+			failed[pkg] = errs[i]
+			continue
+		}
+		f.Packages[pkg] = entries[i]
+	}
+	return failed, firstError(errs)
+}
+
+// FetchResolvedPackages calls FetchResolvedPackage for each package
+// concurrently, like ResolveAll. The results are in the order of pkgs.
+func (f *File) FetchResolvedPackages(ctx context.Context, pkgs []string) ([]*Package, error) {
+	resolved, errs := forEachConcurrently(ctx, pkgs, f.FetchResolvedPackage)
+	return resolved, firstError(errs)
+}
+
+// forEachConcurrently calls fn for each package with at most
+// searcher.MaxConcurrentRequests calls at a time. The results and errors are
+// in the order of pkgs. Once ctx is done, the remaining packages fail with
+// its error.
+//
+// Each package is reported as a resolve task. The errors are left to the
+// caller, which may recover from them.
+func forEachConcurrently(
+	ctx context.Context,
+	pkgs []string,
+	fn func(ctx context.Context, pkg string) (*Package, error),
+) ([]*Package, []error) {
+	results := make([]*Package, len(pkgs))
+	errs := make([]error, len(pkgs))
+	if len(pkgs) == 0 {
+		return results, errs
+	}
+
+	task := progress.Start(progress.Resolve, "Resolving %d packages", len(pkgs))
+	defer task.End(nil)
+	done := atomic.Int64{}
+
+	sem := make(chan struct{}, searcher.MaxConcurrentRequests)
+	var wg sync.WaitGroup
+	for i, pkg := range pkgs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			child := task.Child(progress.Resolve, pkg)
+			results[i], errs[i] = fn(ctx, pkg)
+			child.End(errs[i])
+			task.Update(done.Add(1), int64(len(pkgs)))
+		}()
+	}
+	wg.Wait()
+	return results, errs
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *File) isResolved(pkg string) bool {
+	entry, hasEntry := f.Packages[pkg]
+	return hasEntry && entry.Resolved != ""
+}
+
+// resolveEntry returns the lock entry of pkg without changing the lockfile,
+// so it's safe to call concurrently.
+func (f *File) resolveEntry(ctx context.Context, pkg string) (*Package, error) {
+	defer debug.Timer("resolve " + pkg).End()
+	if _, _, versioned := searcher.ParseVersionedPackage(pkg); pkgtype.IsRunX(pkg) || versioned {
+		return f.FetchResolvedPackage(ctx, pkg)
+	}
+	if IsLegacyPackage(pkg) {
+		// These are legacy packages without a version. Resolve to nixpkgs with
+		// whatever hash is in the devbox.json
+		return &Package{
+			Resolved: f.LegacyNixpkgsPath(pkg),
+			Source:   nixpkgSource,
+		}, nil
+	}
+	return &Package{}, nil
+}
+
 // TODO:
 // Consider a design change to have the File struct match disk to make this system
 // easier to reason about, and have isDirty() compare the in-memory struct to the
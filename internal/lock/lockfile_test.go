@@ -0,0 +1,70 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.jetpack.io/devbox/internal/envir"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/searcher"
+)
+
+func TestResolveAll(t *testing.T) {
+	inFlight, maxInFlight := atomic.Int32{}, atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			peak := maxInFlight.Load()
+			if n <= peak || maxInFlight.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		name := r.URL.Query().Get("name")
+		if name == "missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"name": %[1]q, "version": "1.0.0", "systems": {"x86_64-linux": {
+			"flake_installable": {"ref": {"type": "github", "owner": "NixOS", "repo": "nixpkgs", "rev": "abc"}, "attr_path": %[1]q},
+			"last_updated": "2024-01-01T00:00:00Z"}}}`, name)
+	}))
+	defer server.Close()
+	t.Setenv(envir.DevboxSearchHost, server.URL)
+	t.Setenv("__DEVBOX_NIX_SYSTEM", "x86_64-linux")
+	t.Cleanup(nix.SetSystem("x86_64-linux"))
+
+	pkgs := []string{}
+	for i := range 2 * searcher.MaxConcurrentRequests {
+		pkgs = append(pkgs, fmt.Sprintf("pkg%d@1", i))
+	}
+	f := &File{Packages: map[string]*Package{}}
+	if err := f.ResolveAll(context.Background(), append(pkgs, "missing@1")); err == nil {
+		t.Error("got nil error, want an error for the missing package")
+	}
+
+	if got := maxInFlight.Load(); got > searcher.MaxConcurrentRequests {
+		t.Errorf("got %d concurrent requests, want at most %d", got, searcher.MaxConcurrentRequests)
+	}
+	for _, pkg := range pkgs {
+		entry := f.Get(pkg)
+		if entry == nil || entry.Version != "1.0.0" {
+			t.Errorf("got entry %v for %s, want version 1.0.0", entry, pkg)
+		}
+	}
+	if _, ok := f.Packages["missing@1"]; ok {
+		t.Error("got an entry for the missing package")
+	}
+
+	failed := f.TryResolveAll(context.Background(), []string{"pkg0@1", "missing@1"})
+	if len(failed) != 1 || failed["missing@1"] == nil {
+		t.Errorf("got errors %v, want only an error for missing@1", failed)
+	}
+}
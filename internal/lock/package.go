@@ -142,3 +142,33 @@ func ensurePackagesHaveOutputs(packages map[string]*Package) {
 		}
 	}
 }
+
+// LegacyStorePathPackages returns the packages that only have the legacy
+// store_path field in at least one of their systems.
+func (f *File) LegacyStorePathPackages() []string {
+	pkgs := []string{}
+	for name, pkg := range f.Packages {
+		for _, sysInfo := range pkg.Systems {
+			if sysInfo.outputIsFromStorePath {
+				pkgs = append(pkgs, name)
+				break
+			}
+		}
+	}
+	slices.Sort(pkgs)
+	return pkgs
+}
+
+// MigrateLegacyStorePaths replaces the legacy store_path fields with the
+// outputs that were derived from them, so that Save writes the current
+// format.
+func (f *File) MigrateLegacyStorePaths() {
+	for _, pkg := range f.Packages {
+		for _, sysInfo := range pkg.Systems {
+			if sysInfo.outputIsFromStorePath {
+				sysInfo.StorePath = ""
+				sysInfo.outputIsFromStorePath = false
+			}
+		}
+	}
+}
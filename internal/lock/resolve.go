@@ -27,7 +27,7 @@ import (
 // not changed. This can happen when doing `devbox update` and search has
 // a newer hash than the lock file but same version. In that case we don't want
 // to update because it would be slow and wasteful.
-func (f *File) FetchResolvedPackage(pkg string) (*Package, error) {
+func (f *File) FetchResolvedPackage(ctx context.Context, pkg string) (*Package, error) {
 	if pkgtype.IsFlake(pkg) {
 		return nil, nil
 	}
@@ -38,7 +38,7 @@ func (f *File) FetchResolvedPackage(pkg string) (*Package, error) {
 	}
 
 	if pkgtype.IsRunX(pkg) {
-		ref, err := ResolveRunXPackage(context.TODO(), pkg)
+		ref, err := ResolveRunXPackage(ctx, pkg)
 		if err != nil {
 			return nil, err
 		}
@@ -48,17 +48,17 @@ func (f *File) FetchResolvedPackage(pkg string) (*Package, error) {
 		}, nil
 	}
 	if featureflag.ResolveV2.Enabled() {
-		return resolveV2(context.TODO(), name, version)
+		return resolveV2(ctx, name, version)
 	}
 
-	packageVersion, err := searcher.Client().Resolve(name, version)
+	packageVersion, err := searcher.Client().Resolve(ctx, name, version)
 	if err != nil {
 		return nil, errors.Wrapf(nix.ErrPackageNotFound, "%s@%s", name, version)
 	}
 
 	sysInfos := map[string]*SystemInfo{}
 	if featureflag.RemoveNixpkgs.Enabled() {
-		sysInfos, err = buildLockSystemInfos(packageVersion)
+		sysInfos, err = buildLockSystemInfos(ctx, packageVersion)
 		if err != nil {
 			return nil, err
 		}
@@ -152,13 +152,13 @@ func selectForSystem[V any](systems map[string]V) (v V, err error) {
 	return v, redact.Errorf("no systems found")
 }
 
-func buildLockSystemInfos(pkg *searcher.PackageVersion) (map[string]*SystemInfo, error) {
+func buildLockSystemInfos(ctx context.Context, pkg *searcher.PackageVersion) (map[string]*SystemInfo, error) {
 	// guard against missing search data
 	systems := lo.PickBy(pkg.Systems, func(sysName string, sysInfo searcher.PackageInfo) bool {
 		return sysInfo.StoreHash != "" && sysInfo.StoreName != ""
 	})
 
-	group, ctx := errgroup.WithContext(context.Background())
+	group, ctx := errgroup.WithContext(ctx)
 
 	var storePathLock sync.RWMutex
 	sysStorePaths := map[string]string{}
@@ -167,9 +167,7 @@ func buildLockSystemInfos(pkg *searcher.PackageVersion) (map[string]*SystemInfo,
 		sysInfo := _sysInfo // capture range variable
 
 		group.Go(func() error {
-			// We should use devpkg.BinaryCache here, but it'll cause a circular reference
-			// Just hardcoding for now. Maybe we should move that to nix.DefaultBinaryCache?
-			path, err := nix.StorePathFromHashPart(ctx, sysInfo.StoreHash, "https://cache.nixos.org")
+			path, err := nix.StorePathFromHashPart(ctx, sysInfo.StoreHash, nix.DefaultBinaryCache)
 			if err != nil {
 				// Should we report this to sentry to collect data?
 				debug.Log(
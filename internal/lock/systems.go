@@ -0,0 +1,83 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package lock
+
+import (
+	"context"
+	"slices"
+
+	"go.jetpack.io/devbox/internal/boxcli/featureflag"
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/searcher"
+)
+
+// ResolveSystems adds the outputs of pkg for each of systems that its lock
+// entry doesn't have. The outputs come from the locked version of pkg, so the
+// package isn't updated. Systems that the package isn't available on are left
+// out of the entry.
+//
+// Only packages resolved by the search service have per-system outputs. Other
+// packages are left unchanged.
+func (f *File) ResolveSystems(ctx context.Context, pkg string, systems []string) error {
+	entry, err := f.Resolve(ctx, pkg)
+	if err != nil {
+		return err
+	}
+	if entry.Source != devboxSearchSource || entry.Version == "" {
+		return nil
+	}
+	missing := slices.ContainsFunc(systems, func(sys string) bool {
+		return entry.Systems[sys] == nil
+	})
+	if !missing {
+		return nil
+	}
+
+	name, _, _ := searcher.ParseVersionedPackage(pkg)
+	infos, err := fetchSystemInfos(ctx, name, entry.Version)
+	if err != nil {
+		return err
+	}
+	for _, sys := range systems {
+		if entry.Systems[sys] != nil || infos[sys] == nil {
+			continue
+		}
+		if entry.Systems == nil {
+			entry.Systems = map[string]*SystemInfo{}
+		}
+		entry.Systems[sys] = infos[sys]
+	}
+	return nil
+}
+
+// fetchSystemInfos returns the outputs of each system that an exact version of
+// a package is available on.
+func fetchSystemInfos(ctx context.Context, name, version string) (map[string]*SystemInfo, error) {
+	if featureflag.ResolveV2.Enabled() {
+		resolved, err := resolveV2(ctx, name, version)
+		if err != nil {
+			return nil, err
+		}
+		if resolved.Version != version {
+			debug.Log("resolved %s@%s to version %s", name, version, resolved.Version)
+			return nil, nil
+		}
+		return resolved.Systems, nil
+	}
+
+	packageVersion, err := searcher.Client().Resolve(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+	// A version that's no longer in the search index can resolve to a
+	// different one, whose outputs don't belong in the lock entry.
+	systems := map[string]searcher.PackageInfo{}
+	for sys, info := range packageVersion.Systems {
+		if info.Version == version {
+			systems[sys] = info
+		}
+	}
+	packageVersion.Systems = systems
+	return buildLockSystemInfos(ctx, packageVersion)
+}
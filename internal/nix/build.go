@@ -5,22 +5,29 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"strings"
+	"slices"
 
 	"github.com/pkg/errors"
 	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/redact"
+	"go.jetpack.io/devbox/internal/ux/progress"
 )
 
 type BuildArgs struct {
 	AllowInsecure     bool
 	Env               []string
 	ExtraSubstituters []string
-	Flags             []string
-	Writer            io.Writer
+	// BinaryCaches are the project's caches, which are used in addition to
+	// ExtraSubstituters.
+	BinaryCaches []BinaryCache
+	Flags        []string
+	Writer       io.Writer
+	// Progress is the task that the downloads and builds are reported as
+	// part of.
+	Progress *progress.Task
 }
 
-func Build(ctx context.Context, args *BuildArgs, installables ...string) error {
+func Build(ctx context.Context, args *BuildArgs, installables ...string) (err error) {
 	defer debug.FunctionTimer().End()
 	// --impure is required for allowUnfreeEnv/allowInsecureEnv to work.
 	cmd := commandContext(ctx, "build", "--impure")
@@ -28,12 +35,11 @@ func Build(ctx context.Context, args *BuildArgs, installables ...string) error {
 	cmd.Args = append(cmd.Args, installables...)
 	// Adding extra substituters only here to be conservative, but this could also
 	// be added to ExperimentalFlags() in the future.
-	if len(args.ExtraSubstituters) > 0 {
-		cmd.Args = append(cmd.Args,
-			"--extra-substituters",
-			strings.Join(args.ExtraSubstituters, " "),
-		)
+	caches := slices.Clone(args.BinaryCaches)
+	for _, substituter := range args.ExtraSubstituters {
+		caches = append(caches, BinaryCache{URL: substituter})
 	}
+	cmd.Args = append(cmd.Args, binaryCacheFlags(caches)...)
 	cmd.Env = append(allowUnfreeEnv(os.Environ()), args.Env...)
 	if args.AllowInsecure {
 		debug.Log("Setting Allow-insecure env-var\n")
@@ -44,8 +50,17 @@ func Build(ctx context.Context, args *BuildArgs, installables ...string) error {
 	// need to change this to our own writers, consider that you may need
 	// to implement your own nicer output. --print-build-logs flag may be useful.
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = args.Writer
-	cmd.Stderr = args.Writer
+	cmd.Stdout = progress.Terminal(args.Writer)
+	cmd.Stderr = progress.Terminal(args.Writer)
+	if progress.IsJSON() {
+		cmd.Args = append(cmd.Args, "--log-format", "internal-json")
+		logs := newProgressWriter(args.Writer, args.Progress)
+		defer func() { logs.close(err) }()
+		cmd.Stderr = logs
+	} else {
+		// nix draws its own progress in a terminal.
+		defer progress.Suspend()()
+	}
 
 	debug.Log("Running cmd: %s\n", cmd)
 	if err := cmd.Run(); err != nil {
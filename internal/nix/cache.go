@@ -1,12 +1,145 @@
 package nix
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/redact"
 )
 
+// DefaultBinaryCache is the official Nix binary cache. It's configured in
+// nix.conf by default.
+const DefaultBinaryCache = "https://cache.nixos.org"
+
+// defaultBinaryCachePriority is the priority that cache.nixos.org advertises,
+// and defaultPriority is what Nix uses for caches that don't advertise one.
+const (
+	defaultBinaryCachePriority = 40
+	defaultPriority            = 50
+)
+
+// BinaryCache is a Nix binary cache (a substituter) that's used in addition
+// to the caches in nix.conf.
+type BinaryCache struct {
+	// URL is the store URL of the cache, such as https://cache.example.com
+	// or s3://bucket.
+	URL string `json:"url"`
+
+	// PublicKey is the key that the cache signs store paths with, in the
+	// format name:base64-key.
+	PublicKey string `json:"public_key,omitempty"`
+
+	// Priority orders the cache relative to other caches. Lower values
+	// are preferred. cache.nixos.org has a priority of 40, and caches
+	// without a priority default to 50.
+	Priority int `json:"priority,omitempty"`
+
+	// Upload makes the cache the default destination of devbox cache
+	// upload.
+	Upload bool `json:"upload,omitempty"`
+
+	// SecretKeyFile is the path to the key that uploaded store paths are
+	// signed with. A leading ~ is expanded to the home directory.
+	SecretKeyFile string `json:"secret_key_file,omitempty"`
+}
+
+// Substituter returns the cache's URL with its priority as a query
+// parameter, which is how Nix configures the priority of a substituter.
+func (c BinaryCache) Substituter() string {
+	if c.Priority == 0 {
+		return c.URL
+	}
+	sep := "?"
+	if strings.Contains(c.URL, "?") {
+		sep = "&"
+	}
+	return c.URL + sep + "priority=" + strconv.Itoa(c.Priority)
+}
+
+// UploadURL returns the store URL that nix copy uploads to. If the cache has a
+// secret key, the URL tells Nix to sign the paths that it uploads.
+func (c BinaryCache) UploadURL() string {
+	if c.SecretKeyFile == "" {
+		return c.URL
+	}
+	keyFile := c.SecretKeyFile
+	if rest, ok := strings.CutPrefix(keyFile, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			keyFile = filepath.Join(home, rest)
+		}
+	}
+	sep := "?"
+	if strings.Contains(c.URL, "?") {
+		sep = "&"
+	}
+	return c.URL + sep + "secret-key=" + url.QueryEscape(keyFile)
+}
+
+// IsHTTP returns true if the cache is served over HTTP, which means that
+// narinfo files can be requested from it directly.
+func (c BinaryCache) IsHTTP() bool {
+	u, err := url.Parse(c.URL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+func (c BinaryCache) effectivePriority() int {
+	switch {
+	case c.Priority != 0:
+		return c.Priority
+	case strings.TrimSuffix(c.URL, "/") == DefaultBinaryCache:
+		return defaultBinaryCachePriority
+	default:
+		return defaultPriority
+	}
+}
+
+// BinaryCaches returns the default cache and the extra caches, ordered by
+// priority with the preferred cache first.
+func BinaryCaches(extra []BinaryCache) []BinaryCache {
+	caches := []BinaryCache{}
+	if !slices.ContainsFunc(extra, func(c BinaryCache) bool {
+		return strings.TrimSuffix(c.URL, "/") == DefaultBinaryCache
+	}) {
+		caches = append(caches, BinaryCache{URL: DefaultBinaryCache})
+	}
+	caches = append(caches, extra...)
+	slices.SortStableFunc(caches, func(a, b BinaryCache) int {
+		return cmp.Compare(a.effectivePriority(), b.effectivePriority())
+	})
+	return caches
+}
+
+// binaryCacheFlags returns the flags that add caches to a nix command in
+// addition to the caches in nix.conf. Nix only uses them if the user is
+// trusted or they're listed in trusted-substituters.
+func binaryCacheFlags(caches []BinaryCache) []string {
+	if len(caches) == 0 {
+		return nil
+	}
+	substituters := []string{}
+	keys := []string{}
+	for _, cache := range caches {
+		substituters = append(substituters, cache.Substituter())
+		if cache.PublicKey != "" {
+			keys = append(keys, cache.PublicKey)
+		}
+	}
+	flags := []string{"--extra-substituters", strings.Join(substituters, " ")}
+	if len(keys) > 0 {
+		flags = append(flags, "--extra-trusted-public-keys", strings.Join(keys, " "))
+	}
+	return flags
+}
+
 func CopyInstallableToCache(
 	ctx context.Context,
 	out io.Writer,
@@ -35,3 +168,53 @@ func CopyInstallableToCache(
 
 	return cmd.Run()
 }
+
+// CopyPathsToCache uploads store paths to a binary cache. Unlike
+// CopyInstallableToCache, it only copies the given paths and not their
+// closures, so callers can skip the paths that the cache already has.
+func CopyPathsToCache(ctx context.Context, out io.Writer, to string, paths []string, env []string) error {
+	cmd := commandContext(ctx, append([]string{"copy", "--to", to}, paths...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Run()
+}
+
+// MissingPaths returns the paths that aren't in a store, such as a binary
+// cache.
+func MissingPaths(ctx context.Context, store string, paths []string, env []string) ([]string, error) {
+	defer debug.FunctionTimer().End()
+
+	cmd := commandContext(ctx, append([]string{"path-info", "--json", "--store", store}, paths...)...)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.Output()
+	// Nix exits with an error if any path is missing, but it still prints
+	// the paths that it found.
+	if err != nil && len(out) == 0 {
+		return nil, redact.Errorf("query paths in %s: %w", store, err)
+	}
+	infos, err := parsePathInfos(out)
+	if err != nil {
+		return nil, err
+	}
+	return missingPaths(paths, infos), nil
+}
+
+// missingPaths returns the paths that don't have a valid path info. Older
+// versions of Nix list missing paths without a nar hash.
+func missingPaths(paths []string, infos []PathInfo) []string {
+	valid := map[string]bool{}
+	for _, info := range infos {
+		if info.NarHash != "" {
+			valid[info.Path] = true
+		}
+	}
+	missing := []string{}
+	for _, path := range paths {
+		if !valid[path] {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
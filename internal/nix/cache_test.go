@@ -0,0 +1,95 @@
+package nix
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBinaryCacheSubstituter(t *testing.T) {
+	tests := []struct {
+		cache BinaryCache
+		want  string
+	}{
+		{BinaryCache{URL: "https://cache.example.com"}, "https://cache.example.com"},
+		{BinaryCache{URL: "https://cache.example.com", Priority: 10}, "https://cache.example.com?priority=10"},
+		{BinaryCache{URL: "s3://bucket?region=eu-west-1", Priority: 30}, "s3://bucket?region=eu-west-1&priority=30"},
+	}
+	for _, test := range tests {
+		if got := test.cache.Substituter(); got != test.want {
+			t.Errorf("%+v.Substituter() = %q, want %q", test.cache, got, test.want)
+		}
+	}
+}
+
+func TestBinaryCaches(t *testing.T) {
+	got := []string{}
+	for _, cache := range BinaryCaches([]BinaryCache{
+		{URL: "https://low.example.com", Priority: 60},
+		{URL: "s3://bucket"},
+		{URL: "https://high.example.com", Priority: 10},
+	}) {
+		got = append(got, cache.URL)
+	}
+	want := []string{"https://high.example.com", DefaultBinaryCache, "s3://bucket", "https://low.example.com"}
+	if !slices.Equal(got, want) {
+		t.Errorf("BinaryCaches() = %q, want %q", got, want)
+	}
+}
+
+func TestBinaryCacheFlags(t *testing.T) {
+	if got := binaryCacheFlags(nil); got != nil {
+		t.Errorf("binaryCacheFlags() = %q without extra caches, want nil", got)
+	}
+
+	want := []string{
+		"--extra-substituters", "https://cache.example.com?priority=10 s3://bucket",
+		"--extra-trusted-public-keys", "cache.example.com-1:abc=",
+	}
+	if got := binaryCacheFlags([]BinaryCache{
+		{URL: "https://cache.example.com", PublicKey: "cache.example.com-1:abc=", Priority: 10},
+		{URL: "s3://bucket"},
+	}); !slices.Equal(got, want) {
+		t.Errorf("binaryCacheFlags() = %q, want %q", got, want)
+	}
+}
+
+func TestBinaryCacheUploadURL(t *testing.T) {
+	t.Setenv("HOME", "/home/user")
+
+	tests := []struct {
+		cache BinaryCache
+		want  string
+	}{
+		{BinaryCache{URL: "file:///srv/cache"}, "file:///srv/cache"},
+		{
+			BinaryCache{URL: "file:///srv/cache", SecretKeyFile: "~/.config/nix/cache.key", Priority: 10},
+			"file:///srv/cache?secret-key=%2Fhome%2Fuser%2F.config%2Fnix%2Fcache.key",
+		},
+		{
+			BinaryCache{URL: "s3://bucket?endpoint=localhost:9000&scheme=http", SecretKeyFile: "/etc/nix/key"},
+			"s3://bucket?endpoint=localhost:9000&scheme=http&secret-key=%2Fetc%2Fnix%2Fkey",
+		},
+	}
+	for _, test := range tests {
+		if got := test.cache.UploadURL(); got != test.want {
+			t.Errorf("%+v.UploadURL() = %q, want %q", test.cache, got, test.want)
+		}
+	}
+}
+
+func TestMissingPaths(t *testing.T) {
+	paths := []string{
+		"/nix/store/cvrn84c1hshv2wcds7n1rhydi6lacqns-gnumake-4.4.1",
+		"/nix/store/gfxwrd5nggc68pjj3g3jhlldim9rpg0p-coreutils",
+		"/nix/store/q2xdxsswjqmqcbax81pmazm367s7jzyb-hello-2.12.1",
+	}
+	infos := []PathInfo{
+		{Path: paths[0], NarHash: "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+		// Older versions of nix list missing paths without a hash.
+		{Path: paths[1]},
+	}
+	want := []string{paths[1], paths[2]}
+	if got := missingPaths(paths, infos); !slices.Equal(got, want) {
+		t.Errorf("missingPaths() = %q, want %q", got, want)
+	}
+}
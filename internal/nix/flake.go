@@ -0,0 +1,23 @@
+package nix
+
+import (
+	"context"
+	"io"
+
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/redact"
+)
+
+// FlakeLock creates or updates the flake.lock of the flake in dir.
+func FlakeLock(ctx context.Context, w io.Writer, dir string) error {
+	// Use a path: reference so that the flake doesn't have to be added to
+	// git before it's locked.
+	cmd := commandContext(ctx, "flake", "lock", "path:"+dir)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	debug.Log("Running cmd: %s\n", cmd)
+	if err := cmd.Run(); err != nil {
+		return redact.Errorf("nix flake lock: %w", err)
+	}
+	return nil
+}
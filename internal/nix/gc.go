@@ -0,0 +1,84 @@
+package nix
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// autoGCRootsDir has a symlink to every indirect gc root, such as the
+// generations of devbox's profiles.
+const autoGCRootsDir = "/nix/var/nix/gcroots/auto"
+
+// OldProfileGenerations returns the generation links of a profile other than
+// the current one. Removing them lets nix store gc delete their store paths,
+// like nix profile wipe-history does.
+func OldProfileGenerations(profilePath string) ([]string, error) {
+	current, err := os.Readlink(profilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	dir, name := filepath.Split(profilePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	generation := regexp.MustCompile("^" + regexp.QuoteMeta(name) + `-[0-9]+-link$`)
+	old := []string{}
+	for _, entry := range entries {
+		if generation.MatchString(entry.Name()) && entry.Name() != filepath.Base(current) {
+			old = append(old, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return old, nil
+}
+
+// AutoGCRoots returns the targets of the indirect gc roots, which are the
+// links that keep store paths alive, such as project profiles. Targets that
+// no longer exist are included, because nix store gc only removes them from
+// the gc roots when it runs.
+func AutoGCRoots() ([]string, error) {
+	entries, err := os.ReadDir(autoGCRootsDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	targets := []string{}
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(autoGCRootsDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// StoreGC runs nix store gc, which deletes the store paths that aren't
+// reachable from a gc root.
+func StoreGC(ctx context.Context, w io.Writer, dryRun bool) error {
+	args := []string{"store", "gc"}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+	cmd := commandContext(ctx, args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	return errors.WithStack(cmd.Run())
+}
+
+// ProjectDirOfProfile returns the project directory of a profile in the
+// .devbox directory of a project, or false if the profile isn't in one.
+func ProjectDirOfProfile(profilePath string) (string, bool) {
+	dir, _, ok := strings.Cut(profilePath, string(filepath.Separator)+filepath.Dir(ProfilePath)+string(filepath.Separator))
+	return dir, ok
+}
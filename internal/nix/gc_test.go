@@ -0,0 +1,60 @@
+package nix
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestOldProfileGenerations(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"default-1-link", "default-2-link", "default-3-link", "other-1-link"} {
+		if err := os.Symlink("/nix/store/cvrn84c1hshv2wcds7n1rhydi6lacqns-profile", filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profile := filepath.Join(dir, "default")
+	if err := os.Symlink("default-2-link", profile); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := OldProfileGenerations(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "default-1-link"), filepath.Join(dir, "default-3-link")}
+	if !slices.Equal(got, want) {
+		t.Errorf("OldProfileGenerations() = %q, want %q", got, want)
+	}
+
+	got, err = OldProfileGenerations(filepath.Join(dir, "missing"))
+	if err != nil || len(got) != 0 {
+		t.Errorf("OldProfileGenerations() of a missing profile = %q, %v, want no generations", got, err)
+	}
+}
+
+func TestProjectDirOfProfile(t *testing.T) {
+	dir, ok := ProjectDirOfProfile("/home/user/project/.devbox/nix/profile/default-3-link")
+	if !ok || dir != "/home/user/project" {
+		t.Errorf("got %q, %v, want /home/user/project, true", dir, ok)
+	}
+	if _, ok := ProjectDirOfProfile("/home/user/.local/state/nix/profiles/profile-1-link"); ok {
+		t.Error("got ok for a profile that isn't in a project")
+	}
+}
+
+func TestParseGeneration(t *testing.T) {
+	tests := map[string]int{
+		"default-12-link":     12,
+		"/tmp/default-1-link": 1,
+		"other-3-link":        0,
+		"default-x-link":      0,
+		"default":             0,
+	}
+	for link, want := range tests {
+		if got := parseGeneration("/project/.devbox/nix/profile/default", link); got != want {
+			t.Errorf("parseGeneration(%q) = %d, want %d", link, got, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package nix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/redact"
+)
+
+// metaApply is a Nix function that selects the meta attributes that devbox
+// uses. It normalizes the attributes that nixpkgs allows to be missing so
+// that the JSON output is the same shape for every package.
+const metaApply = `m: {
+  description = m.description or "";
+  homepage = m.homepage or "";
+  license = m.license or [];
+  knownVulnerabilities = m.knownVulnerabilities or [];
+  insecure = m.insecure or false;
+  unfree = m.unfree or false;
+}`
+
+// PackageMeta is a subset of the meta attributes of a nixpkgs derivation.
+//
+// See https://nixos.org/manual/nixpkgs/stable/#chap-meta
+type PackageMeta struct {
+	Description          string   `json:"description"`
+	Homepage             Strings  `json:"homepage"`
+	Licenses             Licenses `json:"license"`
+	KnownVulnerabilities []string `json:"knownVulnerabilities"`
+	Insecure             bool     `json:"insecure"`
+	Unfree               bool     `json:"unfree"`
+}
+
+// License is a nixpkgs license from lib.licenses.
+type License struct {
+	// SPDXID is the license's SPDX identifier. It's empty for licenses that
+	// aren't on the SPDX license list.
+	SPDXID    string `json:"spdxId,omitempty"`
+	ShortName string `json:"shortName,omitempty"`
+	FullName  string `json:"fullName,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Free      bool   `json:"free"`
+}
+
+// ID returns the license's SPDX identifier if it has one. Otherwise it returns
+// its short name or, as a last resort, its full name.
+func (l License) ID() string {
+	switch {
+	case l.SPDXID != "":
+		return l.SPDXID
+	case l.ShortName != "":
+		return l.ShortName
+	}
+	return l.FullName
+}
+
+// Licenses unmarshals meta.license, which may be a single license or a list
+// of licenses. Older packages also use plain strings instead of license
+// attribute sets.
+type Licenses []License
+
+func (l *Licenses) UnmarshalJSON(data []byte) error {
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil {
+		list = []json.RawMessage{data}
+	}
+	*l = make(Licenses, 0, len(list))
+	for _, raw := range list {
+		license := License{Free: true}
+		if err := json.Unmarshal(raw, &license); err != nil {
+			var name string
+			if err := json.Unmarshal(raw, &name); err != nil {
+				return err
+			}
+			license = License{ShortName: name, Free: true}
+		}
+		*l = append(*l, license)
+	}
+	return nil
+}
+
+// Strings unmarshals a Nix attribute that may be a single string or a list of
+// strings, such as meta.homepage.
+type Strings []string
+
+func (s *Strings) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*s = list
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*s = nil
+	if str != "" {
+		*s = Strings{str}
+	}
+	return nil
+}
+
+// EvalPackageMeta evaluates the meta attributes of an installable that
+// refers to a derivation, such as "nixpkgs#hello". It doesn't build or
+// download the package.
+func EvalPackageMeta(ctx context.Context, installable string) (*PackageMeta, error) {
+	defer debug.FunctionTimer().End()
+
+	// --impure for NIXPKGS_ALLOW_UNFREE and NIXPKGS_ALLOW_INSECURE
+	cmd := commandContext(ctx, "eval", "--json", "--impure",
+		installable+".meta", "--apply", metaApply)
+	cmd.Env = allowInsecureEnv(allowUnfreeEnv(cmd.Environ()))
+	debug.Log("Running cmd %s", cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr := (&exec.ExitError{}); errors.As(err, &exitErr) {
+			return nil, redact.Errorf(
+				"nix eval exit code: %d, output: %s, err: %w",
+				redact.Safe(exitErr.ExitCode()),
+				exitErr.Stderr,
+				err,
+			)
+		}
+		return nil, err
+	}
+	return parsePackageMeta(out)
+}
+
+func parsePackageMeta(data []byte) (*PackageMeta, error) {
+	meta := &PackageMeta{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, redact.Errorf("parse nix package meta: %w", err)
+	}
+	return meta, nil
+}
@@ -0,0 +1,44 @@
+package nix
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePackageMeta(t *testing.T) {
+	meta, err := parsePackageMeta([]byte(`{
+		"description": "A tool",
+		"homepage": ["https://example.com", "https://example.org"],
+		"license": {"spdxId": "MIT", "shortName": "mit", "free": true},
+		"knownVulnerabilities": [],
+		"insecure": false,
+		"unfree": false
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error but got error: %s", err)
+	}
+	if len(meta.Licenses) != 1 || meta.Licenses[0].ID() != "MIT" {
+		t.Errorf("Expected a single MIT license but got %+v", meta.Licenses)
+	}
+	if len(meta.Homepage) != 2 {
+		t.Errorf("Expected 2 homepages but got %v", meta.Homepage)
+	}
+
+	meta, err = parsePackageMeta([]byte(`{
+		"description": "",
+		"homepage": "https://example.com",
+		"license": [{"shortName": "unfree", "free": false}, "Public Domain"],
+		"knownVulnerabilities": ["CVE-2024-0001"],
+		"insecure": true,
+		"unfree": true
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error but got error: %s", err)
+	}
+	if len(meta.Licenses) != 2 || meta.Licenses[0].Free || meta.Licenses[1].ID() != "Public Domain" {
+		t.Errorf("Expected an unfree and a public domain license but got %+v", meta.Licenses)
+	}
+	if !slices.Equal(meta.Homepage, Strings{"https://example.com"}) {
+		t.Errorf("Expected homepage https://example.com but got %v", meta.Homepage)
+	}
+}
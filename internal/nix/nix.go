@@ -14,6 +14,7 @@ import (
 	"regexp"
 	"runtime"
 	"runtime/trace"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -146,6 +147,16 @@ func System() string {
 
 var cachedSystem string
 
+// SetSystem overrides the system that System returns, like
+// __DEVBOX_NIX_SYSTEM does before the system is computed, and returns a
+// function that restores the previous system. Tests use it to pretend to run
+// on another system.
+func SetSystem(system string) (restore func()) {
+	prev := cachedSystem
+	cachedSystem = system
+	return func() { cachedSystem = prev }
+}
+
 func ComputeSystem() error {
 	// For Savil to debug "remove nixpkgs" feature. The Search api lacks x86-darwin info.
 	// So, I need to fake that I am x86-linux and inspect the output in generated devbox.lock
@@ -344,6 +355,11 @@ var nixPlatforms = []string{
 	"armv7l-linux",
 }
 
+// Platforms returns the platforms that packages can be installed on.
+func Platforms() []string {
+	return slices.Clone(nixPlatforms)
+}
+
 // EnsureValidPlatform returns an error if the platform is not supported by nix.
 // https://nixos.org/manual/nix/stable/installation/supported-platforms.html
 func EnsureValidPlatform(platforms ...string) error {
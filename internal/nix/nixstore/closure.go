@@ -0,0 +1,129 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package nixstore
+
+import (
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// Name returns the package's store name without its hash prefix. For example,
+// the name of "mil5crms7gfpv03vjj094zz1igvapv6i-go-1.20.2" is "go-1.20.2".
+func (p *Package) Name() string {
+	if len(p.StoreName) > 33 {
+		return p.StoreName[33:]
+	}
+	return p.StoreName
+}
+
+// Size returns the number of bytes used by the package's files and symlinks.
+// It doesn't include the size of the package's dependencies. The result is
+// cached after the first call.
+func (p *Package) Size() (int64, error) {
+	if p.sizeKnown {
+		return p.size, nil
+	}
+
+	var total int64
+	err := fs.WalkDir(p, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error calculating size of %s: %v", p.StoreName, err)
+	}
+	p.size, p.sizeKnown = total, true
+	return total, nil
+}
+
+// Closure returns the given packages and all of their transitive dependencies
+// in topological order. Dependencies shared between packages only appear once.
+func Closure(pkgs ...*Package) []*Package {
+	var sorted []*Package
+	seen := make(map[*Package]bool)
+	for _, pkg := range pkgs {
+		sorted = tsort(sorted, seen, pkg)
+	}
+	return sorted
+}
+
+// ClosureSize returns the combined size of the packages' closure.
+func ClosureSize(pkgs ...*Package) (int64, error) {
+	var total int64
+	for _, pkg := range Closure(pkgs...) {
+		size, err := pkg.Size()
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// DependencyPath returns the shortest chain of dependencies that leads from
+// pkg to dep, starting with pkg and ending with dep. It returns nil if dep
+// isn't in pkg's closure.
+func DependencyPath(pkg, dep *Package) []*Package {
+	// Breadth-first search so that the first path we find is the shortest.
+	// Dependencies are visited in store name order to keep the result
+	// deterministic when there are multiple shortest paths.
+	parent := map[*Package]*Package{pkg: nil}
+	queue := []*Package{pkg}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == dep {
+			var path []*Package
+			for p := current; p != nil; p = parent[p] {
+				path = append(path, p)
+			}
+			slices.Reverse(path)
+			return path
+		}
+		for _, next := range sortedByStoreName(current.DirectDependencies) {
+			if _, ok := parent[next]; ok {
+				continue
+			}
+			parent[next] = current
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
+
+// FindInClosure returns the packages in pkg's closure (excluding pkg itself)
+// with a name that starts with query. Query may also be a full store name or
+// store hash. The results are sorted by store name.
+func FindInClosure(pkg *Package, query string) []*Package {
+	var found []*Package
+	for _, p := range Closure(pkg) {
+		if p == pkg {
+			continue
+		}
+		if p.StoreName == query || p.Hash == query || strings.HasPrefix(p.Name(), query) {
+			found = append(found, p)
+		}
+	}
+	return sortedByStoreName(found)
+}
+
+func sortedByStoreName(pkgs []*Package) []*Package {
+	sorted := slices.Clone(pkgs)
+	slices.SortFunc(sorted, func(a, b *Package) int {
+		return strings.Compare(a.StoreName, b.StoreName)
+	})
+	return sorted
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package nixstore
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const (
+	helloStoreName  = "11111111111111111111111111111111-hello-2.12.1"
+	glibcStoreName  = "22222222222222222222222222222222-glibc-2.38"
+	iconvStoreName  = "33333333333333333333333333333333-libiconv-1.17"
+	unusedStoreName = "44444444444444444444444444444444-unused-1.0"
+)
+
+// newTestStore creates a fake store where hello depends on glibc and libiconv,
+// and libiconv depends on glibc.
+func newTestStore(t *testing.T) *Root {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(helloStoreName+"/bin/hello", "#!/nix/store/"+glibcStoreName+"/lib/ld.so")
+	writeFile(glibcStoreName+"/lib/libc.so", strings.Repeat("x", 1000))
+	writeFile(iconvStoreName+"/lib/libiconv.so", "/nix/store/"+glibcStoreName)
+	writeFile(unusedStoreName+"/README", "unused")
+	err := os.Symlink(
+		filepath.Join(dir, iconvStoreName, "lib"),
+		filepath.Join(dir, helloStoreName, "iconv"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := Local(dir)
+	if err != nil {
+		t.Fatalf("got error for local Nix store %s: %v", dir, err)
+	}
+	return root
+}
+
+func storeNames(pkgs []*Package) []string {
+	names := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		names[i] = pkg.StoreName
+	}
+	return names
+}
+
+func TestClosure(t *testing.T) {
+	root := newTestStore(t)
+	hello, err := root.Package(helloStoreName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := storeNames(Closure(hello))
+	want := []string{glibcStoreName, iconvStoreName, helloStoreName}
+	if !slices.Equal(got, want) {
+		t.Errorf("got closure %v, want %v", got, want)
+	}
+}
+
+func TestClosureSize(t *testing.T) {
+	root := newTestStore(t)
+	hello, err := root.Package(helloStoreName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	glibc, err := root.Package(glibcStoreName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	glibcSize, err := glibc.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if glibcSize != 1000 {
+		t.Errorf("got glibc size %d, want 1000", glibcSize)
+	}
+
+	closureSize, err := ClosureSize(hello, glibc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want int64
+	for _, pkg := range Closure(hello) {
+		size, err := pkg.Size()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want += size
+	}
+	if closureSize != want {
+		t.Errorf("got closure size %d, want %d", closureSize, want)
+	}
+}
+
+func TestDependencyPath(t *testing.T) {
+	root := newTestStore(t)
+	hello, err := root.Package(helloStoreName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := FindInClosure(hello, "glibc")
+	if len(found) != 1 || found[0].StoreName != glibcStoreName {
+		t.Fatalf("got FindInClosure(hello, %q) = %v, want [%s]", "glibc", found, glibcStoreName)
+	}
+
+	// hello references glibc directly, so that path is shorter than the one
+	// through libiconv.
+	got := storeNames(DependencyPath(hello, found[0]))
+	want := []string{helloStoreName, glibcStoreName}
+	if !slices.Equal(got, want) {
+		t.Errorf("got path %v, want %v", got, want)
+	}
+
+	unused, err := root.Package(unusedStoreName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path := DependencyPath(hello, unused); path != nil {
+		t.Errorf("got path %v to a package outside of the closure, want nil", path)
+	}
+}
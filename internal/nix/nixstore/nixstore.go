@@ -82,6 +82,7 @@ func (r *Root) indexPkg(name string) (*Package, error) {
 	r.pkgs = append(r.pkgs, Package{
 		StoreName: name,
 		Hash:      hash,
+		root:      r,
 	})
 	r.storeHashes = append(r.storeHashes, []byte(hash))
 	pkg := &r.pkgs[i]
@@ -235,6 +236,21 @@ type Package struct {
 	// DirectDependencies are the other packages in the store that this
 	// package depends on. It does not contain transitive dependencies.
 	DirectDependencies []*Package
+
+	// root is the store that contains the package.
+	root *Root
+
+	// size caches the result of Size once sizeKnown is true.
+	size      int64
+	sizeKnown bool
+}
+
+// ReadLink returns the destination of the symbolic link at name, which is
+// relative to the package root. The destination is relative to the store root
+// (for example, "<nixhash>-<name>/bin/go") regardless of whether the link is
+// absolute or relative on disk.
+func (p *Package) ReadLink(name string) (string, error) {
+	return readLink(p.root.FS, path.Join(p.StoreName, name))
 }
 
 func (p Package) String() string {
@@ -0,0 +1,126 @@
+package nix
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/redact"
+)
+
+// PathInfo is the metadata that Nix keeps about a valid store path.
+type PathInfo struct {
+	Path       string   `json:"path"`
+	NarHash    string   `json:"narHash"`
+	NarSize    int64    `json:"narSize"`
+	References []string `json:"references"`
+	Deriver    string   `json:"deriver,omitempty"`
+}
+
+// NarHashHex returns the SHA-256 hash of the path's NAR serialization as a
+// hexadecimal string. Nix reports the hash in either its own base-32 format
+// (sha256:<base32>) or as an SRI hash (sha256-<base64>) depending on its
+// version.
+func (p PathInfo) NarHashHex() (string, error) {
+	if b64, ok := strings.CutPrefix(p.NarHash, "sha256-"); ok {
+		sum, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return "", fmt.Errorf("invalid SRI nar hash %q: %v", p.NarHash, err)
+		}
+		return hex.EncodeToString(sum), nil
+	}
+	if b32, ok := strings.CutPrefix(p.NarHash, "sha256:"); ok {
+		sum, err := decodeNixBase32(b32, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid nar hash %q: %v", p.NarHash, err)
+		}
+		return hex.EncodeToString(sum), nil
+	}
+	return "", fmt.Errorf("unsupported nar hash %q", p.NarHash)
+}
+
+// nixBase32Alphabet omits the letters e, o, t and u.
+const nixBase32Alphabet = "0123456789abcdfghijklmnpqrsvwxyz"
+
+// decodeNixBase32 decodes Nix's variant of base-32, which uses a different
+// alphabet and bit order than RFC 4648.
+//
+// See https://github.com/NixOS/nix/blob/master/src/libutil/hash.cc
+func decodeNixBase32(s string, size int) ([]byte, error) {
+	if len(s) != (size*8-1)/5+1 {
+		return nil, fmt.Errorf("got %d characters, want %d", len(s), (size*8-1)/5+1)
+	}
+	out := make([]byte, size)
+	for n := 0; n < len(s); n++ {
+		digit := strings.IndexByte(nixBase32Alphabet, s[len(s)-n-1])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid character %q", s[len(s)-n-1])
+		}
+		b := n * 5
+		i, j := b/8, uint(b%8)
+		out[i] |= byte(digit << j)
+		if carry := byte(digit >> (8 - j)); i < size-1 {
+			out[i+1] |= carry
+		} else if carry != 0 {
+			return nil, errors.New("invalid trailing bits")
+		}
+	}
+	return out, nil
+}
+
+// PathInfos returns the path info of every store path in the closure of
+// paths, sorted by path.
+func PathInfos(ctx context.Context, paths ...string) ([]PathInfo, error) {
+	defer debug.FunctionTimer().End()
+
+	cmd := commandContext(ctx, append([]string{"path-info", "--json", "--recursive"}, paths...)...)
+	debug.Log("Running cmd %s", cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr := (&exec.ExitError{}); errors.As(err, &exitErr) {
+			return nil, redact.Errorf(
+				"nix path-info exit code: %d, output: %s, err: %w",
+				redact.Safe(exitErr.ExitCode()),
+				exitErr.Stderr,
+				err,
+			)
+		}
+		return nil, err
+	}
+	return parsePathInfos(out)
+}
+
+// parsePathInfos parses the output of `nix path-info --json`. It's
+// decomposed out of PathInfos to make it testable.
+func parsePathInfos(data []byte) ([]PathInfo, error) {
+	var infos []PathInfo
+
+	// Newer nix versions (2.19+) output an object keyed by path.
+	var byPath map[string]*PathInfo
+	if err := json.Unmarshal(data, &byPath); err == nil {
+		for path, info := range byPath {
+			if info == nil {
+				// Invalid (missing) paths have a null value.
+				continue
+			}
+			info.Path = path
+			infos = append(infos, *info)
+		}
+	} else if err := json.Unmarshal(data, &infos); err != nil {
+		// Older nix versions output a list.
+		return nil, redact.Errorf("parse nix path-info output: %w", err)
+	}
+
+	slices.SortFunc(infos, func(a, b PathInfo) int { return strings.Compare(a.Path, b.Path) })
+	for i := range infos {
+		slices.Sort(infos[i].References)
+	}
+	return infos, nil
+}
@@ -0,0 +1,77 @@
+package nix
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePathInfos(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{
+			name: "nix-2-20",
+			input: `{
+				"/nix/store/fgkl3qk8p5hnd07b0dhzfky3ys5gxjmq-go-1.22.0": {
+					"narHash": "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+					"narSize": 10,
+					"references": ["/nix/store/xwl0am98klc8mz074jdyvpnyc6vwzlla-tzdata"]
+				},
+				"/nix/store/xwl0am98klc8mz074jdyvpnyc6vwzlla-tzdata": {
+					"narHash": "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+					"narSize": 20,
+					"references": []
+				}
+			}`,
+		},
+		{
+			name: "nix-2-17",
+			input: `[
+				{
+					"path": "/nix/store/xwl0am98klc8mz074jdyvpnyc6vwzlla-tzdata",
+					"narHash": "sha256:0mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73",
+					"narSize": 20,
+					"references": []
+				},
+				{
+					"path": "/nix/store/fgkl3qk8p5hnd07b0dhzfky3ys5gxjmq-go-1.22.0",
+					"narHash": "sha256:0mdqa9w1p6cmli6976v4wi0sw9r4p5prkj7lzfd1877wk11c9c73",
+					"narSize": 10,
+					"references": ["/nix/store/xwl0am98klc8mz074jdyvpnyc6vwzlla-tzdata"]
+				}
+			]`,
+		},
+	}
+
+	// Both hashes are the SHA-256 of an empty string.
+	const wantHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			infos, err := parsePathInfos([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Expected no error but got error: %s", err)
+			}
+			paths := []string{infos[0].Path, infos[1].Path}
+			wantPaths := []string{
+				"/nix/store/fgkl3qk8p5hnd07b0dhzfky3ys5gxjmq-go-1.22.0",
+				"/nix/store/xwl0am98klc8mz074jdyvpnyc6vwzlla-tzdata",
+			}
+			if !slices.Equal(paths, wantPaths) {
+				t.Errorf("Expected paths %v but got %v", wantPaths, paths)
+			}
+			if infos[0].NarSize != 10 || len(infos[0].References) != 1 {
+				t.Errorf("Got unexpected path info for go: %+v", infos[0])
+			}
+			for _, info := range infos {
+				got, err := info.NarHashHex()
+				if err != nil {
+					t.Fatalf("Expected no error but got error: %s", err)
+				}
+				if got != wantHash {
+					t.Errorf("Expected nar hash %s but got %s", wantHash, got)
+				}
+			}
+		})
+	}
+}
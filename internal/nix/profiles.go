@@ -11,6 +11,8 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 	"go.jetpack.io/devbox/internal/boxcli/usererr"
@@ -94,6 +96,58 @@ func ProfileRemove(profilePath string, packageNames ...string) error {
 	return nil
 }
 
+// ProfileRollback switches a profile to one of its existing generations
+// without installing anything.
+func ProfileRollback(ctx context.Context, profilePath string, generation int) error {
+	cmd := commandContext(ctx,
+		"profile", "rollback",
+		"--profile", profilePath,
+		"--to", strconv.Itoa(generation),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return redact.Errorf("error running \"nix profile rollback\": %s: %w", out, err)
+	}
+	return nil
+}
+
+// ProfileGeneration returns the current generation of a profile, or 0 if the
+// profile doesn't exist.
+func ProfileGeneration(profilePath string) (int, error) {
+	link, err := os.Readlink(profilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return parseGeneration(profilePath, link), nil
+}
+
+// ProfileGenerationExists returns true if a profile still has a generation.
+// devbox gc removes old generations.
+func ProfileGenerationExists(profilePath string, generation int) bool {
+	_, err := os.Lstat(fmt.Sprintf("%s-%d-link", profilePath, generation))
+	return err == nil
+}
+
+// parseGeneration returns the number of a generation link, such as 3 for
+// default-3-link, or 0 if link isn't a generation of the profile.
+func parseGeneration(profilePath, link string) int {
+	number, ok := strings.CutPrefix(filepath.Base(link), filepath.Base(profilePath)+"-")
+	if !ok {
+		return 0
+	}
+	number, ok = strings.CutSuffix(number, "-link")
+	if !ok {
+		return 0
+	}
+	generation, err := strconv.Atoi(number)
+	if err != nil {
+		return 0
+	}
+	return generation
+}
+
 type manifest struct {
 	Elements []struct {
 		Priority int
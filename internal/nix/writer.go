@@ -4,44 +4,153 @@
 package nix
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
 	"strings"
+	"sync"
 
 	"go.jetpack.io/devbox/internal/debug"
+	"go.jetpack.io/devbox/internal/ux/progress"
 )
 
-// packageInstallIgnore will skip lines that have the strings in the keys of this map.
-// The boolean values inform the writer whether to log the line to debug.Log.
-var packageInstallIgnore = map[string]bool{
-	`replacing old 'devbox-development'`: false,
-	`installing 'devbox-development'`:    false,
+// Activity and result types of nix's internal-json log format.
+// https://github.com/NixOS/nix/blob/master/src/libutil/logging.hh
+const (
+	activityCopyPath     = 100
+	activityFileTransfer = 101
+	activityBuild        = 105
+	activitySubstitute   = 108
+	resultProgress       = 105
+)
+
+// nixLogLine is a line of nix's --log-format internal-json output, without
+// its "@nix " prefix.
+type nixLogLine struct {
+	Action string `json:"action"`
+	ID     int    `json:"id"`
+	Parent int    `json:"parent"`
+	Type   int    `json:"type"`
+	Msg    string `json:"msg"`
+	Fields []any  `json:"fields"`
 }
 
-type PackageInstallWriter struct {
-	io.Writer
+// progressWriter turns the output of a nix command that runs with
+// --log-format internal-json into progress events. Substitutions are reported
+// as downloads and builds as builds, both as children of a task. Messages and
+// lines that aren't in the internal-json format are written to w.
+type progressWriter struct {
+	w      io.Writer
+	parent *progress.Task
+
+	mu      sync.Mutex
+	partial []byte
+	// tasks maps the IDs of nix's activities to their tasks. Activities
+	// that are part of a substitution, such as its file transfer, map to
+	// the substitution's task so that their progress is reported for it.
+	tasks map[int]*progress.Task
+	// started are the IDs of the activities that started a task.
+	started map[int]bool
 }
 
-func (fw *PackageInstallWriter) Write(p []byte) (n int, err error) {
-	lines := strings.Split(string(p), "\n")
-	for _, line := range lines {
-		if line != "" && !fw.ignore(line) {
-			_, err = io.WriteString(fw.Writer, "\t"+line+"\n")
-			if err != nil {
-				return n, err
-			}
+func newProgressWriter(w io.Writer, parent *progress.Task) *progressWriter {
+	return &progressWriter{
+		w:       w,
+		parent:  parent,
+		tasks:   map[int]*progress.Task{},
+		started: map[int]bool{},
+	}
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	pw.partial = append(pw.partial, p...)
+	for {
+		i := bytes.IndexByte(pw.partial, '\n')
+		if i == -1 {
+			break
+		}
+		line := string(pw.partial[:i])
+		pw.partial = pw.partial[i+1:]
+		if err := pw.writeLine(line); err != nil {
+			return len(p), err
 		}
 	}
 	return len(p), nil
 }
 
-func (*PackageInstallWriter) ignore(line string) bool {
-	for filter, shouldLog := range packageInstallIgnore {
-		if strings.Contains(line, filter) {
-			if shouldLog {
-				debug.Log("Hiding output for user: %s", line)
+func (pw *progressWriter) writeLine(line string) error {
+	data, ok := strings.CutPrefix(line, "@nix ")
+	if !ok {
+		_, err := io.WriteString(pw.w, line+"\n")
+		return err
+	}
+	entry := nixLogLine{}
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		debug.Log("failed to parse nix log line %q: %v", line, err)
+		return nil
+	}
+
+	switch entry.Action {
+	case "msg":
+		_, err := io.WriteString(pw.w, entry.Msg+"\n")
+		return err
+	case "start":
+		switch entry.Type {
+		case activitySubstitute:
+			pw.tasks[entry.ID] = pw.parent.Child(progress.Download, entry.field(0))
+			pw.started[entry.ID] = true
+		case activityBuild:
+			pw.tasks[entry.ID] = pw.parent.Child(progress.Build, entry.field(0))
+			pw.started[entry.ID] = true
+		case activityCopyPath, activityFileTransfer:
+			if task := pw.tasks[entry.Parent]; task != nil {
+				pw.tasks[entry.ID] = task
 			}
-			return true
 		}
+	case "result":
+		if task := pw.tasks[entry.ID]; task != nil && entry.Type == resultProgress {
+			task.Update(entry.intField(0), entry.intField(1))
+		}
+	case "stop":
+		if task := pw.tasks[entry.ID]; task != nil && pw.started[entry.ID] {
+			task.End(nil)
+			delete(pw.started, entry.ID)
+		}
+		delete(pw.tasks, entry.ID)
+	}
+	return nil
+}
+
+// close ends the tasks that nix didn't stop, for example because it failed.
+func (pw *progressWriter) close(err error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	for id := range pw.started {
+		pw.tasks[id].End(err)
+	}
+	pw.started = map[int]bool{}
+	if len(pw.partial) > 0 {
+		_ = pw.writeLine(string(pw.partial))
+		pw.partial = nil
+	}
+}
+
+func (l *nixLogLine) field(i int) string {
+	if i >= len(l.Fields) {
+		return ""
+	}
+	s, _ := l.Fields[i].(string)
+	return s
+}
+
+func (l *nixLogLine) intField(i int) int64 {
+	if i >= len(l.Fields) {
+		return 0
 	}
-	return false
+	// JSON numbers are unmarshaled as float64.
+	n, _ := l.Fields[i].(float64)
+	return int64(n)
 }
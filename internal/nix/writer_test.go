@@ -0,0 +1,95 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package nix
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+
+	"go.jetpack.io/devbox/internal/ux/progress"
+)
+
+func TestProgressWriter(t *testing.T) {
+	noColor := color.NoColor
+	t.Cleanup(func() {
+		color.NoColor = noColor
+		progress.Init(progress.FormatText, io.Discard)
+	})
+	events := &bytes.Buffer{}
+	progress.Init(progress.FormatJSON, events)
+
+	out := &bytes.Buffer{}
+	parent := progress.Start(progress.Install, "Installing 2 packages")
+	pw := newProgressWriter(out, parent)
+	log := strings.Join([]string{
+		`@nix {"action":"start","id":1,"type":108,"fields":["/nix/store/abc-go-1.22","https://cache.nixos.org"]}`,
+		`@nix {"action":"start","id":2,"parent":1,"type":101,"fields":["https://cache.nixos.org/nar/abc.nar.xz"]}`,
+		`@nix {"action":"result","id":2,"type":105,"fields":[512,1024,0,0]}`,
+		`@nix {"action":"stop","id":2}`,
+		`@nix {"action":"stop","id":1}`,
+		`@nix {"action":"msg","level":0,"msg":"error: builder failed"}`,
+		`@nix {"action":"start","id":3,"type":105,"fields":["/nix/store/def-hello.drv"]}`,
+		`warning: not a json line`,
+		`@nix {"action":"start",`,
+	}, "\n")
+	// Write in chunks that split lines to check that they're reassembled.
+	data := []byte(log + "\n")
+	for len(data) > 0 {
+		n := min(7, len(data))
+		if _, err := pw.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	pw.close(errors.New("build failed"))
+
+	if want := "error: builder failed\nwarning: not a json line\n"; out.String() != want {
+		t.Errorf("got output %q, want %q", out, want)
+	}
+
+	type event struct {
+		typ, phase, name, err string
+		done, total           int64
+		child                 bool
+	}
+	want := []event{
+		{typ: "start", phase: "install"},
+		{typ: "start", phase: "download", name: "/nix/store/abc-go-1.22", child: true},
+		{typ: "update", phase: "download", name: "/nix/store/abc-go-1.22", done: 512, total: 1024, child: true},
+		{typ: "end", phase: "download", name: "/nix/store/abc-go-1.22", child: true},
+		{typ: "start", phase: "build", name: "/nix/store/def-hello.drv", child: true},
+		{typ: "end", phase: "build", name: "/nix/store/def-hello.drv", err: "build failed", child: true},
+	}
+	var got []event
+	dec := json.NewDecoder(events)
+	for dec.More() {
+		e := progress.Event{}
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, event{
+			typ:   string(e.Type),
+			phase: string(e.Phase),
+			name:  e.Name,
+			err:   e.Error,
+			done:  e.Done,
+			total: e.Total,
+			child: e.Parent != 0,
+		})
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d:\n%+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
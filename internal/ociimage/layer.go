@@ -0,0 +1,238 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package ociimage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.jetpack.io/devbox/internal/nix/nixstore"
+)
+
+// storeDir is where store paths are placed in the image.
+const storeDir = "nix/store"
+
+type layerBlob struct {
+	descriptor
+
+	// diffID is the digest of the uncompressed layer.
+	diffID string
+}
+
+// writeLayerBlob writes a gzipped layer containing pkgs to the layout's blob
+// directory.
+func writeLayerBlob(layoutDir string, pkgs []*nixstore.Package) (layerBlob, error) {
+	diffHash := sha256.New()
+	desc, err := writeBlob(layoutDir, mediaTypeLayer, func(w io.Writer) error {
+		zw, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
+		if err != nil {
+			return err
+		}
+		if err := writeLayer(io.MultiWriter(zw, diffHash), pkgs); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+	if err != nil {
+		return layerBlob{}, err
+	}
+	return layerBlob{descriptor: desc, diffID: digest(diffHash)}, nil
+}
+
+// writeLayer writes an uncompressed layer tarball containing pkgs to w.
+func writeLayer(w io.Writer, pkgs []*nixstore.Package) error {
+	tw := tar.NewWriter(w)
+	for _, dir := range []string{"nix", storeDir} {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     dir + "/",
+			Mode:     0o755,
+			ModTime:  epoch,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	sorted := slices.Clone(pkgs)
+	slices.SortFunc(sorted, func(a, b *nixstore.Package) int {
+		return strings.Compare(a.StoreName, b.StoreName)
+	})
+	for _, pkg := range sorted {
+		if err := writePackage(tw, pkg); err != nil {
+			return fmt.Errorf("add %s to image layer: %w", pkg.StoreName, err)
+		}
+	}
+	return tw.Close()
+}
+
+// writePackage adds the files of a store package to a layer. File modes are
+// normalized the same way that Nix normalizes them in the store.
+func writePackage(tw *tar.Writer, pkg *nixstore.Package) error {
+	return fs.WalkDir(pkg, ".", func(entryPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    path.Join(storeDir, pkg.StoreName, entryPath),
+			Mode:    0o444,
+			ModTime: epoch,
+		}
+
+		switch {
+		case entry.IsDir():
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			hdr.Mode = 0o555
+			return tw.WriteHeader(hdr)
+		case entry.Type() == fs.ModeSymlink:
+			dst, err := pkg.ReadLink(entryPath)
+			if err != nil {
+				return err
+			}
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = "/" + path.Join(storeDir, dst)
+			hdr.Mode = 0o777
+			return tw.WriteHeader(hdr)
+		case entry.Type().IsRegular():
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = info.Size()
+			if info.Mode().Perm()&0o111 != 0 {
+				hdr.Mode = 0o555
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			f, err := pkg.Open(entryPath)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		}
+		// Nix store paths can only contain directories, regular files
+		// and symlinks.
+		return fmt.Errorf("unsupported file type %s: %s", entry.Type(), entryPath)
+	})
+}
+
+// writeJSONBlob marshals v and writes it to the layout's blob directory.
+func writeJSONBlob(layoutDir, mediaType string, v any) (descriptor, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return descriptor{}, err
+	}
+	return writeBlob(layoutDir, mediaType, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// writeBlob writes the content produced by write to a content-addressed file
+// in the layout's blob directory.
+func writeBlob(layoutDir, mediaType string, write func(io.Writer) error) (descriptor, error) {
+	blobDir := filepath.Join(layoutDir, "blobs", "sha256")
+	f, err := os.CreateTemp(blobDir, ".tmp-")
+	if err != nil {
+		return descriptor{}, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	counter := &countWriter{}
+	if err := write(io.MultiWriter(f, h, counter)); err != nil {
+		return descriptor{}, err
+	}
+	if err := f.Close(); err != nil {
+		return descriptor{}, err
+	}
+
+	desc := descriptor{MediaType: mediaType, Digest: digest(h), Size: counter.n}
+	if err := os.Rename(f.Name(), filepath.Join(layoutDir, blobPath(desc.Digest))); err != nil {
+		return descriptor{}, err
+	}
+	return desc, nil
+}
+
+// writeDirArchive writes the contents of dir to w as a tar archive with
+// normalized metadata.
+func writeDirArchive(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(osPath string, entry fs.DirEntry, err error) error {
+		if err != nil || osPath == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, osPath)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    filepath.ToSlash(rel),
+			Mode:    0o644,
+			ModTime: epoch,
+		}
+		if entry.IsDir() {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			hdr.Mode = 0o755
+			return tw.WriteHeader(hdr)
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = info.Size()
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		f, err := os.Open(osPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// blobPath returns the path of a blob relative to the root of an image
+// layout.
+func blobPath(digest string) string {
+	return "blobs/sha256/" + strings.TrimPrefix(digest, "sha256:")
+}
+
+func digest(h hash.Hash) string {
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+type countWriter struct{ n int64 }
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
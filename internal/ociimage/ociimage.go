@@ -0,0 +1,249 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package ociimage builds OCI container images directly from Nix store
+// closures without a container runtime.
+//
+// Images are reproducible: building the same closure with the same
+// configuration always produces the same image digest. Every file has a fixed
+// modification time and owner, and layers are written in a stable order.
+package ociimage
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/nix/nixstore"
+)
+
+// DefaultMaxLayers is the default maximum number of layers in an image. It
+// leaves some headroom below Docker's limit of 125 layers so that users can
+// build on top of the image.
+const DefaultMaxLayers = 100
+
+// epoch is the creation time of every image and the modification time of
+// every file in it. Some tools treat a zero time as unset, so use 1 second
+// past the Unix epoch instead.
+var epoch = time.Unix(1, 0).UTC()
+
+// Image describes a container image that contains the closure of one or more
+// Nix store packages.
+type Image struct {
+	// Packages are the store packages to include in the image. Their
+	// dependencies are included automatically.
+	Packages []*nixstore.Package
+
+	// Env, Entrypoint, Cmd and WorkingDir are the image's default execution
+	// parameters.
+	Env        map[string]string
+	Entrypoint []string
+	Cmd        []string
+	WorkingDir string
+
+	// Architecture is the image's CPU architecture using Go/OCI naming (for
+	// example, "amd64" or "arm64").
+	Architecture string
+
+	// Tag is the image reference written to the image index and the
+	// Docker-compatible manifest (for example, "devbox:latest"). It's
+	// optional.
+	Tag string
+
+	// MaxLayers is the maximum number of layers in the image. If it's less
+	// than 1, then DefaultMaxLayers is used.
+	MaxLayers int
+}
+
+// Architecture converts a Nix system (for example, "x86_64-linux") to an OCI
+// architecture. It returns an error for systems that can't run Linux
+// containers.
+func Architecture(nixSystem string) (string, error) {
+	arch, kernel, _ := strings.Cut(nixSystem, "-")
+	if kernel != "linux" {
+		return "", fmt.Errorf("container images can only be built on Linux, not %s", nixSystem)
+	}
+	switch arch {
+	case "x86_64":
+		return "amd64", nil
+	case "aarch64":
+		return "arm64", nil
+	case "i686":
+		return "386", nil
+	case "armv7l", "armv6l":
+		return "arm", nil
+	case "riscv64":
+		return "riscv64", nil
+	}
+	return "", fmt.Errorf("unsupported container image architecture: %s", nixSystem)
+}
+
+// WriteLayout writes the image to dir as an [OCI image layout]. The layout
+// also contains a Docker-compatible manifest.json, so an archive of dir can be
+// loaded with `docker load`. The directory is created if it doesn't exist.
+//
+// [OCI image layout]: https://github.com/opencontainers/image-spec/blob/main/image-layout.md
+func (img *Image) WriteLayout(dir string) error {
+	if len(img.Packages) == 0 {
+		return errors.New("image has no packages")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0o755); err != nil {
+		return err
+	}
+
+	cfg := imageConfig{
+		Created:      epoch,
+		Architecture: img.Architecture,
+		OS:           "linux",
+		Config: execConfig{
+			Env:        envList(img.Env),
+			Entrypoint: img.Entrypoint,
+			Cmd:        img.Cmd,
+			WorkingDir: img.WorkingDir,
+		},
+		RootFS: rootFS{Type: "layers", DiffIDs: []string{}},
+	}
+	man := manifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeManifest,
+		Layers:        []descriptor{},
+	}
+	for _, group := range groupLayers(img.Packages, img.maxLayers()) {
+		layer, err := writeLayerBlob(dir, group)
+		if err != nil {
+			return err
+		}
+		man.Layers = append(man.Layers, layer.descriptor)
+		cfg.RootFS.DiffIDs = append(cfg.RootFS.DiffIDs, layer.diffID)
+		cfg.History = append(cfg.History, history{
+			Created:   epoch,
+			CreatedBy: "devbox",
+			Comment:   strings.Join(storeNames(group), " "),
+		})
+	}
+
+	var err error
+	man.Config, err = writeJSONBlob(dir, mediaTypeConfig, cfg)
+	if err != nil {
+		return err
+	}
+	manifestDesc, err := writeJSONBlob(dir, mediaTypeManifest, man)
+	if err != nil {
+		return err
+	}
+	if img.Tag != "" {
+		manifestDesc.Annotations = map[string]string{annotationRefName: img.Tag}
+	}
+
+	idx := index{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeIndex,
+		Manifests:     []descriptor{manifestDesc},
+	}
+	if err := writeJSONFile(filepath.Join(dir, "index.json"), idx); err != nil {
+		return err
+	}
+	err = writeJSONFile(filepath.Join(dir, "oci-layout"), layout{Version: "1.0.0"})
+	if err != nil {
+		return err
+	}
+
+	dockerManifest := []dockerManifestEntry{{
+		Config: blobPath(man.Config.Digest),
+		Layers: make([]string, len(man.Layers)),
+	}}
+	for i, layer := range man.Layers {
+		dockerManifest[0].Layers[i] = blobPath(layer.Digest)
+	}
+	if img.Tag != "" {
+		dockerManifest[0].RepoTags = []string{img.Tag}
+	}
+	return writeJSONFile(filepath.Join(dir, "manifest.json"), dockerManifest)
+}
+
+// WriteArchive writes the image to w as a tar archive of its OCI image layout.
+// The archive can be loaded with `docker load` or `podman load`.
+func (img *Image) WriteArchive(w io.Writer) error {
+	dir, err := os.MkdirTemp("", "devbox-image-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := img.WriteLayout(dir); err != nil {
+		return err
+	}
+	return writeDirArchive(w, dir)
+}
+
+func (img *Image) maxLayers() int {
+	if img.MaxLayers < 1 {
+		return DefaultMaxLayers
+	}
+	return img.MaxLayers
+}
+
+// groupLayers splits the closure of pkgs into at most maxLayers groups. The
+// most popular store paths (the ones that the most other paths depend on) get
+// their own layer so that they're likely to be shared between images. All
+// remaining paths share the final layer.
+func groupLayers(pkgs []*nixstore.Package, maxLayers int) [][]*nixstore.Package {
+	closure := nixstore.Closure(pkgs...)
+	popularity := make(map[*nixstore.Package]int, len(closure))
+	for _, pkg := range closure {
+		for _, dep := range nixstore.Closure(pkg) {
+			popularity[dep]++
+		}
+	}
+	slices.SortFunc(closure, func(a, b *nixstore.Package) int {
+		return cmp.Or(
+			cmp.Compare(popularity[b], popularity[a]),
+			strings.Compare(a.StoreName, b.StoreName),
+		)
+	})
+
+	groups := make([][]*nixstore.Package, 0, min(len(closure), maxLayers))
+	for i, pkg := range closure {
+		if i < maxLayers-1 || len(closure) == maxLayers {
+			groups = append(groups, []*nixstore.Package{pkg})
+			continue
+		}
+		groups = append(groups, closure[i:])
+		break
+	}
+	return groups
+}
+
+func storeNames(pkgs []*nixstore.Package) []string {
+	names := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		names[i] = pkg.StoreName
+	}
+	return names
+}
+
+func envList(env map[string]string) []string {
+	keys := lo.Keys(env)
+	slices.Sort(keys)
+	list := make([]string, len(keys))
+	for i, k := range keys {
+		list[i] = k + "=" + env[k]
+	}
+	return list
+}
+
+func writeJSONFile(path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
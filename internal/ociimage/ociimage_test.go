@@ -0,0 +1,219 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package ociimage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.jetpack.io/devbox/internal/nix/nixstore"
+)
+
+const (
+	helloStoreName = "11111111111111111111111111111111-hello-2.12.1"
+	glibcStoreName = "22222222222222222222222222222222-glibc-2.38"
+	iconvStoreName = "33333333333333333333333333333333-libiconv-1.17"
+)
+
+// testPackages creates a fake store where hello depends on glibc and
+// libiconv, and libiconv depends on glibc. It returns the hello package.
+func testPackages(t *testing.T) []*nixstore.Package {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeFile := func(name, content string, perm os.FileMode) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(helloStoreName+"/bin/hello", "#!/nix/store/"+glibcStoreName+"/lib/ld.so", 0o755)
+	writeFile(glibcStoreName+"/lib/libc.so", "libc", 0o644)
+	writeFile(iconvStoreName+"/lib/libiconv.so", "/nix/store/"+glibcStoreName, 0o644)
+	err := os.Symlink(
+		filepath.Join(dir, iconvStoreName, "lib"),
+		filepath.Join(dir, helloStoreName, "iconv"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := nixstore.Local(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello, err := root.Package(helloStoreName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*nixstore.Package{hello}
+}
+
+func readJSON(t *testing.T, path string, v any) {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		t.Fatalf("unmarshal %s: %v", path, err)
+	}
+}
+
+func TestWriteLayoutIsReproducible(t *testing.T) {
+	pkgs := testPackages(t)
+	var indexes [2][]byte
+	for i := range indexes {
+		img := &Image{
+			Packages:     pkgs,
+			Env:          map[string]string{"PATH": "/nix/store/" + helloStoreName + "/bin", "LANG": "C.UTF-8"},
+			Entrypoint:   []string{"/nix/store/" + helloStoreName + "/bin/hello"},
+			Architecture: "amd64",
+			Tag:          "hello:latest",
+		}
+		dir := t.TempDir()
+		if err := img.WriteLayout(dir); err != nil {
+			t.Fatalf("got WriteLayout error: %v", err)
+		}
+		var err error
+		indexes[i], err = os.ReadFile(filepath.Join(dir, "index.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(indexes[0], indexes[1]) {
+		t.Errorf("got different image indexes for the same image:\n%s\n%s", indexes[0], indexes[1])
+	}
+}
+
+func TestWriteLayout(t *testing.T) {
+	img := &Image{
+		Packages:     testPackages(t),
+		Env:          map[string]string{"PATH": "/nix/store/" + helloStoreName + "/bin", "LANG": "C.UTF-8"},
+		Architecture: "arm64",
+		MaxLayers:    2,
+	}
+	dir := t.TempDir()
+	if err := img.WriteLayout(dir); err != nil {
+		t.Fatalf("got WriteLayout error: %v", err)
+	}
+
+	var idx index
+	readJSON(t, filepath.Join(dir, "index.json"), &idx)
+	if len(idx.Manifests) != 1 {
+		t.Fatalf("got %d manifests in index, want 1", len(idx.Manifests))
+	}
+	var man manifest
+	readJSON(t, filepath.Join(dir, blobPath(idx.Manifests[0].Digest)), &man)
+	var cfg imageConfig
+	readJSON(t, filepath.Join(dir, blobPath(man.Config.Digest)), &cfg)
+
+	wantEnv := []string{"LANG=C.UTF-8", "PATH=/nix/store/" + helloStoreName + "/bin"}
+	if !slices.Equal(cfg.Config.Env, wantEnv) {
+		t.Errorf("got image env %v, want %v", cfg.Config.Env, wantEnv)
+	}
+	if cfg.Architecture != "arm64" {
+		t.Errorf("got image architecture %q, want %q", cfg.Architecture, "arm64")
+	}
+	if len(man.Layers) != 2 || len(cfg.RootFS.DiffIDs) != 2 {
+		t.Fatalf("got %d layers and %d diff IDs, want 2 of each",
+			len(man.Layers), len(cfg.RootFS.DiffIDs))
+	}
+
+	// glibc is the most popular path, so it gets its own layer and the rest
+	// of the closure goes in the last layer.
+	first := layerEntries(t, filepath.Join(dir, blobPath(man.Layers[0].Digest)))
+	if _, ok := first["nix/store/"+glibcStoreName+"/lib/libc.so"]; !ok {
+		t.Errorf("first layer is missing glibc, got entries: %v", first)
+	}
+	last := layerEntries(t, filepath.Join(dir, blobPath(man.Layers[1].Digest)))
+	hello := last["nix/store/"+helloStoreName+"/bin/hello"]
+	if hello == nil {
+		t.Fatalf("last layer is missing hello, got entries: %v", last)
+	}
+	if hello.Mode != 0o555 || hello.ModTime.Unix() != epoch.Unix() {
+		t.Errorf("got hello mode %o and mtime %v, want mode 555 and mtime %v",
+			hello.Mode, hello.ModTime, epoch)
+	}
+	link := last["nix/store/"+helloStoreName+"/iconv"]
+	if link == nil || link.Linkname != "/nix/store/"+iconvStoreName+"/lib" {
+		t.Errorf("got iconv symlink %+v, want it to point to /nix/store/%s/lib", link, iconvStoreName)
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	img := &Image{Packages: testPackages(t), Architecture: "amd64", Tag: "hello:latest"}
+	buf := &bytes.Buffer{}
+	if err := img.WriteArchive(buf); err != nil {
+		t.Fatalf("got WriteArchive error: %v", err)
+	}
+
+	tr := tar.NewReader(buf)
+	var dockerManifest []dockerManifestEntry
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name == "manifest.json" {
+			if err := json.NewDecoder(tr).Decode(&dockerManifest); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if len(dockerManifest) != 1 || !slices.Equal(dockerManifest[0].RepoTags, []string{"hello:latest"}) {
+		t.Errorf("got docker manifest %+v, want a single image tagged hello:latest", dockerManifest)
+	}
+}
+
+func TestArchitecture(t *testing.T) {
+	if got, err := Architecture("aarch64-linux"); err != nil || got != "arm64" {
+		t.Errorf("got Architecture(aarch64-linux) = %q, %v, want arm64", got, err)
+	}
+	if _, err := Architecture("aarch64-darwin"); err == nil {
+		t.Error("got nil error for Architecture(aarch64-darwin), want an error")
+	}
+}
+
+func layerEntries(t *testing.T, path string) map[string]*tar.Header {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]*tar.Header{}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[hdr.Name] = hdr
+	}
+}
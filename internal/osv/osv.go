@@ -0,0 +1,249 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package osv matches Nix packages against an offline database of security
+// advisories in the Open Source Vulnerability (OSV) format.
+//
+// See https://ossf.github.io/osv-schema/
+package osv
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Advisory is an OSV vulnerability entry. Only the fields that devbox uses
+// are included.
+type Advisory struct {
+	ID               string           `json:"id"`
+	Aliases          []string         `json:"aliases,omitempty"`
+	Summary          string           `json:"summary,omitempty"`
+	Details          string           `json:"details,omitempty"`
+	Withdrawn        string           `json:"withdrawn,omitempty"`
+	Severity         []SeverityScore  `json:"severity,omitempty"`
+	Affected         []Affected       `json:"affected"`
+	DatabaseSpecific databaseSpecific `json:"database_specific,omitempty"`
+}
+
+// SeverityScore is a severity score in a standard scoring system, such as a
+// CVSS vector.
+type SeverityScore struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+// Affected describes the versions of a single package that an advisory
+// applies to.
+type Affected struct {
+	Package  AffectedPackage `json:"package"`
+	Severity []SeverityScore `json:"severity,omitempty"`
+	Ranges   []Range         `json:"ranges,omitempty"`
+	Versions []string        `json:"versions,omitempty"`
+}
+
+type AffectedPackage struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	PURL      string `json:"purl,omitempty"`
+}
+
+// Range is a range of affected versions described by a sequence of events.
+type Range struct {
+	Type   string  `json:"type"`
+	Events []Event `json:"events"`
+}
+
+// Event is a change in the affected status of versions. Exactly one field is
+// set.
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// databaseSpecific holds the qualitative severity that databases such as the
+// GitHub Advisory Database attach to advisories.
+type databaseSpecific struct {
+	Severity string `json:"severity,omitempty"`
+}
+
+// Database is a set of advisories loaded from disk.
+type Database struct {
+	Advisories []*Advisory
+}
+
+// Load reads advisories from a JSON file or a directory of JSON files. A file
+// may contain a single advisory or a list of advisories.
+func Load(path string) (*Database, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !fi.IsDir() {
+		db := &Database{}
+		return db, db.loadFile(path)
+	}
+
+	db := &Database{}
+	err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		return db.loadFile(path)
+	})
+	return db, errors.WithStack(err)
+}
+
+func (db *Database) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var advisories []*Advisory
+	if err := json.Unmarshal(data, &advisories); err != nil {
+		advisory := &Advisory{}
+		if err := json.Unmarshal(data, advisory); err != nil {
+			return errors.Wrapf(err, "parse OSV advisory %s", path)
+		}
+		advisories = []*Advisory{advisory}
+	}
+	db.Advisories = append(db.Advisories, advisories...)
+	return nil
+}
+
+// Match is an advisory that applies to a package version.
+type Match struct {
+	Advisory *Advisory
+	Severity Severity
+
+	// Fixed is the lowest version that fixes the vulnerability, if known.
+	Fixed string
+}
+
+// Match returns the advisories that affect a version of a Nix package, sorted
+// by advisory ID. Withdrawn advisories are ignored.
+func (db *Database) Match(name, version string) []Match {
+	matches := []Match{}
+	for _, advisory := range db.Advisories {
+		if advisory.Withdrawn != "" {
+			continue
+		}
+		for _, affected := range advisory.Affected {
+			if !affected.Package.matches(name) {
+				continue
+			}
+			if ok, fixed := affected.affects(version); ok {
+				matches = append(matches, Match{
+					Advisory: advisory,
+					Severity: advisory.severity(&affected),
+					Fixed:    fixed,
+				})
+				break
+			}
+		}
+	}
+	slices.SortFunc(matches, func(a, b Match) int {
+		return strings.Compare(a.Advisory.ID, b.Advisory.ID)
+	})
+	return matches
+}
+
+// matches reports if the affected package refers to a Nix package. Advisories
+// for other ecosystems, such as a Linux distribution's package of the same
+// name, are ignored.
+func (p *AffectedPackage) matches(name string) bool {
+	if purl, ok := strings.CutPrefix(p.PURL, "pkg:nix/"); ok {
+		purl, _, _ = strings.Cut(purl, "?")
+		purl, _, _ = strings.Cut(purl, "@")
+		return purl == name
+	}
+	switch strings.ToLower(p.Ecosystem) {
+	case "", "nix", "nixpkgs":
+		return p.Name == name
+	}
+	return false
+}
+
+// affects reports if version is affected. If it is, it also returns the first
+// fixed version after it, if there is one.
+func (a *Affected) affects(version string) (bool, string) {
+	if slices.Contains(a.Versions, version) {
+		return true, ""
+	}
+	for _, r := range a.Ranges {
+		if r.Type != "ECOSYSTEM" && r.Type != "SEMVER" {
+			// GIT ranges refer to commits, which we don't know.
+			continue
+		}
+		if ok, fixed := r.affects(version); ok {
+			return true, fixed
+		}
+	}
+	return false, ""
+}
+
+// affects evaluates the range's events in version order as described by the
+// OSV schema.
+func (r *Range) affects(version string) (bool, string) {
+	events := slices.Clone(r.Events)
+	slices.SortStableFunc(events, func(a, b Event) int {
+		return compareEventVersions(a.version(), b.version())
+	})
+
+	affected := false
+	for _, e := range events {
+		switch {
+		case e.Introduced != "":
+			if e.Introduced == "0" || CompareVersions(version, e.Introduced) >= 0 {
+				affected = true
+			}
+		case e.Fixed != "":
+			if CompareVersions(version, e.Fixed) >= 0 {
+				affected = false
+			} else if affected {
+				return true, e.Fixed
+			}
+		case e.LastAffected != "":
+			if CompareVersions(version, e.LastAffected) > 0 {
+				affected = false
+			}
+		}
+	}
+	return affected, ""
+}
+
+func (e Event) version() string {
+	switch {
+	case e.Introduced != "":
+		return e.Introduced
+	case e.Fixed != "":
+		return e.Fixed
+	case e.LastAffected != "":
+		return e.LastAffected
+	}
+	return e.Limit
+}
+
+// compareEventVersions orders event versions. "0" means "all versions" in
+// introduced events, so it sorts before everything else.
+func compareEventVersions(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "0":
+		return -1
+	case b == "0":
+		return 1
+	}
+	return CompareVersions(a, b)
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package osv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "2.3", -1},
+		{"2.1", "2.3", -1},
+		{"2.3", "2.3.1", -1},
+		{"2.3.1", "2.3a", 1},
+		{"2.3pre1", "2.3", -1},
+		{"2.3pre3", "2.3pre12", -1},
+		{"2.3a", "2.3c", -1},
+		{"2.3pre1", "2.3c", -1},
+		{"2.3pre1", "2.3q", -1},
+		{"3.0.12", "3.0.9", 1},
+		{"1.1.1w", "1.1.1v", 1},
+		{"2.38-44", "2.38-27", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := CompareVersions(tt.b, tt.a); got != -tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestCVSS3BaseScore(t *testing.T) {
+	tests := []struct {
+		vector string
+		want   float64
+	}{
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", 10},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", 6.1},
+		{"CVSS:3.0/AV:L/AC:H/PR:L/UI:N/S:U/C:L/I:N/A:N", 2.5},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", 0},
+	}
+	for _, tt := range tests {
+		got, err := cvss3BaseScore(tt.vector)
+		if err != nil {
+			t.Errorf("cvss3BaseScore(%q) error: %v", tt.vector, err)
+		} else if got != tt.want {
+			t.Errorf("cvss3BaseScore(%q) = %v, want %v", tt.vector, got, tt.want)
+		}
+	}
+	if _, err := cvss3BaseScore("CVSS:3.1/AV:N"); err == nil {
+		t.Error("got nil error for an incomplete vector")
+	}
+}
+
+const testAdvisories = `[
+  {
+    "id": "DEVBOX-2024-0001",
+    "summary": "openssl is broken",
+    "severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}],
+    "affected": [{
+      "package": {"ecosystem": "Nix", "name": "openssl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [
+        {"fixed": "3.0.12"}, {"introduced": "3.0.0"}, {"introduced": "0"}, {"fixed": "1.1.1w"}
+      ]}]
+    }]
+  },
+  {
+    "id": "DEVBOX-2024-0002",
+    "database_specific": {"severity": "MODERATE"},
+    "affected": [{
+      "package": {"ecosystem": "Nix", "name": "curl", "purl": "pkg:nix/curl"},
+      "ranges": [{"type": "SEMVER", "events": [{"introduced": "8.0.0"}, {"last_affected": "8.4.0"}]}]
+    }]
+  },
+  {
+    "id": "DEVBOX-2024-0003",
+    "withdrawn": "2024-01-01T00:00:00Z",
+    "affected": [{"package": {"name": "curl"}, "versions": ["8.1.0"]}]
+  },
+  {
+    "id": "DEBIAN-2024-0004",
+    "affected": [{"package": {"ecosystem": "Debian", "name": "curl"}, "versions": ["8.1.0"]}]
+  }
+]`
+
+func TestMatch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "advisories.json"), []byte(testAdvisories), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	single := `{"id": "DEVBOX-2024-0005", "affected": [{"package": {"name": "curl"}, "versions": ["8.1.0"]}]}`
+	if err := os.WriteFile(filepath.Join(dir, "single.json"), []byte(single), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.Advisories) != 5 {
+		t.Fatalf("got %d advisories, want 5", len(db.Advisories))
+	}
+
+	tests := []struct {
+		name, version string
+		want          []Match
+	}{
+		{"openssl", "1.1.1v", []Match{{Severity: SeverityCritical, Fixed: "1.1.1w"}}},
+		{"openssl", "1.1.1w", nil},
+		{"openssl", "3.0.9", []Match{{Severity: SeverityCritical, Fixed: "3.0.12"}}},
+		{"openssl", "3.0.12", nil},
+		{"curl", "7.9.0", nil},
+		{"curl", "8.1.0", []Match{{Severity: SeverityMedium}, {Severity: SeverityUnknown}}},
+		{"curl", "8.4.0", []Match{{Severity: SeverityMedium}}},
+		{"curl", "8.4.1", nil},
+		{"hello", "1.0", nil},
+	}
+	for _, tt := range tests {
+		got := db.Match(tt.name, tt.version)
+		if len(got) != len(tt.want) {
+			t.Errorf("Match(%q, %q) got %d matches, want %d", tt.name, tt.version, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if got[i].Severity != tt.want[i].Severity || got[i].Fixed != tt.want[i].Fixed {
+				t.Errorf("Match(%q, %q)[%d] = %s (severity %s, fixed %q), want severity %s, fixed %q",
+					tt.name, tt.version, i, got[i].Advisory.ID, got[i].Severity, got[i].Fixed,
+					tt.want[i].Severity, tt.want[i].Fixed)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package osv
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Severity is the qualitative severity of a vulnerability.
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = []string{"unknown", "low", "medium", "high", "critical"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity parses a qualitative severity such as "high". It accepts
+// "moderate" as an alias for "medium", which is what the GitHub Advisory
+// Database uses.
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "unknown", "":
+		return SeverityUnknown, nil
+	case "low":
+		return SeverityLow, nil
+	case "medium", "moderate":
+		return SeverityMedium, nil
+	case "high":
+		return SeverityHigh, nil
+	case "critical":
+		return SeverityCritical, nil
+	}
+	return SeverityUnknown, fmt.Errorf("unknown severity %q", s)
+}
+
+// severityFromScore converts a CVSS base score to its qualitative rating.
+func severityFromScore(score float64) Severity {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	}
+	return SeverityUnknown
+}
+
+// severity returns the highest severity that the advisory or the affected
+// package declares, either as a CVSS v3 vector or score, or as a qualitative
+// rating in database_specific.
+func (a *Advisory) severity(affected *Affected) Severity {
+	sev, _ := ParseSeverity(a.DatabaseSpecific.Severity)
+	for _, s := range slices.Concat(affected.Severity, a.Severity) {
+		var score float64
+		var err error
+		if strings.HasPrefix(s.Score, "CVSS:3") {
+			score, err = cvss3BaseScore(s.Score)
+		} else {
+			score, err = strconv.ParseFloat(s.Score, 64)
+		}
+		if err == nil {
+			sev = max(sev, severityFromScore(score))
+		}
+	}
+	return sev
+}
+
+// cvss3BaseScore computes the base score of a CVSS v3.0 or v3.1 vector such
+// as "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H".
+//
+// See https://www.first.org/cvss/v3.1/specification-document#7-1-Base-Metrics-Equations
+func cvss3BaseScore(vector string) (float64, error) {
+	metrics := map[string]string{}
+	for _, part := range strings.Split(vector, "/")[1:] {
+		k, v, ok := strings.Cut(part, ":")
+		if !ok {
+			return 0, fmt.Errorf("invalid CVSS vector %q", vector)
+		}
+		metrics[k] = v
+	}
+
+	weights := map[string]map[string]float64{
+		"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+		"AC": {"L": 0.77, "H": 0.44},
+		"UI": {"N": 0.85, "R": 0.62},
+		"C":  {"H": 0.56, "L": 0.22, "N": 0},
+		"I":  {"H": 0.56, "L": 0.22, "N": 0},
+		"A":  {"H": 0.56, "L": 0.22, "N": 0},
+	}
+	changed := metrics["S"] == "C"
+	if changed {
+		weights["PR"] = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}
+	} else {
+		weights["PR"] = map[string]float64{"N": 0.85, "L": 0.62, "H": 0.27}
+	}
+	w := map[string]float64{}
+	for metric, values := range weights {
+		v, ok := values[metrics[metric]]
+		if !ok {
+			return 0, fmt.Errorf("invalid CVSS vector %q: bad or missing %s", vector, metric)
+		}
+		w[metric] = v
+	}
+
+	iss := 1 - (1-w["C"])*(1-w["I"])*(1-w["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+	exploitability := 8.22 * w["AV"] * w["AC"] * w["PR"] * w["UI"]
+	if changed {
+		return roundUp(min(1.08*(impact+exploitability), 10)), nil
+	}
+	return roundUp(min(impact+exploitability, 10)), nil
+}
+
+// roundUp returns the smallest number with one decimal place that's greater
+// than or equal to x, avoiding floating point errors as the CVSS
+// specification describes.
+func roundUp(x float64) float64 {
+	i := int64(math.Round(x * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package osv
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two Nix package versions the same way as Nix's
+// builtins.compareVersions. It returns -1, 0 or 1 if a is older than, the
+// same as, or newer than b.
+//
+// Versions are split into components at dots, dashes and boundaries between
+// digits and other characters. Numeric components compare as numbers and are
+// newer than non-numeric ones, except that "pre" is older than anything and a
+// missing component is older than a number. This makes 1.2pre1 < 1.2 < 1.2.1.
+//
+// See https://nix.dev/manual/nix/stable/language/builtins#builtins-compareVersions
+func CompareVersions(a, b string) int {
+	for a != "" || b != "" {
+		var ca, cb string
+		ca, a = nextVersionComponent(a)
+		cb, b = nextVersionComponent(b)
+		switch {
+		case componentLess(ca, cb):
+			return -1
+		case componentLess(cb, ca):
+			return 1
+		}
+	}
+	return 0
+}
+
+func nextVersionComponent(v string) (component, rest string) {
+	v = strings.TrimLeft(v, ".-")
+	if v == "" {
+		return "", ""
+	}
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	end := 1
+	if isDigit(v[0]) {
+		for end < len(v) && isDigit(v[end]) {
+			end++
+		}
+	} else {
+		for end < len(v) && !isDigit(v[end]) && v[end] != '.' && v[end] != '-' {
+			end++
+		}
+	}
+	return v[:end], v[end:]
+}
+
+func componentLess(a, b string) bool {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return na < nb
+	case a == "" && errB == nil:
+		return true
+	case a == "pre" && b != "pre":
+		return true
+	case b == "pre":
+		return false
+	case errA == nil:
+		return false
+	case errB == nil:
+		return true
+	}
+	return a < b
+}
@@ -0,0 +1,44 @@
+package plugin
+
+import (
+	"os"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/tailscale/hujson"
+)
+
+// MigrateReadme renames the deprecated readme field in the plugin.json of a
+// local plugin to description. If the plugin already has a description, the
+// readme is removed. Comments and formatting are preserved. It returns false
+// if the plugin doesn't have a readme field.
+func (l *LocalPlugin) MigrateReadme() (bool, error) {
+	path := addFilenameIfMissing(l.Path())
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	root, err := hujson.Parse(content)
+	if err != nil {
+		return false, errors.Wrapf(err, "parse %s", path)
+	}
+	obj, ok := root.Value.(*hujson.Object)
+	if !ok {
+		return false, nil
+	}
+	memberIndex := func(name string) int {
+		return slices.IndexFunc(obj.Members, func(m hujson.ObjectMember) bool {
+			return m.Name.Value.(hujson.Literal).String() == name
+		})
+	}
+	i := memberIndex("readme")
+	if i == -1 {
+		return false, nil
+	}
+	if memberIndex("description") == -1 {
+		obj.Members[i].Name.Value = hujson.String("description")
+	} else {
+		obj.Members = slices.Delete(obj.Members, i, i+1)
+	}
+	return true, errors.WithStack(os.WriteFile(path, root.Pack(), 0o644))
+}
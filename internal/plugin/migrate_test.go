@@ -0,0 +1,55 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.jetpack.io/devbox/nix/flake"
+)
+
+func TestMigrateReadme(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+		migrated bool
+	}{
+		{
+			name:     "rename readme",
+			content:  "{\n  \"name\": \"my-plugin\",\n  // docs\n  \"readme\": \"Hello\"\n}\n",
+			expected: "{\n  \"name\": \"my-plugin\",\n  // docs\n  \"description\": \"Hello\"\n}\n",
+			migrated: true,
+		},
+		{
+			name:     "remove readme with description",
+			content:  `{"name": "my-plugin", "description": "Hi", "readme": "Hello"}`,
+			expected: `{"name": "my-plugin", "description": "Hi"}`,
+			migrated: true,
+		},
+		{
+			name:     "no readme",
+			content:  `{"name": "my-plugin", "description": "Hi"}`,
+			expected: `{"name": "my-plugin", "description": "Hi"}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, pluginConfigName)
+			require.NoError(t, os.WriteFile(path, []byte(testCase.content), 0o644))
+			plugin, err := newLocalPlugin(flake.Ref{Type: flake.TypePath, Path: "."}, dir)
+			require.NoError(t, err)
+
+			migrated, err := plugin.MigrateReadme()
+			require.NoError(t, err)
+			assert.Equal(t, testCase.migrated, migrated)
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, string(content))
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package sbom
+
+import (
+	"time"
+)
+
+// CycloneDX 1.5 JSON types. Only the fields that devbox populates are
+// included.
+//
+// See https://cyclonedx.org/docs/1.5/json/
+type (
+	cdxDocument struct {
+		BOMFormat    string          `json:"bomFormat"`
+		SpecVersion  string          `json:"specVersion"`
+		SerialNumber string          `json:"serialNumber"`
+		Version      int             `json:"version"`
+		Metadata     cdxMetadata     `json:"metadata"`
+		Components   []cdxComponent  `json:"components"`
+		Dependencies []cdxDependency `json:"dependencies"`
+	}
+
+	cdxMetadata struct {
+		Timestamp string       `json:"timestamp"`
+		Tools     cdxTools     `json:"tools"`
+		Component cdxComponent `json:"component"`
+	}
+
+	cdxTools struct {
+		Components []cdxComponent `json:"components"`
+	}
+
+	cdxComponent struct {
+		Type               string                 `json:"type"`
+		BOMRef             string                 `json:"bom-ref,omitempty"`
+		Name               string                 `json:"name"`
+		Version            string                 `json:"version,omitempty"`
+		Description        string                 `json:"description,omitempty"`
+		Hashes             []cdxHash              `json:"hashes,omitempty"`
+		Licenses           []cdxLicenseChoice     `json:"licenses,omitempty"`
+		PURL               string                 `json:"purl,omitempty"`
+		ExternalReferences []cdxExternalReference `json:"externalReferences,omitempty"`
+		Properties         []cdxProperty          `json:"properties,omitempty"`
+	}
+
+	cdxHash struct {
+		Alg     string `json:"alg"`
+		Content string `json:"content"`
+	}
+
+	cdxLicenseChoice struct {
+		License cdxLicense `json:"license"`
+	}
+
+	cdxLicense struct {
+		ID   string `json:"id,omitempty"`
+		Name string `json:"name,omitempty"`
+	}
+
+	cdxExternalReference struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	}
+
+	cdxProperty struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	cdxDependency struct {
+		Ref       string   `json:"ref"`
+		DependsOn []string `json:"dependsOn"`
+	}
+)
+
+// cdxProjectRef is the bom-ref of the project that the document describes.
+const cdxProjectRef = "devbox-project"
+
+func newCycloneDXDocument(doc *Document) *cdxDocument {
+	out := &cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + documentUUID(doc).String(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: doc.Created.Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{{
+				Type:    "application",
+				Name:    "devbox",
+				Version: doc.ToolVersion,
+			}}},
+			Component: cdxComponent{
+				Type:   "application",
+				BOMRef: cdxProjectRef,
+				Name:   doc.Name,
+			},
+		},
+		Components:   []cdxComponent{},
+		Dependencies: []cdxDependency{},
+	}
+
+	refs := map[string]bool{}
+	for _, pkgs := range [][]Package{doc.Packages, doc.Closure} {
+		for _, pkg := range pkgs {
+			refs[pkg.ID] = true
+			out.Components = append(out.Components, newCycloneDXComponent(&pkg))
+		}
+	}
+
+	project := cdxDependency{Ref: cdxProjectRef, DependsOn: []string{}}
+	for _, pkg := range doc.Packages {
+		project.DependsOn = append(project.DependsOn, pkg.ID)
+	}
+	out.Dependencies = append(out.Dependencies, project)
+	for _, pkgs := range [][]Package{doc.Packages, doc.Closure} {
+		for _, pkg := range pkgs {
+			dep := cdxDependency{Ref: pkg.ID, DependsOn: []string{}}
+			for _, id := range pkg.DependsOn {
+				if refs[id] && id != pkg.ID {
+					dep.DependsOn = append(dep.DependsOn, id)
+				}
+			}
+			out.Dependencies = append(out.Dependencies, dep)
+		}
+	}
+	return out
+}
+
+func newCycloneDXComponent(pkg *Package) cdxComponent {
+	out := cdxComponent{
+		Type:        "library",
+		BOMRef:      pkg.ID,
+		Name:        pkg.Name,
+		Version:     pkg.Version,
+		Description: pkg.Description,
+		PURL:        pkg.purl(),
+	}
+	if pkg.NarHash != "" {
+		out.Hashes = []cdxHash{{Alg: "SHA-256", Content: pkg.NarHash}}
+	}
+	for _, l := range pkg.Licenses {
+		if l.SPDXID != "" {
+			out.Licenses = append(out.Licenses, cdxLicenseChoice{License: cdxLicense{ID: l.SPDXID}})
+		} else {
+			out.Licenses = append(out.Licenses, cdxLicenseChoice{License: cdxLicense{Name: l.Name}})
+		}
+	}
+	if pkg.Homepage != "" {
+		out.ExternalReferences = []cdxExternalReference{{Type: "website", URL: pkg.Homepage}}
+	}
+	if pkg.FlakeRef != "" {
+		out.Properties = append(out.Properties, cdxProperty{Name: "devbox:flakeRef", Value: pkg.FlakeRef})
+	}
+	if pkg.StorePath != "" {
+		out.Properties = append(out.Properties, cdxProperty{Name: "nix:storePath", Value: pkg.StorePath})
+	}
+	for _, o := range pkg.Outputs {
+		out.Properties = append(out.Properties, cdxProperty{
+			Name:  "nix:output:" + o.System + ":" + o.Name,
+			Value: o.Path,
+		})
+	}
+	return out
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package sbom writes software bills of materials for devbox environments in
+// the SPDX and CycloneDX JSON formats.
+//
+// Output is deterministic. Packages, outputs and dependencies are sorted, and
+// identifiers and timestamps are derived from the document's contents, so
+// generating an SBOM for the same lockfile twice produces identical files.
+package sbom
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+
+	"go.jetpack.io/devbox/internal/cachehash"
+)
+
+const (
+	FormatSPDX      = "spdx-json"
+	FormatCycloneDX = "cyclonedx-json"
+)
+
+// Document is a format-independent bill of materials.
+type Document struct {
+	// Name is the name of the project that the document describes.
+	Name string
+
+	// Created is the document's creation time. It should be derived from the
+	// inputs (such as the lockfile) rather than the current time to keep the
+	// output reproducible.
+	Created time.Time
+
+	// ToolVersion is the version of devbox that generated the document.
+	ToolVersion string
+
+	// Packages are the packages that the project depends on directly.
+	Packages []Package
+
+	// Closure contains the store paths in the runtime closure of Packages.
+	// It's empty unless the closure was requested.
+	Closure []Package
+}
+
+// Package is a single component in the bill of materials. It's either a
+// devbox package or a store path in the closure.
+type Package struct {
+	// ID uniquely identifies the package within the document. It's the
+	// lockfile key for devbox packages and the store path for closure
+	// entries.
+	ID          string
+	Name        string
+	Version     string
+	FlakeRef    string
+	Description string
+	Homepage    string
+	Licenses    []License
+	Outputs     []Output
+
+	// StorePath and NarHash are only set for closure entries. NarHash is the
+	// hex-encoded SHA-256 hash of the path's NAR serialization.
+	StorePath string
+	NarHash   string
+
+	// DependsOn contains the IDs of the package's direct dependencies.
+	DependsOn []string
+}
+
+// License identifies a package's license.
+type License struct {
+	// SPDXID is empty if the license isn't on the SPDX license list.
+	SPDXID string
+	Name   string
+}
+
+// Output is a store path that a package builds for a system.
+type Output struct {
+	System string
+	Name   string
+	Path   string
+}
+
+// Write writes doc to w in the given format.
+func Write(w io.Writer, format string, doc *Document) error {
+	doc.normalize()
+
+	var v any
+	switch format {
+	case FormatSPDX:
+		v = newSPDXDocument(doc)
+	case FormatCycloneDX:
+		v = newCycloneDXDocument(doc)
+	default:
+		return fmt.Errorf("unsupported SBOM format %q, must be %s or %s",
+			format, FormatSPDX, FormatCycloneDX)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// normalize sorts the document so that its output doesn't depend on the order
+// in which packages were added.
+func (doc *Document) normalize() {
+	for _, pkgs := range [][]Package{doc.Packages, doc.Closure} {
+		slices.SortFunc(pkgs, func(a, b Package) int { return strings.Compare(a.ID, b.ID) })
+		for i := range pkgs {
+			slices.SortFunc(pkgs[i].Outputs, func(a, b Output) int {
+				return cmp.Or(strings.Compare(a.System, b.System), strings.Compare(a.Name, b.Name))
+			})
+			slices.Sort(pkgs[i].DependsOn)
+			pkgs[i].DependsOn = slices.Compact(pkgs[i].DependsOn)
+		}
+	}
+	doc.Created = doc.Created.UTC().Truncate(time.Second)
+}
+
+// hash returns a stable hash of the document's contents for use in document
+// identifiers.
+func (doc *Document) hash() string {
+	h, err := cachehash.JSON(doc)
+	if err != nil {
+		// Document only contains strings, slices and a time, which
+		// always marshal successfully.
+		panic(err)
+	}
+	return h
+}
+
+// purl returns a package URL for a package. There's no registered purl type
+// for Nix yet, so this follows the proposed "nix" type.
+func (p *Package) purl() string {
+	if p.Version == "" {
+		return "pkg:nix/" + p.Name
+	}
+	return "pkg:nix/" + p.Name + "@" + p.Version
+}
+
+var nonIDChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// licenseRef returns an SPDX license reference for a license that isn't on
+// the SPDX license list.
+func licenseRef(name string) string {
+	return "LicenseRef-" + strings.Trim(nonIDChars.ReplaceAllString(name, "-"), "-")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := lo.Keys(m)
+	slices.Sort(keys)
+	return keys
+}
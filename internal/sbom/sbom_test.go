@@ -0,0 +1,168 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	helloPath = "/nix/store/11111111111111111111111111111111-hello-2.12.1"
+	glibcPath = "/nix/store/22222222222222222222222222222222-glibc-2.38-44"
+)
+
+func testDocument() *Document {
+	return &Document{
+		Name:        "test-project",
+		Created:     time.Date(2024, 2, 10, 18, 15, 24, 0, time.UTC),
+		ToolVersion: "0.10.0",
+		Packages: []Package{
+			{
+				ID:       "hello@latest",
+				Name:     "hello",
+				Version:  "2.12.1",
+				FlakeRef: "github:NixOS/nixpkgs/0123456789abcdef#hello",
+				Homepage: "https://www.gnu.org/software/hello/",
+				Licenses: []License{{SPDXID: "GPL-3.0-or-later", Name: "GPL-3.0-or-later"}},
+				Outputs: []Output{
+					{System: "x86_64-linux", Name: "out", Path: helloPath},
+					{System: "aarch64-darwin", Name: "out", Path: "/nix/store/33333333333333333333333333333333-hello-2.12.1"},
+				},
+				DependsOn: []string{helloPath},
+			},
+			{
+				ID:       "custom@1.0",
+				Name:     "custom",
+				Version:  "1.0",
+				Licenses: []License{{Name: "Custom License"}},
+			},
+		},
+		Closure: []Package{
+			{ID: helloPath, Name: "hello", Version: "2.12.1", StorePath: helloPath, NarHash: "aa", DependsOn: []string{glibcPath, helloPath}},
+			{ID: glibcPath, Name: "glibc", Version: "2.38-44", StorePath: glibcPath, NarHash: "bb", DependsOn: []string{glibcPath}},
+		},
+	}
+}
+
+func TestWriteDeterministic(t *testing.T) {
+	for _, format := range []string{FormatSPDX, FormatCycloneDX} {
+		t.Run(format, func(t *testing.T) {
+			want := &bytes.Buffer{}
+			if err := Write(want, format, testDocument()); err != nil {
+				t.Fatal(err)
+			}
+
+			shuffled := testDocument()
+			slices.Reverse(shuffled.Packages)
+			slices.Reverse(shuffled.Closure)
+			slices.Reverse(shuffled.Packages[1].Outputs)
+			got := &bytes.Buffer{}
+			if err := Write(got, format, shuffled); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("output depends on input order:\n%s\n\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteSPDX(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Write(buf, FormatSPDX, testDocument()); err != nil {
+		t.Fatal(err)
+	}
+	doc := &spdxDocument{}
+	if err := json.Unmarshal(buf.Bytes(), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := doc.CreationInfo.Created, "2024-02-10T18:15:24Z"; got != want {
+		t.Errorf("got created = %q, want %q", got, want)
+	}
+	if len(doc.Packages) != 4 {
+		t.Fatalf("got %d packages, want 4", len(doc.Packages))
+	}
+	custom := doc.Packages[0]
+	if got, want := custom.LicenseDeclared, "LicenseRef-Custom-License"; got != want {
+		t.Errorf("got license = %q, want %q", got, want)
+	}
+	if len(doc.ExtractedLicenses) != 1 || doc.ExtractedLicenses[0].LicenseID != custom.LicenseDeclared {
+		t.Errorf("got extracted licenses %+v, want %s", doc.ExtractedLicenses, custom.LicenseDeclared)
+	}
+	hello := doc.Packages[1]
+	if got, want := hello.ExternalRefs[0].ReferenceLocator, "pkg:nix/hello@2.12.1"; got != want {
+		t.Errorf("got purl = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(hello.Comment, "Outputs:\naarch64-darwin out: ") {
+		t.Errorf("got comment %q, want outputs sorted by system", hello.Comment)
+	}
+
+	ids := map[string]bool{spdxDocumentID: true}
+	for _, pkg := range doc.Packages {
+		if ids[pkg.SPDXID] {
+			t.Errorf("duplicate SPDXID %s", pkg.SPDXID)
+		}
+		ids[pkg.SPDXID] = true
+	}
+	dependsOn := 0
+	for _, rel := range doc.Relationships {
+		if !ids[rel.SPDXElementID] || !ids[rel.RelatedSPDXElement] {
+			t.Errorf("relationship %+v refers to an unknown element", rel)
+		}
+		if rel.RelationshipType == "DEPENDS_ON" {
+			dependsOn++
+		}
+	}
+	// hello@latest -> hello, hello -> glibc. Self references are dropped.
+	if dependsOn != 2 {
+		t.Errorf("got %d DEPENDS_ON relationships, want 2", dependsOn)
+	}
+}
+
+func TestWriteCycloneDX(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Write(buf, FormatCycloneDX, testDocument()); err != nil {
+		t.Fatal(err)
+	}
+	doc := &cdxDocument{}
+	if err := json.Unmarshal(buf.Bytes(), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(doc.SerialNumber, "urn:uuid:") {
+		t.Errorf("got serial number %q, want a UUID URN", doc.SerialNumber)
+	}
+	if len(doc.Components) != 4 {
+		t.Fatalf("got %d components, want 4", len(doc.Components))
+	}
+	if got := doc.Components[0].Licenses[0].License; got.Name != "Custom License" || got.ID != "" {
+		t.Errorf("got license %+v, want name Custom License", got)
+	}
+	if got := doc.Components[3].Hashes; len(got) != 1 || got[0].Content != "bb" {
+		t.Errorf("got hashes %+v, want glibc's nar hash", got)
+	}
+
+	deps := map[string][]string{}
+	for _, dep := range doc.Dependencies {
+		deps[dep.Ref] = dep.DependsOn
+	}
+	if got, want := deps[cdxProjectRef], []string{"custom@1.0", "hello@latest"}; !slices.Equal(got, want) {
+		t.Errorf("got project dependencies %v, want %v", got, want)
+	}
+	if got, want := deps[helloPath], []string{glibcPath}; !slices.Equal(got, want) {
+		t.Errorf("got hello dependencies %v, want %v", got, want)
+	}
+}
+
+func TestWriteUnsupportedFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "xml", testDocument()); err == nil {
+		t.Error("got nil error for an unsupported format")
+	}
+}
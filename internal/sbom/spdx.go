@@ -0,0 +1,201 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package sbom
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go.jetpack.io/devbox/internal/cachehash"
+)
+
+// SPDX 2.3 JSON types. Only the fields that devbox populates are included.
+//
+// See https://spdx.github.io/spdx-spec/v2.3/
+type (
+	spdxDocument struct {
+		SPDXVersion       string                 `json:"spdxVersion"`
+		DataLicense       string                 `json:"dataLicense"`
+		SPDXID            string                 `json:"SPDXID"`
+		Name              string                 `json:"name"`
+		DocumentNamespace string                 `json:"documentNamespace"`
+		CreationInfo      spdxCreationInfo       `json:"creationInfo"`
+		Packages          []spdxPackage          `json:"packages"`
+		Relationships     []spdxRelationship     `json:"relationships"`
+		ExtractedLicenses []spdxExtractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+	}
+
+	spdxCreationInfo struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	}
+
+	spdxPackage struct {
+		SPDXID           string            `json:"SPDXID"`
+		Name             string            `json:"name"`
+		VersionInfo      string            `json:"versionInfo,omitempty"`
+		PackageFileName  string            `json:"packageFileName,omitempty"`
+		DownloadLocation string            `json:"downloadLocation"`
+		FilesAnalyzed    bool              `json:"filesAnalyzed"`
+		Homepage         string            `json:"homepage,omitempty"`
+		SourceInfo       string            `json:"sourceInfo,omitempty"`
+		LicenseConcluded string            `json:"licenseConcluded"`
+		LicenseDeclared  string            `json:"licenseDeclared"`
+		CopyrightText    string            `json:"copyrightText"`
+		Summary          string            `json:"summary,omitempty"`
+		Comment          string            `json:"comment,omitempty"`
+		Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+		ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+	}
+
+	spdxChecksum struct {
+		Algorithm     string `json:"algorithm"`
+		ChecksumValue string `json:"checksumValue"`
+	}
+
+	spdxExternalRef struct {
+		ReferenceCategory string `json:"referenceCategory"`
+		ReferenceType     string `json:"referenceType"`
+		ReferenceLocator  string `json:"referenceLocator"`
+	}
+
+	spdxRelationship struct {
+		SPDXElementID      string `json:"spdxElementId"`
+		RelationshipType   string `json:"relationshipType"`
+		RelatedSPDXElement string `json:"relatedSpdxElement"`
+	}
+
+	spdxExtractedLicense struct {
+		LicenseID     string `json:"licenseId"`
+		Name          string `json:"name"`
+		ExtractedText string `json:"extractedText"`
+	}
+)
+
+const (
+	spdxDocumentID  = "SPDXRef-DOCUMENT"
+	spdxNoAssertion = "NOASSERTION"
+)
+
+func newSPDXDocument(doc *Document) *spdxDocument {
+	out := &spdxDocument{
+		SPDXVersion: "SPDX-2.3",
+		DataLicense: "CC0-1.0",
+		SPDXID:      spdxDocumentID,
+		Name:        doc.Name,
+		DocumentNamespace: fmt.Sprintf("https://www.jetify.com/devbox/spdx/%s-%s",
+			nonIDChars.ReplaceAllString(doc.Name, "-"), documentUUID(doc)),
+		CreationInfo: spdxCreationInfo{
+			Created:  doc.Created.Format(time.RFC3339),
+			Creators: []string{"Tool: devbox-" + doc.ToolVersion},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+
+	ids := map[string]string{}
+	for _, pkgs := range [][]Package{doc.Packages, doc.Closure} {
+		for _, pkg := range pkgs {
+			ids[pkg.ID] = spdxPackageID(pkg.ID)
+		}
+	}
+
+	extracted := map[string]string{}
+	for _, pkg := range doc.Packages {
+		out.Packages = append(out.Packages, newSPDXPackage(&pkg, ids[pkg.ID], extracted))
+		out.Relationships = append(out.Relationships, spdxRelationship{
+			SPDXElementID:      spdxDocumentID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: ids[pkg.ID],
+		})
+	}
+	for _, pkg := range doc.Closure {
+		out.Packages = append(out.Packages, newSPDXPackage(&pkg, ids[pkg.ID], extracted))
+	}
+	for _, pkgs := range [][]Package{doc.Packages, doc.Closure} {
+		for _, pkg := range pkgs {
+			for _, dep := range pkg.DependsOn {
+				if depID, ok := ids[dep]; ok && depID != ids[pkg.ID] {
+					out.Relationships = append(out.Relationships, spdxRelationship{
+						SPDXElementID:      ids[pkg.ID],
+						RelationshipType:   "DEPENDS_ON",
+						RelatedSPDXElement: depID,
+					})
+				}
+			}
+		}
+	}
+
+	for _, ref := range sortedKeys(extracted) {
+		out.ExtractedLicenses = append(out.ExtractedLicenses, spdxExtractedLicense{
+			LicenseID:     ref,
+			Name:          extracted[ref],
+			ExtractedText: "The license is identified by the name " + extracted[ref] + " in nixpkgs.",
+		})
+	}
+	return out
+}
+
+// newSPDXPackage converts pkg to an SPDX package and records the license
+// references it uses in extracted.
+func newSPDXPackage(pkg *Package, id string, extracted map[string]string) spdxPackage {
+	out := spdxPackage{
+		SPDXID:           id,
+		Name:             pkg.Name,
+		VersionInfo:      pkg.Version,
+		PackageFileName:  pkg.StorePath,
+		DownloadLocation: spdxNoAssertion,
+		Homepage:         pkg.Homepage,
+		LicenseConcluded: spdxNoAssertion,
+		LicenseDeclared:  spdxNoAssertion,
+		CopyrightText:    spdxNoAssertion,
+		Summary:          pkg.Description,
+		ExternalRefs: []spdxExternalRef{{
+			ReferenceCategory: "PACKAGE-MANAGER",
+			ReferenceType:     "purl",
+			ReferenceLocator:  pkg.purl(),
+		}},
+	}
+	if pkg.FlakeRef != "" {
+		out.SourceInfo = "resolved from " + pkg.FlakeRef
+	}
+	if pkg.NarHash != "" {
+		out.Checksums = []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: pkg.NarHash}}
+	}
+	if len(pkg.Licenses) > 0 {
+		exprs := make([]string, len(pkg.Licenses))
+		for i, l := range pkg.Licenses {
+			exprs[i] = l.SPDXID
+			if l.SPDXID == "" {
+				exprs[i] = licenseRef(l.Name)
+				extracted[exprs[i]] = l.Name
+			}
+		}
+		out.LicenseDeclared = strings.Join(exprs, " AND ")
+	}
+	if len(pkg.Outputs) > 0 {
+		lines := make([]string, len(pkg.Outputs))
+		for i, o := range pkg.Outputs {
+			lines[i] = fmt.Sprintf("%s %s: %s", o.System, o.Name, o.Path)
+		}
+		out.Comment = "Outputs:\n" + strings.Join(lines, "\n")
+	}
+	return out
+}
+
+// spdxPackageID returns an SPDX element ID for a package ID. A short hash of
+// the original ID keeps the element IDs unique after replacing the characters
+// that SPDX doesn't allow.
+func spdxPackageID(id string) string {
+	return "SPDXRef-Package-" + strings.Trim(nonIDChars.ReplaceAllString(id, "-"), "-") +
+		"-" + cachehash.Bytes6([]byte(id))
+}
+
+// documentUUID returns a UUID that identifies the document's contents.
+func documentUUID(doc *Document) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(doc.hash()))
+}
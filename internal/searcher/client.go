@@ -8,10 +8,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
+	"go.jetpack.io/devbox/internal/debug"
 	"go.jetpack.io/devbox/internal/envir"
 	"go.jetpack.io/devbox/internal/redact"
 )
@@ -30,7 +36,7 @@ func Client() *client {
 	}
 }
 
-func (c *client) Search(query string) (*SearchResults, error) {
+func (c *client) Search(ctx context.Context, query string) (*SearchResults, error) {
 	if query == "" {
 		return nil, fmt.Errorf("query should not be empty")
 	}
@@ -41,12 +47,12 @@ func (c *client) Search(query string) (*SearchResults, error) {
 	}
 	searchURL := endpoint + "?q=" + url.QueryEscape(query)
 
-	return execGet[SearchResults](context.TODO(), searchURL)
+	return execGet[SearchResults](ctx, searchURL)
 }
 
 // Resolve calls the /resolve endpoint of the search service. This returns
 // the latest version of the package that matches the version constraint.
-func (c *client) Resolve(name, version string) (*PackageVersion, error) {
+func (c *client) Resolve(ctx context.Context, name, version string) (*PackageVersion, error) {
 	if name == "" || version == "" {
 		return nil, fmt.Errorf("name and version should not be empty")
 	}
@@ -59,7 +65,7 @@ func (c *client) Resolve(name, version string) (*PackageVersion, error) {
 		"?name=" + url.QueryEscape(name) +
 		"&version=" + url.QueryEscape(version)
 
-	return execGet[PackageVersion](context.TODO(), searchURL)
+	return execGet[PackageVersion](ctx, searchURL)
 }
 
 // Resolve calls the /resolve endpoint of the search service. This returns
@@ -83,33 +89,109 @@ func (c *client) ResolveV2(ctx context.Context, name, version string) (*ResolveR
 	return execGet[ResolveResponse](ctx, searchURL)
 }
 
+// MaxConcurrentRequests is the number of requests that devbox makes to the
+// search service at a time when it resolves several packages.
+const MaxConcurrentRequests = 8
+
+// httpClient is shared by every request so that concurrent resolutions reuse
+// connections instead of opening one per package.
+var httpClient = &http.Client{
+	Transport: func() http.RoundTripper {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = MaxConcurrentRequests
+		return transport
+	}(),
+}
+
+const maxAttempts = 4
+
+// retryDelay is the delay before the first retry. It doubles with each
+// attempt.
+var retryDelay = 250 * time.Millisecond
+
+// execGet sends a GET request and unmarshals the JSON response. Requests that
+// fail with a transient error are retried with exponential backoff.
 func execGet[T any](ctx context.Context, url string) (*T, error) {
+	for attempt := 1; ; attempt++ {
+		result, retry, err := get[T](ctx, url)
+		if !retry || attempt == maxAttempts {
+			return result, err
+		}
+		delay := retryDelay << (attempt - 1)
+		// Jitter keeps concurrent requests from retrying in lockstep.
+		if jitter := delay / 2; jitter > 0 {
+			delay += rand.N(jitter)
+		}
+		debug.Log("searcher: attempt %d failed, retrying in %s: %v", attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// get sends a single GET request. It reports whether the request failed with
+// an error that may go away if it's retried.
+func get[T any](ctx context.Context, url string) (result *T, retry bool, err error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, redact.Errorf("GET %s: %w", redact.Safe(url), redact.Safe(err))
+		return nil, false, redact.Errorf("GET %s: %w", redact.Safe(url), redact.Safe(err))
 	}
-	response, err := http.DefaultClient.Do(req)
+	response, err := httpClient.Do(req)
 	if err != nil {
-		return nil, redact.Errorf("GET %s: %w", redact.Safe(url), redact.Safe(err))
+		return nil, shouldRetry(ctx, err), redact.Errorf("GET %s: %w", redact.Safe(url), redact.Safe(err))
 	}
 	defer response.Body.Close()
 	data, err := io.ReadAll(response.Body)
 	if err != nil {
-		return nil, redact.Errorf("GET %s: read respoonse body: %w", redact.Safe(url), redact.Safe(err))
+		return nil, shouldRetry(ctx, err), redact.Errorf("GET %s: read respoonse body: %w", redact.Safe(url), redact.Safe(err))
 	}
 	if response.StatusCode == http.StatusNotFound {
-		return nil, ErrNotFound
+		return nil, false, ErrNotFound
 	}
 	if response.StatusCode >= 400 {
-		return nil, redact.Errorf("GET %s: unexpected status code %s: %s",
+		retry := response.StatusCode == http.StatusTooManyRequests ||
+			response.StatusCode == http.StatusBadGateway ||
+			response.StatusCode == http.StatusServiceUnavailable ||
+			response.StatusCode == http.StatusGatewayTimeout
+		return nil, retry, redact.Errorf("GET %s: unexpected status code %s: %s",
 			redact.Safe(url),
 			redact.Safe(response.Status),
 			redact.Safe(data),
 		)
 	}
-	var result T
 	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, redact.Errorf("GET %s: unmarshal response JSON: %w", redact.Safe(url), redact.Safe(err))
+		return nil, false, redact.Errorf("GET %s: unmarshal response JSON: %w", redact.Safe(url), redact.Safe(err))
+	}
+	return result, false, nil
+}
+
+// shouldRetry returns true if a request that failed with err may succeed if
+// it's retried, which is the case for connection errors. Hosts that don't
+// resolve aren't retried, so that devbox quickly notices it's offline.
+func shouldRetry(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || !IsConnectionError(err) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	return !errors.As(err, &dnsErr) || !dnsErr.IsNotFound
+}
+
+// IsConnectionError returns true if err is a network error, such as a host
+// that doesn't resolve or a refused or reset connection. Errors from nix
+// commands only have the message of the error, so it's checked as well.
+func IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
 	}
-	return &result, nil
+	return strings.Contains(err.Error(), "no such host") ||
+		strings.Contains(err.Error(), "connection refused")
 }
@@ -0,0 +1,93 @@
+package searcher
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestExecGetRetries(t *testing.T) {
+	retryDelay = time.Millisecond
+	t.Cleanup(func() { retryDelay = 250 * time.Millisecond })
+
+	tests := map[string]struct {
+		statuses     []int
+		wantAttempts int32
+		wantErr      bool
+	}{
+		"transient error":  {statuses: []int{503, 502, 200}, wantAttempts: 3},
+		"not found":        {statuses: []int{404}, wantAttempts: 1, wantErr: true},
+		"bad request":      {statuses: []int{400}, wantAttempts: 1, wantErr: true},
+		"too many retries": {statuses: []int{429, 429, 429, 429, 200}, wantAttempts: maxAttempts, wantErr: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			attempts := atomic.Int32{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := test.statuses[attempts.Add(1)-1]
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					_, _ = w.Write([]byte(`{"name": "go", "version": "1.22.1"}`))
+				}
+			}))
+			defer server.Close()
+
+			result, err := execGet[PackageVersion](context.Background(), server.URL)
+			if test.wantErr && err == nil {
+				t.Errorf("got nil error, want an error")
+			}
+			if !test.wantErr && (err != nil || result.Version != "1.22.1") {
+				t.Errorf("got result %v and error %v, want version 1.22.1", result, err)
+			}
+			if got := attempts.Load(); got != test.wantAttempts {
+				t.Errorf("got %d attempts, want %d", got, test.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestExecGetNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := execGet[PackageVersion](context.Background(), server.URL)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetRetriesRefusedConnection(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	_, retry, err := get[PackageVersion](context.Background(), server.URL)
+	if err == nil || !retry {
+		t.Errorf("got retry %v and error %v, want a retryable error", retry, err)
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	notFound := &net.DNSError{Err: "no such host", Name: "search.devbox.sh", IsNotFound: true}
+	if !IsConnectionError(notFound) {
+		t.Error("got IsConnectionError false for a host that doesn't resolve")
+	}
+	if shouldRetry(context.Background(), notFound) {
+		t.Error("got shouldRetry true for a host that doesn't resolve")
+	}
+
+	refused := &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	if !shouldRetry(context.Background(), refused) {
+		t.Error("got shouldRetry false for a refused connection")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if shouldRetry(ctx, refused) {
+		t.Error("got shouldRetry true after the context was canceled")
+	}
+}
@@ -94,14 +94,14 @@ func (f *flakeInput) BuildInputsForSymlinkJoin() ([]*SymlinkJoin, error) {
 			return nil, err
 		}
 
+		expr := f.attrExpr(attributePath)
+		if pkg.Override != nil {
+			expr = overrideName(pkg)
+		}
 		joins = append(joins, &SymlinkJoin{
 			Name: pkg.String() + "-combined",
 			Paths: lo.Map(outputNames, func(output string, _ int) string {
-				if !f.IsNixpkgs() {
-					return f.Name + "." + attributePath + "." + output
-				}
-				parts := strings.Split(attributePath, ".")
-				return f.PkgImportName() + "." + strings.Join(parts[2:], ".") + "." + output
+				return expr + "." + output
 			}),
 		})
 	}
@@ -121,7 +121,12 @@ func (f *flakeInput) BuildInputs() ([]string, error) {
 		}
 	}
 
-	attributePaths := lo.Map(packages, func(pkg *devpkg.Package, _ int) string {
+	buildInputs := lo.Map(packages, func(pkg *devpkg.Package, _ int) string {
+		// Overridden packages are bound to a name in the flake's let
+		// block.
+		if pkg.Override != nil {
+			return overrideName(pkg)
+		}
 		attributePath, attributePathErr := pkg.FullPackageAttributePath()
 		if attributePathErr != nil {
 			err = attributePathErr
@@ -131,23 +136,25 @@ func (f *flakeInput) BuildInputs() ([]string, error) {
 			// "legacyPackages" portion of the attribute path
 			// becomes just "packages" (matching the standard flake
 			// output schema).
-			return strings.Replace(attributePath, "legacyPackages", "packages", 1)
+			return f.attrExpr(strings.Replace(attributePath, "legacyPackages", "packages", 1))
 		}
-		return attributePath
+		return f.attrExpr(attributePath)
 	})
 	if err != nil {
 		return nil, err
 	}
+	return buildInputs, nil
+}
+
+// attrExpr returns the Nix expression for a package's full attribute path in
+// the input.
+func (f *flakeInput) attrExpr(attributePath string) string {
 	if !f.IsNixpkgs() {
-		return lo.Map(attributePaths, func(pkg string, _ int) string {
-			return f.Name + "." + pkg
-		}), nil
+		return f.Name + "." + attributePath
 	}
-	return lo.Map(attributePaths, func(pkg string, _ int) string {
-		parts := strings.Split(pkg, ".")
-		// Ugh, not sure if this is reliable?
-		return f.PkgImportName() + "." + strings.Join(parts[2:], ".")
-	}), nil
+	parts := strings.Split(attributePath, ".")
+	// Ugh, not sure if this is reliable?
+	return f.PkgImportName() + "." + strings.Join(parts[2:], ".")
 }
 
 // flakeInputs returns a list of flake inputs for the top level flake.nix
@@ -14,11 +14,12 @@ import (
 // flakePlan contains the data to populate the top level flake.nix file
 // that builds the devbox environment
 type flakePlan struct {
-	BinaryCache string
 	NixpkgsInfo *NixpkgsInfo
 	Packages    []*devpkg.Package
 	FlakeInputs []flakeInput
 	System      string
+
+	flakeOverrides
 }
 
 func newFlakePlan(ctx context.Context, devbox devboxer) (*flakePlan, error) {
@@ -51,12 +52,18 @@ func newFlakePlan(ctx context.Context, devbox devboxer) (*flakePlan, error) {
 		}
 	}
 
+	overrides, err := newFlakeOverrides(
+		flakeInputs, devbox.Config().Derivations(), devbox.ProjectDir(), copiedPatchPath)
+	if err != nil {
+		return nil, err
+	}
+
 	return &flakePlan{
-		BinaryCache: devpkg.BinaryCache,
-		FlakeInputs: flakeInputs,
-		NixpkgsInfo: nixpkgsInfo,
-		Packages:    packages,
-		System:      nix.System(),
+		FlakeInputs:    flakeInputs,
+		NixpkgsInfo:    nixpkgsInfo,
+		Packages:       packages,
+		System:         nix.System(),
+		flakeOverrides: overrides,
 	}, nil
 }
 
@@ -137,11 +144,15 @@ func (g *glibcPatchFlake) fetchClosureExpr(pkg *devpkg.Package) (string, error)
 	if len(storePaths) == 0 {
 		return "", fmt.Errorf("no store path for package %s", pkg.Raw)
 	}
+	cache, err := pkg.BinaryCache()
+	if err != nil {
+		return "", err
+	}
 	return fmt.Sprintf(`builtins.fetchClosure {
   fromStore = "%s";
   fromPath = "%s";
   inputAddressed = true;
-}`, devpkg.BinaryCache, storePaths[0]), nil
+}`, cache, storePaths[0]), nil
 }
 
 func (g *glibcPatchFlake) writeTo(dir string) error {
@@ -59,6 +59,9 @@ func GenerateForPrintEnv(ctx context.Context, devbox devboxer) error {
 			return redact.Errorf("write glibc patch flake to directory: %v", err)
 		}
 	}
+	if err := plan.writePatches(FlakePath(devbox)); err != nil {
+		return err
+	}
 	if err := makeFlakeFile(devbox, plan); err != nil {
 		return err
 	}
@@ -180,6 +183,7 @@ var templateFuncs = template.FuncMap{
 	"json":     toJSON,
 	"contains": strings.Contains,
 	"debug":    debug.IsEnabled,
+	"indent":   indent,
 }
 
 func makeFlakeFile(d devboxer, plan *flakePlan) error {
@@ -4,6 +4,7 @@
 package shellgen
 
 import (
+	"context"
 	"flag"
 	"os"
 	"path/filepath"
@@ -11,8 +12,11 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"go.jetpack.io/devbox/internal/devbox/shellcmd"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
 	"go.jetpack.io/devbox/internal/devpkg"
 	"go.jetpack.io/devbox/internal/lock"
+	"go.jetpack.io/devbox/internal/nix"
 	"go.jetpack.io/devbox/internal/searcher"
 )
 
@@ -42,6 +46,7 @@ func TestWriteFromTemplate(t *testing.T) {
 				URL string
 			}
 			FlakeInputs []flakeInput
+			flakeOverrides
 		}{}
 		err = writeFromTemplate(dir, emptyPlan, "flake.nix", "flake.nix")
 		if err != nil {
@@ -51,6 +56,26 @@ func TestWriteFromTemplate(t *testing.T) {
 	})
 }
 
+func TestWriteFromTemplateDerivations(t *testing.T) {
+	overrides, err := newFlakeOverrides(nil, map[string]configfile.Derivation{
+		"greet": {
+			Src:     &configfile.Source{Path: "greet"},
+			Install: &shellcmd.Commands{Cmds: []string{"install -D greet.sh $out/bin/greet"}},
+		},
+	}, "/project", copiedPatchPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &flakePlan{NixpkgsInfo: &NixpkgsInfo{}, flakeOverrides: overrides}
+
+	dir := t.TempDir()
+	err = writeFromTemplate(dir, plan, "flake.nix", "flake.nix")
+	if err != nil {
+		t.Fatal("got error writing flake template:", err)
+	}
+	cmpGoldenFile(t, filepath.Join(dir, "flake.nix"), "testdata/flake-derivations.nix.golden")
+}
+
 func cmpGoldenFile(t *testing.T, gotPath, wantGoldenPath string) {
 	got, err := os.ReadFile(gotPath)
 	if err != nil {
@@ -87,6 +112,7 @@ var (
 			URL string
 		}
 		FlakeInputs []flakeInput
+		flakeOverrides
 	}{
 		NixpkgsInfo: struct {
 			URL string
@@ -128,7 +154,7 @@ var (
 
 type lockmock struct{}
 
-func (*lockmock) Resolve(pkg string) (*lock.Package, error) {
+func (*lockmock) Resolve(ctx context.Context, pkg string) (*lock.Package, error) {
 	name, _, _ := searcher.ParseVersionedPackage(pkg)
 	return &lock.Package{
 		Resolved: "github:NixOS/nixpkgs/b22db301217578a8edfccccf5cedafe5fc54e78b#" + name,
@@ -146,3 +172,7 @@ func (*lockmock) LegacyNixpkgsPath(pkg string) string {
 func (*lockmock) ProjectDir() string {
 	return ""
 }
+
+func (*lockmock) BinaryCaches() []nix.BinaryCache {
+	return nix.BinaryCaches(nil)
+}
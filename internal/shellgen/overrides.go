@@ -0,0 +1,259 @@
+package shellgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/cachehash"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+	"go.jetpack.io/devbox/internal/devpkg"
+	"go.jetpack.io/devbox/internal/redact"
+)
+
+// patchesDir is the directory in the generated flake that patch files are
+// copied to. Flakes are evaluated in pure mode, so they can only read files
+// inside the flake or its inputs.
+const patchesDir = "patches"
+
+// nixBinding is a let binding in the generated flake.
+type nixBinding struct {
+	Name string
+	Expr string
+}
+
+// sourceInput is a non-flake input of the generated flake for a local source
+// directory.
+type sourceInput struct {
+	Name string
+	URL  string
+}
+
+// flakeOverrides has the parts of the generated flake that come from package
+// overrides and derivations in devbox.json.
+type flakeOverrides struct {
+	Overrides    []nixBinding
+	Derivations  []nixBinding
+	SourceInputs []sourceInput
+
+	// patchFiles maps file names in the flake's patches directory to the
+	// patch files they're copied from.
+	patchFiles map[string]string
+}
+
+// newFlakeOverrides renders the overridden packages in inputs and the
+// derivations. patchPath returns how the flake refers to a patch file.
+func newFlakeOverrides(
+	inputs []flakeInput,
+	derivations map[string]configfile.Derivation,
+	projectDir string,
+	patchPath func(string) string,
+) (flakeOverrides, error) {
+	o := flakeOverrides{patchFiles: map[string]string{}}
+	sources := map[string]string{}
+	addSource := func(src *configfile.Source) {
+		if src != nil && src.Path != "" {
+			sources[sourceInputName(src.Path)] = "path:" + src.Path
+		}
+	}
+
+	for _, input := range inputs {
+		for _, pkg := range input.Packages {
+			if pkg.Override == nil {
+				continue
+			}
+			attrPath, err := pkg.FullPackageAttributePath()
+			if err != nil {
+				return flakeOverrides{}, err
+			}
+			o.Overrides = append(o.Overrides, nixBinding{
+				Name: overrideName(pkg),
+				Expr: overrideExpr(input.attrExpr(attrPath), pkg.Override, patchPath),
+			})
+			addSource(pkg.Override.Src)
+			for _, patch := range pkg.Override.Patches {
+				o.patchFiles[patchFileName(patch)] = patch
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(derivations) {
+		drv := derivations[name]
+		drv.Src = drv.Src.AbsPaths(projectDir)
+		o.Derivations = append(o.Derivations, nixBinding{
+			Name: derivationName(name),
+			Expr: derivationExpr(name, drv),
+		})
+		addSource(drv.Src)
+	}
+
+	for _, name := range sortedKeys(sources) {
+		o.SourceInputs = append(o.SourceInputs, sourceInput{Name: name, URL: sources[name]})
+	}
+	return o, nil
+}
+
+// writePatches copies the patch files of package overrides to the flake's
+// patches directory and removes patches that are no longer used.
+func (o *flakeOverrides) writePatches(flakeDir string) error {
+	dir := filepath.Join(flakeDir, patchesDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return redact.Errorf("read patches directory: %v", err)
+	}
+	for _, entry := range entries {
+		if _, ok := o.patchFiles[entry.Name()]; !ok {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return redact.Errorf("remove unused patch: %v", err)
+			}
+		}
+	}
+	for name, path := range o.patchFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return redact.Errorf("read patch file: %v", err)
+		}
+		if err := overwriteFileIfChanged(filepath.Join(dir, name), data, 0o644); err != nil {
+			return redact.Errorf("write patch file to flake: %v", err)
+		}
+	}
+	return nil
+}
+
+var nonIdentRegexp = regexp.MustCompile("[^a-zA-Z0-9_-]+")
+
+// overrideName returns the name of the let binding for an overridden package.
+func overrideName(pkg *devpkg.Package) string {
+	return "override-" + nonIdentRegexp.ReplaceAllString(pkg.Raw, "-")
+}
+
+// derivationName returns the name of the let binding for a derivation. It has
+// a prefix so that it can't shadow other bindings in the flake.
+func derivationName(name string) string {
+	return "drv-" + name
+}
+
+func sourceInputName(path string) string {
+	return "src-" + cachehash.Bytes6([]byte(path))
+}
+
+// patchFileName returns a unique name for a patch file in the flake's patches
+// directory.
+func patchFileName(path string) string {
+	return cachehash.Bytes6([]byte(path)) + "-" + filepath.Base(path)
+}
+
+// copiedPatchPath refers to a patch file that writePatches copies into the
+// generated flake.
+func copiedPatchPath(path string) string {
+	return "./" + patchesDir + "/" + patchFileName(path)
+}
+
+// overrideExpr returns a Nix expression that applies an override to the
+// package that expr evaluates to.
+func overrideExpr(expr string, o *configfile.PackageOverride, patchPath func(string) string) string {
+	attrs := []string{}
+	if o.Src != nil {
+		attrs = append(attrs, "src = "+sourceExpr(o.Src)+";")
+	}
+	if len(o.Patches) > 0 {
+		patches := make([]string, len(o.Patches))
+		for i, patch := range o.Patches {
+			patches[i] = patchPath(patch)
+		}
+		attrs = append(attrs, "patches = (old.patches or [ ]) ++ "+nixList(patches)+";")
+	}
+	for _, name := range sortedKeys(o.Attrs) {
+		attrs = append(attrs, name+" = "+o.Attrs[name]+";")
+	}
+	if len(attrs) > 0 {
+		expr = "(" + expr + ".overrideAttrs (old: " + nixAttrSet(attrs) + "))"
+	}
+	if len(o.WithPackages) > 0 {
+		expr += ".withPackages (ps: with ps; " + nixList(o.WithPackages) + ")"
+	}
+	return expr
+}
+
+// derivationExpr returns a Nix expression that builds a derivation with
+// mkDerivation.
+func derivationExpr(name string, drv configfile.Derivation) string {
+	attrs := []string{}
+	if drv.Version == "" {
+		attrs = append(attrs, "name = "+nixString(name)+";")
+	} else {
+		attrs = append(attrs,
+			"pname = "+nixString(name)+";",
+			"version = "+nixString(drv.Version)+";",
+		)
+	}
+	if drv.Src != nil {
+		attrs = append(attrs, "src = "+sourceExpr(drv.Src)+";")
+	} else {
+		attrs = append(attrs, "dontUnpack = true;")
+	}
+	if len(drv.BuildInputs) > 0 {
+		attrs = append(attrs, "buildInputs = with pkgs; "+nixList(drv.BuildInputs)+";")
+	}
+	if len(drv.NativeBuildInputs) > 0 {
+		attrs = append(attrs, "nativeBuildInputs = with pkgs; "+nixList(drv.NativeBuildInputs)+";")
+	}
+	if drv.Build != nil && len(drv.Build.Cmds) > 0 {
+		attrs = append(attrs, "buildPhase = "+nixIndentedString(drv.Build.String())+";")
+	}
+	if drv.Install != nil && len(drv.Install.Cmds) > 0 {
+		attrs = append(attrs, "installPhase = "+nixIndentedString(drv.Install.String())+";")
+	}
+	return "pkgs.stdenv.mkDerivation " + nixAttrSet(attrs)
+}
+
+func sourceExpr(src *configfile.Source) string {
+	if src.Path != "" {
+		return sourceInputName(src.Path)
+	}
+	return fmt.Sprintf("pkgs.fetchurl { url = %s; hash = %s; }", nixString(src.URL), nixString(src.Hash))
+}
+
+// nixAttrSet formats attribute definitions as a multi-line attribute set.
+func nixAttrSet(attrs []string) string {
+	return "{" + indent(2, "\n"+strings.Join(attrs, "\n")) + "\n}"
+}
+
+// nixList formats elements as a multi-line list.
+func nixList(elems []string) string {
+	return "[" + indent(2, "\n"+strings.Join(elems, "\n")) + "\n]"
+}
+
+// nixString quotes s as a Nix string.
+func nixString(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+// nixIndentedString quotes s as a multi-line Nix string.
+func nixIndentedString(s string) string {
+	s = strings.NewReplacer("''", "'''", "${", "''${").Replace(s)
+	return "''" + indent(2, "\n"+s) + "\n''"
+}
+
+// indent indents every line of s except for the first by n spaces. Empty lines
+// aren't indented.
+func indent(n int, s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines[1:] {
+		if line != "" {
+			lines[i+1] = strings.Repeat(" ", n) + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := lo.Keys(m)
+	slices.Sort(keys)
+	return keys
+}
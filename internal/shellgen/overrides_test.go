@@ -0,0 +1,122 @@
+package shellgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.jetpack.io/devbox/internal/devbox/shellcmd"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+)
+
+func TestOverrideExpr(t *testing.T) {
+	got := overrideExpr("nixpkgs-pkgs.hello", &configfile.PackageOverride{
+		Src:     &configfile.Source{URL: "https://example.com/hello.tar.gz", Hash: "sha256-abc="},
+		Patches: []string{"/project/fix.patch"},
+		Attrs:   map[string]string{"doCheck": "false", "version": `"2.13"`},
+	}, copiedPatchPath)
+	want := `(nixpkgs-pkgs.hello.overrideAttrs (old: {
+  src = pkgs.fetchurl { url = "https://example.com/hello.tar.gz"; hash = "sha256-abc="; };
+  patches = (old.patches or [ ]) ++ [
+    ./patches/` + patchFileName("/project/fix.patch") + `
+  ];
+  doCheck = false;
+  version = "2.13";
+}))`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("got wrong override expression (-want +got):\n%s", diff)
+	}
+}
+
+func TestOverrideExprWithPackages(t *testing.T) {
+	got := overrideExpr("nixpkgs-pkgs.python312", &configfile.PackageOverride{
+		WithPackages: []string{"numpy", "pandas"},
+	}, copiedPatchPath)
+	want := `nixpkgs-pkgs.python312.withPackages (ps: with ps; [
+  numpy
+  pandas
+])`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("got wrong override expression (-want +got):\n%s", diff)
+	}
+}
+
+func TestDerivationExpr(t *testing.T) {
+	got := derivationExpr("greet", configfile.Derivation{
+		Version:           "1.0",
+		Src:               &configfile.Source{Path: "/project/greet"},
+		NativeBuildInputs: []string{"pkg-config"},
+		Build:             &shellcmd.Commands{Cmds: []string{"make"}},
+		Install:           &shellcmd.Commands{Cmds: []string{"mkdir -p $out/bin", "cp greet ${out}/bin"}},
+	})
+	want := `pkgs.stdenv.mkDerivation {
+  pname = "greet";
+  version = "1.0";
+  src = ` + sourceInputName("/project/greet") + `;
+  nativeBuildInputs = with pkgs; [
+    pkg-config
+  ];
+  buildPhase = ''
+    make
+  '';
+  installPhase = ''
+    mkdir -p $out/bin
+    cp greet ''${out}/bin
+  '';
+}`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("got wrong derivation expression (-want +got):\n%s", diff)
+	}
+}
+
+func TestNixString(t *testing.T) {
+	got := nixString(`say "hi" to ${USER}\n`)
+	want := `"say \"hi\" to \${USER}\\n"`
+	if got != want {
+		t.Errorf("got nixString = %s, want %s", got, want)
+	}
+}
+
+func TestWriteFlakeOverrides(t *testing.T) {
+	project := t.TempDir()
+	patch := filepath.Join(project, "fix.patch")
+	if err := os.WriteFile(patch, []byte("--- a\n+++ b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := newFlakeOverrides(nil, map[string]configfile.Derivation{
+		"greet": {Src: &configfile.Source{Path: "greet"}},
+	}, project, copiedPatchPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overrides.patchFiles[patchFileName(patch)] = patch
+
+	if len(overrides.SourceInputs) != 1 || overrides.SourceInputs[0].URL != "path:"+filepath.Join(project, "greet") {
+		t.Errorf("got source inputs %v, want the derivation's absolute path", overrides.SourceInputs)
+	}
+
+	flakeDir := t.TempDir()
+	stale := filepath.Join(flakeDir, patchesDir, "old.patch")
+	if err := os.MkdirAll(filepath.Dir(stale), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := overrides.writePatches(flakeDir); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(filepath.Join(flakeDir, patchesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := patchFileName(patch); strings.Join(names, " ") != want {
+		t.Errorf("got patches %q, want only %q", names, want)
+	}
+}
@@ -0,0 +1,337 @@
+package shellgen
+
+import (
+	"context"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/trace"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+	"go.jetpack.io/devbox/internal/cachehash"
+	"go.jetpack.io/devbox/internal/devconfig/configfile"
+	"go.jetpack.io/devbox/internal/nix"
+	"go.jetpack.io/devbox/internal/plugin"
+)
+
+// StandaloneFlakeName is the name of the flake that WriteStandaloneFlake
+// writes to the project directory.
+const StandaloneFlakeName = "flake.nix"
+
+// projectRootPlaceholder replaces the project directory in the contents of
+// plugin files. The shell hook substitutes the directory that the flake is
+// used from.
+const projectRootPlaceholder = "@DEVBOX_PROJECT_ROOT@"
+
+// profilePlaceholder replaces the Devbox profile directory until the flake
+// is rendered, where it becomes the store path of the flake's profile.
+const profilePlaceholder = "\x00profile\x00"
+
+// nixRefPlaceholder returns a placeholder for a reference to a let binding in
+// a string. It's replaced with an interpolation after the string is quoted,
+// so that quoting doesn't escape the interpolation.
+func nixRefPlaceholder(name string) string {
+	return "\x00" + name + "\x00"
+}
+
+var nixRefPlaceholderRegexp = regexp.MustCompile("\x00([a-zA-Z0-9_-]+)\x00")
+
+// interpolateNixRefs replaces the placeholders in a quoted Nix string with
+// interpolations.
+func interpolateNixRefs(quoted string) string {
+	return nixRefPlaceholderRegexp.ReplaceAllString(quoted, "$${$1}")
+}
+
+// standaloneFlake has the data to populate a flake.nix that reproduces the
+// Devbox environment with nix develop, without Devbox.
+type standaloneFlake struct {
+	NixpkgsURL  string
+	FlakeInputs []flakeInput
+	Inputs      []standaloneInput
+	flakeOverrides
+
+	// Files are let bindings for the contents of the files that plugins
+	// create.
+	Files []nixBinding
+
+	// ShellHook is a Nix expression for the shell hook of the dev shell.
+	ShellHook string
+}
+
+// standaloneInput is an input of the standalone flake with a URL that's
+// relative to the project directory when possible.
+type standaloneInput struct {
+	Name  string
+	URL   string
+	Flake bool
+}
+
+// WriteStandaloneFlake writes a flake.nix to the project directory whose
+// default dev shell has the project's packages, environment variables, init
+// hook and plugin files. Unlike the flake in .devbox/gen, it doesn't depend on
+// any files that Devbox generates, so it can be committed and used with
+// nix develop.
+func WriteStandaloneFlake(ctx context.Context, devbox devboxer) error {
+	defer trace.StartRegion(ctx, "WriteStandaloneFlake").End()
+
+	plan, err := newFlakePlan(ctx, devbox)
+	if err != nil {
+		return err
+	}
+	if plan.needsGlibcPatch() {
+		return usererr.New("Packages with patch_glibc can't be exported to a standalone flake.")
+	}
+
+	projectDir := devbox.ProjectDir()
+	flake := &standaloneFlake{
+		NixpkgsURL:  "github:NixOS/nixpkgs/" + devbox.Config().NixPkgsCommitHash(),
+		FlakeInputs: plan.FlakeInputs,
+	}
+	for _, input := range plan.FlakeInputs {
+		if input.IsNixpkgs() {
+			flake.NixpkgsURL = input.URL
+			break
+		}
+	}
+
+	// Patches are referenced from the project instead of being copied.
+	var relErr error
+	flake.flakeOverrides, err = newFlakeOverrides(
+		plan.FlakeInputs, devbox.Config().Derivations(), projectDir,
+		func(path string) string {
+			rel, err := relativeToProject(projectDir, path)
+			if err != nil {
+				relErr = err
+			}
+			return rel
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if relErr != nil {
+		return relErr
+	}
+	for i := range flake.Overrides {
+		flake.Overrides[i].Expr = anySystem(flake.Overrides[i].Expr)
+	}
+
+	for _, input := range plan.FlakeInputs {
+		url, err := relativeFlakeURL(projectDir, input.URL)
+		if err != nil {
+			return err
+		}
+		flake.Inputs = append(flake.Inputs, standaloneInput{Name: input.Name, URL: url, Flake: true})
+	}
+	for _, input := range flake.SourceInputs {
+		url, err := relativeFlakeURL(projectDir, input.URL)
+		if err != nil {
+			return err
+		}
+		flake.Inputs = append(flake.Inputs, standaloneInput{Name: input.Name, URL: url})
+	}
+
+	// newFlakePlan created the plugin files, so their contents can be read.
+	hook := &strings.Builder{}
+	hook.WriteString(findProjectRootScript)
+	flake.Files, err = writePluginFilesScript(hook, devbox.Config().IncludedPluginConfigs(), projectDir)
+	if err != nil {
+		return err
+	}
+	writeEnvScript(hook, devbox.Config().Env(), projectDir)
+	initHook := devbox.Config().InitHook().String()
+	if initHook != "" {
+		hook.WriteString("\n# init_hook from devbox.json\n")
+		hook.WriteString(replaceProjectPaths(initHook, projectDir, "$DEVBOX_PROJECT_ROOT"))
+		hook.WriteString("\n")
+	}
+	flake.ShellHook = interpolateNixRefs(nixIndentedString(strings.TrimSpace(hook.String())))
+
+	return writeFromTemplate(projectDir, flake, "standalone-flake.nix", StandaloneFlakeName)
+}
+
+// BuildInputs returns the flake's packages for any system. The packages of
+// flakes other than nixpkgs have the system in their attribute paths, so it's
+// replaced with the system that the flake is evaluated for.
+func (f *standaloneFlake) BuildInputs() ([]string, error) {
+	inputs := []string{}
+	for _, input := range f.FlakeInputs {
+		buildInputs, err := input.BuildInputs()
+		if err != nil {
+			return nil, err
+		}
+		joins, err := input.BuildInputsForSymlinkJoin()
+		if err != nil {
+			return nil, err
+		}
+		for _, join := range joins {
+			paths := lo.Map(join.Paths, func(path string, _ int) string { return "    " + path })
+			buildInputs = append(buildInputs, "(pkgs.symlinkJoin {\n  name = "+nixString(join.Name)+
+				";\n  paths = [\n"+strings.Join(paths, "\n")+"\n  ];\n})")
+		}
+		for _, buildInput := range buildInputs {
+			inputs = append(inputs, anySystem(buildInput))
+		}
+	}
+	for _, drv := range f.Derivations {
+		inputs = append(inputs, drv.Name)
+	}
+	return inputs, nil
+}
+
+// anySystem replaces the current system in the attribute paths of an
+// expression with the system that the flake is evaluated for.
+func anySystem(expr string) string {
+	return strings.ReplaceAll(expr, "."+nix.System()+".", ".${system}.")
+}
+
+// findProjectRootScript sets DEVBOX_PROJECT_ROOT to the closest directory with
+// a devbox.json, because nix develop can run from any directory.
+const findProjectRootScript = `DEVBOX_PROJECT_ROOT="$PWD"
+while [ "$DEVBOX_PROJECT_ROOT" != / ] && [ ! -e "$DEVBOX_PROJECT_ROOT/` + configfile.DefaultName + `" ]; do
+  DEVBOX_PROJECT_ROOT="$(dirname "$DEVBOX_PROJECT_ROOT")"
+done
+if [ ! -e "$DEVBOX_PROJECT_ROOT/` + configfile.DefaultName + `" ]; then
+  DEVBOX_PROJECT_ROOT="$PWD"
+fi
+export DEVBOX_PROJECT_ROOT
+export DEVBOX_CONFIG_DIR="$DEVBOX_PROJECT_ROOT/devbox.d"
+export DEVBOX_PACKAGES_DIR="` + profilePlaceholder + `"
+`
+
+// writePluginFilesScript writes shell commands that create the files from
+// the create_files of plugins. Files in .devbox are always recreated, and
+// other files are only created if they don't exist, which is how Devbox
+// creates them. It returns the let bindings of the files' contents.
+func writePluginFilesScript(w *strings.Builder, plugins []*plugin.Config, projectDir string) ([]nixBinding, error) {
+	files := []nixBinding{}
+	wroteHeader := false
+	hiddenDir := filepath.Join(projectDir, ".devbox") + string(filepath.Separator)
+	for _, cfg := range plugins {
+		for _, path := range sortedKeys(cfg.CreateFiles) {
+			rel, err := filepath.Rel(projectDir, path)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			dest := `"$DEVBOX_PROJECT_ROOT/` + rel + `"`
+			if !wroteHeader {
+				w.WriteString("\n# Files created by plugins\n")
+				wroteHeader = true
+			}
+			if cfg.CreateFiles[path] == "" {
+				w.WriteString("mkdir -p " + dest + "\n")
+				continue
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "read file %s of plugin %s", rel, cfg.Name)
+			}
+
+			name := "file-" + cachehash.Bytes6([]byte(rel))
+			contentStr := replaceProjectPaths(string(content), projectDir, projectRootPlaceholder)
+			files = append(files, nixBinding{
+				Name: name,
+				Expr: "pkgs.writeText " + nixString(filepath.Base(path)) + " " +
+					interpolateNixRefs(nixString(contentStr)),
+			})
+
+			create := "mkdir -p \"$(dirname " + dest + ")\"\n" +
+				"sed \"s|" + projectRootPlaceholder + "|$DEVBOX_PROJECT_ROOT|g\" " + nixRefPlaceholder(name) + " > " + dest + "\n"
+			if strings.Contains(path, "bin/") {
+				create += "chmod 755 " + dest + "\n" +
+					"mkdir -p \"$DEVBOX_PROJECT_ROOT/" + plugin.VirtenvBinPath + "\"\n" +
+					"ln -sf " + dest + " \"$DEVBOX_PROJECT_ROOT/" + plugin.VirtenvBinPath + "/" + filepath.Base(path) + "\"\n"
+			}
+			if !strings.HasPrefix(path, hiddenDir) {
+				create = "if [ ! -e " + dest + " ]; then\n" + indentAll(2, create) + "fi\n"
+			}
+			w.WriteString(create)
+		}
+	}
+	return files, nil
+}
+
+// writeEnvScript writes shell commands that export the env variables. Like
+// Devbox, it expands variables in the values, but not command substitutions.
+func writeEnvScript(w *strings.Builder, env map[string]string, projectDir string) {
+	if len(env) == 0 {
+		return
+	}
+	w.WriteString("\n# env from devbox.json\n")
+	for _, name := range sortedKeys(env) {
+		value := os.Expand(env[name], func(v string) string {
+			switch v {
+			case "$":
+				return "$"
+			case "PWD":
+				v = "DEVBOX_PROJECT_ROOT"
+			}
+			return "\x00" + v + "\x00"
+		})
+		parts := strings.Split(value, "\x00")
+		quoted := &strings.Builder{}
+		for i, part := range parts {
+			if i%2 == 1 {
+				quoted.WriteString("${" + part + "}")
+				continue
+			}
+			part = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(part)
+			quoted.WriteString(replaceProjectPaths(part, projectDir, "${DEVBOX_PROJECT_ROOT}"))
+		}
+		w.WriteString("export " + name + "=\"" + quoted.String() + "\"\n")
+	}
+}
+
+// replaceProjectPaths replaces absolute paths in the project with paths that
+// are relative to root. Paths to the Devbox profile are replaced with the
+// profile placeholder.
+func replaceProjectPaths(s, projectDir, root string) string {
+	s = strings.ReplaceAll(s, filepath.Join(projectDir, nix.ProfilePath), profilePlaceholder)
+	return strings.ReplaceAll(s, projectDir, root)
+}
+
+// relativeToProject returns a Nix path for a file in the project, relative to
+// the standalone flake. Files in .devbox are rejected, because Devbox
+// generates them and they aren't committed.
+func relativeToProject(projectDir, path string) (string, error) {
+	rel, err := filepath.Rel(projectDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", usererr.New(
+			"%s is outside of the project, so it can't be used in a standalone flake.", path)
+	}
+	if rel == ".devbox" || strings.HasPrefix(rel, ".devbox"+string(filepath.Separator)) {
+		return "", usererr.New(
+			"%s is generated by Devbox, usually for a plugin, so it can't be used in a standalone flake.", rel)
+	}
+	return "./" + filepath.ToSlash(rel), nil
+}
+
+// relativeFlakeURL makes path: URLs in the project relative, so that the
+// flake works from any checkout.
+func relativeFlakeURL(projectDir, url string) (string, error) {
+	path, ok := strings.CutPrefix(url, "path:")
+	if !ok || !filepath.IsAbs(path) {
+		return url, nil
+	}
+	path, query, _ := strings.Cut(path, "?")
+	if unescaped, err := neturl.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+	rel, err := relativeToProject(projectDir, path)
+	if err != nil {
+		return "", err
+	}
+	if query != "" {
+		rel += "?" + query
+	}
+	return "path:" + rel, nil
+}
+
+// indentAll indents every line of s by n spaces.
+func indentAll(n int, s string) string {
+	return strings.TrimPrefix(indent(n, "\n"+s), "\n")
+}
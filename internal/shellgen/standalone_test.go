@@ -0,0 +1,57 @@
+package shellgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteEnvScript(t *testing.T) {
+	w := &strings.Builder{}
+	writeEnvScript(w, map[string]string{
+		"PATH":     "$PWD/bin:$PATH",
+		"DATA":     "/project/data",
+		"GREETING": `say "hi" for $$5`,
+	}, "/project")
+	want := `
+# env from devbox.json
+export DATA="${DEVBOX_PROJECT_ROOT}/data"
+export GREETING="say \"hi\" for \$5"
+export PATH="${DEVBOX_PROJECT_ROOT}/bin:${PATH}"
+`
+	if diff := cmp.Diff(want, w.String()); diff != "" {
+		t.Errorf("got wrong env script (-want +got):\n%s", diff)
+	}
+}
+
+func TestRelativeFlakeURL(t *testing.T) {
+	tests := map[string]string{
+		"github:NixOS/nixpkgs/nixos-unstable":     "github:NixOS/nixpkgs/nixos-unstable",
+		"path:/project/my-flake":                  "path:./my-flake",
+		"path:/project/my%20flake?lastModified=1": "path:./my flake?lastModified=1",
+	}
+	for url, want := range tests {
+		got, err := relativeFlakeURL("/project", url)
+		if err != nil {
+			t.Errorf("relativeFlakeURL(%q) returned error: %v", url, err)
+		}
+		if got != want {
+			t.Errorf("got relativeFlakeURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+	if _, err := relativeFlakeURL("/project", "path:/elsewhere/flake"); err == nil {
+		t.Error("got nil error for a flake outside of the project")
+	}
+	if _, err := relativeFlakeURL("/project", "path:/project/.devbox/virtenv/php/flake"); err == nil {
+		t.Error("got nil error for a flake that a plugin generated")
+	}
+}
+
+func TestInterpolateNixRefs(t *testing.T) {
+	got := interpolateNixRefs(nixString("cat " + nixRefPlaceholder("file-abc") + " ${HOME}"))
+	want := `"cat ${file-abc} \${HOME}"`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
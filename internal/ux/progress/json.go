@@ -0,0 +1,54 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+)
+
+// jsonRenderer writes each event as a line of JSON.
+type jsonRenderer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newJSONRenderer(w io.Writer) *jsonRenderer {
+	return &jsonRenderer{enc: json.NewEncoder(w)}
+}
+
+func (r *jsonRenderer) render(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// There's nowhere to report a failure to write progress.
+	_ = r.enc.Encode(event)
+}
+
+// logWriter reports each line written to it as a log event.
+type logWriter struct {
+	mu      sync.Mutex
+	partial []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i == -1 {
+			break
+		}
+		line := strings.TrimRight(string(w.partial[:i]), "\r")
+		w.partial = w.partial[i+1:]
+		if strings.TrimSpace(line) != "" {
+			Log(line)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package progress reports the progress of long operations, such as resolving
+// and installing packages, as a stream of events. By default, the events are
+// rendered as a spinner when stderr is a terminal. With the JSON format, each
+// event is written to stderr as a line of JSON, so that editors and CI can
+// show progress without parsing devbox's messages.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+
+	"go.jetpack.io/devbox/internal/boxcli/usererr"
+)
+
+// Phase is the kind of work that a task does.
+type Phase string
+
+const (
+	Resolve     Phase = "resolve"
+	Install     Phase = "install"
+	Download    Phase = "download"
+	Build       Phase = "build"
+	Plugin      Phase = "plugin"
+	Environment Phase = "environment"
+	Hook        Phase = "hook"
+)
+
+// EventType is the type of an event.
+type EventType string
+
+const (
+	EventStart  EventType = "start"
+	EventUpdate EventType = "update"
+	EventEnd    EventType = "end"
+	// EventLog is a line of output that isn't part of a task's progress,
+	// such as a message from devbox or nix.
+	EventLog EventType = "log"
+)
+
+// Event is reported when a task starts, makes progress or ends.
+type Event struct {
+	Type EventType `json:"event"`
+	Time time.Time `json:"time"`
+
+	// Task identifies the task that start, update and end events belong
+	// to. Parent is the task that it's part of, if any.
+	Task   int `json:"task,omitempty"`
+	Parent int `json:"parent,omitempty"`
+
+	Phase Phase `json:"phase,omitempty"`
+	// Name is what the task works on, such as a package or a store path.
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// Done and Total count the task's progress, such as resolved packages
+	// or downloaded bytes. Total is 0 if it isn't known.
+	Done  int64 `json:"done,omitempty"`
+	Total int64 `json:"total,omitempty"`
+
+	// Error and Duration are set in end events.
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+}
+
+// Format is how events are rendered.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case FormatText, FormatJSON:
+		return Format(name), nil
+	}
+	return "", usererr.New("Invalid output format %q. Valid formats are: text, json", name)
+}
+
+type renderer interface {
+	render(Event)
+}
+
+type nopRenderer struct{}
+
+func (nopRenderer) render(Event) {}
+
+var (
+	mu            sync.Mutex
+	lastID        int
+	currentFormat          = FormatText
+	current       renderer = nopRenderer{}
+	now                    = time.Now
+)
+
+// Init starts rendering events to w in the given format. It returns the
+// writer that the command's other output to w must go through, so that it
+// doesn't garble the spinner or, with the JSON format, is reported as log
+// events.
+func Init(format Format, w io.Writer) io.Writer {
+	mu.Lock()
+	defer mu.Unlock()
+
+	currentFormat = format
+	switch format {
+	case FormatJSON:
+		// Messages are reported as log events, which shouldn't contain
+		// terminal escape codes.
+		color.NoColor = true
+		r := newJSONRenderer(w)
+		current = r
+		return &logWriter{}
+	default:
+		f, ok := w.(*os.File)
+		if !ok || !isatty.IsTerminal(f.Fd()) {
+			current = nopRenderer{}
+			return w
+		}
+		t := newTUI(f)
+		current = t
+		return t
+	}
+}
+
+// IsJSON returns true if events are written as JSON.
+func IsJSON() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return currentFormat == FormatJSON
+}
+
+// Suspend stops rendering a spinner until the returned function is called.
+// It's used while a command that draws its own progress, such as nix build,
+// writes directly to the terminal.
+func Suspend() (resume func()) {
+	mu.Lock()
+	t, ok := current.(*tui)
+	mu.Unlock()
+	if !ok {
+		return func() {}
+	}
+	return t.suspend()
+}
+
+// Terminal returns the terminal that w writes to if w is the writer returned
+// by Init, so that commands like nix can detect that they run in a terminal.
+// Otherwise, it returns w.
+func Terminal(w io.Writer) io.Writer {
+	if t, ok := w.(*tui); ok {
+		return t.w
+	}
+	return w
+}
+
+func emit(event Event) {
+	event.Time = now()
+	mu.Lock()
+	r := current
+	mu.Unlock()
+	r.render(event)
+}
+
+// Task is an operation whose progress is reported.
+type Task struct {
+	id      int
+	parent  int
+	phase   Phase
+	name    string
+	message string
+	start   time.Time
+}
+
+// Start reports that a task started.
+func Start(phase Phase, format string, a ...any) *Task {
+	return start(0, phase, "", fmt.Sprintf(format, a...))
+}
+
+// Child reports that a task that's part of t, such as resolving one of its
+// packages, started. If t is nil, the task isn't part of another one.
+func (t *Task) Child(phase Phase, name string) *Task {
+	if t == nil {
+		return start(0, phase, name, "")
+	}
+	return start(t.id, phase, name, "")
+}
+
+func start(parent int, phase Phase, name, message string) *Task {
+	mu.Lock()
+	lastID++
+	id := lastID
+	mu.Unlock()
+
+	t := &Task{id: id, parent: parent, phase: phase, name: name, message: message, start: now()}
+	emit(t.event(EventStart))
+	return t
+}
+
+// Update reports how much of the task is done.
+func (t *Task) Update(done, total int64) {
+	event := t.event(EventUpdate)
+	event.Done, event.Total = done, total
+	emit(event)
+}
+
+// End reports that the task finished, or failed if err isn't nil.
+func (t *Task) End(err error) {
+	event := t.event(EventEnd)
+	event.DurationMS = now().Sub(t.start).Milliseconds()
+	if err != nil {
+		event.Error = err.Error()
+	}
+	emit(event)
+}
+
+func (t *Task) event(typ EventType) Event {
+	return Event{
+		Type:    typ,
+		Task:    t.id,
+		Parent:  t.parent,
+		Phase:   t.phase,
+		Name:    t.name,
+		Message: t.message,
+	}
+}
+
+// Log reports a line of output that isn't part of a task's progress.
+func Log(message string) {
+	emit(Event{Type: EventLog, Message: message})
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// initJSON renders events to a buffer as JSON until the test ends.
+func initJSON(t *testing.T) (events func() []Event, stderr *logWriter) {
+	t.Helper()
+	noColor := color.NoColor
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	t.Cleanup(func() {
+		color.NoColor = noColor
+		now = time.Now
+		lastID = 0
+		currentFormat = FormatText
+		current = nopRenderer{}
+	})
+
+	buf := &bytes.Buffer{}
+	w := Init(FormatJSON, buf)
+	return func() []Event {
+		var events []Event
+		dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+		for dec.More() {
+			event := Event{}
+			if err := dec.Decode(&event); err != nil {
+				t.Fatalf("invalid event: %v\n%s", err, buf)
+			}
+			events = append(events, event)
+		}
+		return events
+	}, w.(*logWriter)
+}
+
+func TestJSONEvents(t *testing.T) {
+	events, _ := initJSON(t)
+
+	task := Start(Resolve, "Resolving %d packages", 2)
+	child := task.Child(Resolve, "go@latest")
+	child.Update(1, 2)
+	child.End(errors.New("not found"))
+	task.End(nil)
+
+	got := events()
+	if len(got) != 5 {
+		t.Fatalf("got %d events, want 5: %+v", len(got), got)
+	}
+	want := []Event{
+		{Type: EventStart, Task: 1, Phase: Resolve, Message: "Resolving 2 packages"},
+		{Type: EventStart, Task: 2, Parent: 1, Phase: Resolve, Name: "go@latest"},
+		{Type: EventUpdate, Task: 2, Parent: 1, Phase: Resolve, Name: "go@latest", Done: 1, Total: 2},
+		{Type: EventEnd, Task: 2, Parent: 1, Phase: Resolve, Name: "go@latest", Error: "not found", DurationMS: 3000},
+		{Type: EventEnd, Task: 1, Phase: Resolve, Message: "Resolving 2 packages", DurationMS: 7000},
+	}
+	for i := range want {
+		got[i].Time = time.Time{}
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	events, w := initJSON(t)
+	_, _ = w.Write([]byte("Installing\nhello "))
+	_, _ = w.Write([]byte("world\r\n\n  \nlast"))
+
+	got := events()
+	want := []string{"Installing", "hello world"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i, msg := range want {
+		if got[i].Type != EventLog || got[i].Message != msg {
+			t.Errorf("event %d = %+v, want log %q", i, got[i], msg)
+		}
+	}
+	if string(w.partial) != "last" {
+		t.Errorf("got partial line %q, want %q", w.partial, "last")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"text", "json"} {
+		if format, err := ParseFormat(name); err != nil || string(format) != name {
+			t.Errorf("ParseFormat(%q) = %q, %v", name, format, err)
+		}
+	}
+	if _, err := ParseFormat("yaml"); err == nil {
+		t.Error("ParseFormat(\"yaml\") returned no error")
+	}
+}
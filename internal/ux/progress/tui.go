@@ -0,0 +1,147 @@
+// Copyright 2024 Jetify Inc. and contributors. All rights reserved.
+// Use of this source code is governed by the license in the LICENSE file.
+
+package progress
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+)
+
+// tui renders the running tasks as a spinner in a terminal, and prints a line
+// when a task ends. Only tasks without a parent are shown. Their children
+// only change the spinner's message.
+//
+// It's also the writer for the command's other output, which it writes
+// above the spinner.
+type tui struct {
+	mu        sync.Mutex
+	w         *os.File
+	spinner   *spinner.Spinner
+	tasks     []*tuiTask
+	suspended bool
+	// midLine is true if the last write didn't end a line, so the spinner
+	// would overwrite it.
+	midLine bool
+}
+
+type tuiTask struct {
+	id          int
+	message     string
+	detail      string
+	done, total int64
+}
+
+func newTUI(w *os.File) *tui {
+	return &tui{w: w}
+}
+
+func (t *tui) render(event Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if event.Parent != 0 {
+		if parent := t.task(event.Parent); parent != nil && event.Type == EventStart {
+			parent.detail = event.Name
+			t.refresh()
+		}
+		return
+	}
+	switch event.Type {
+	case EventStart:
+		t.tasks = append(t.tasks, &tuiTask{id: event.Task, message: event.Message})
+	case EventUpdate:
+		if task := t.task(event.Task); task != nil {
+			task.done, task.total = event.Done, event.Total
+		}
+	case EventEnd:
+		t.tasks = slices.DeleteFunc(t.tasks, func(task *tuiTask) bool { return task.id == event.Task })
+		duration := time.Duration(event.DurationMS) * time.Millisecond
+		line := fmt.Sprintf("%s %s (%s)\n", color.GreenString("✓"), event.Message, duration.Round(10*time.Millisecond))
+		if event.Error != "" {
+			line = fmt.Sprintf("%s %s\n", color.RedString("✘"), event.Message)
+		}
+		t.stop()
+		fmt.Fprint(t.w, line)
+	}
+	t.refresh()
+}
+
+func (t *tui) task(id int) *tuiTask {
+	for _, task := range t.tasks {
+		if task.id == id {
+			return task
+		}
+	}
+	return nil
+}
+
+// refresh shows the spinner for the latest task, or hides it if there are no
+// tasks.
+func (t *tui) refresh() {
+	if t.suspended || t.midLine || len(t.tasks) == 0 {
+		t.stop()
+		return
+	}
+	task := t.tasks[len(t.tasks)-1]
+	suffix := " " + task.message
+	if task.total > 0 {
+		suffix += fmt.Sprintf(" (%d/%d)", task.done, task.total)
+	}
+	if task.detail != "" {
+		suffix += ": " + task.detail
+	}
+
+	if t.spinner == nil {
+		// A new spinner is started each time, because a stopped spinner
+		// can't always be restarted.
+		t.spinner = spinner.New(spinner.CharSets[11], 100*time.Millisecond,
+			spinner.WithWriterFile(t.w))
+		_ = t.spinner.Color("magenta")
+		t.spinner.Suffix = suffix
+		t.spinner.Start()
+		return
+	}
+	t.spinner.Lock()
+	t.spinner.Suffix = suffix
+	t.spinner.Unlock()
+}
+
+func (t *tui) stop() {
+	if t.spinner != nil {
+		t.spinner.Stop()
+		t.spinner = nil
+	}
+}
+
+func (t *tui) suspend() func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.suspended = true
+	t.stop()
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.suspended = false
+		t.refresh()
+	}
+}
+
+// Write writes p above the spinner.
+func (t *tui) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stop()
+	n, err := t.w.Write(p)
+	if len(p) > 0 {
+		t.midLine = p[len(p)-1] != '\n'
+	}
+	t.refresh()
+	return n, err
+}
@@ -214,6 +214,9 @@ func parseGitHubRef(refURL *url.URL, parsed *Ref) error {
 	if err != nil {
 		return err
 	}
+	if len(split) < 2 || split[0] == "" || split[1] == "" {
+		return redact.Errorf("github flake reference is missing an owner or repo")
+	}
 	parsed.Owner = split[0]
 	parsed.Repo = split[1]
 	if len(split) > 2 {